@@ -0,0 +1,74 @@
+package bot
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"bushlatinga_bot/database"
+	"bushlatinga_bot/render"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// fileDownloadTimeout - сколько ждать скачивания файла для /admin import
+const fileDownloadTimeout = 30 * time.Second
+
+// replyFile возвращает документ из сообщения, на которое ответили командой, или nil.
+// Файл скачивается с серверов Telegram, только когда команда его запросит.
+func replyFile(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) *database.AttachedFile {
+	if msg.ReplyToMessage == nil || msg.ReplyToMessage.Document == nil {
+		return nil
+	}
+	document := msg.ReplyToMessage.Document
+
+	return &database.AttachedFile{
+		Name: document.FileName,
+		Size: document.FileSize,
+		Load: func() ([]byte, error) {
+			url, err := bot.GetFileDirectURL(document.FileID)
+			if err != nil {
+				return nil, err
+			}
+
+			client := &http.Client{Timeout: fileDownloadTimeout}
+			resp, err := client.Get(url)
+			if err != nil {
+				return nil, err
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				return nil, fmt.Errorf("Telegram вернул %s", resp.Status)
+			}
+			return io.ReadAll(resp.Body)
+		},
+	}
+}
+
+// sendAdminDocument отправляет файл, который вернула команда администратора.
+// Подпись длиннее лимита Telegram продолжается отдельными сообщениями.
+func sendAdminDocument(bot *tgbotapi.BotAPI, chatID int64, document *database.AdminDocument, caption string) error {
+	upload := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: document.Name, Bytes: document.Data})
+	chunks := render.Markup(caption).Render(replyMode, render.CaptionLimit)
+	if len(chunks) > 0 {
+		upload.Caption, upload.ParseMode = chunks[0].Text, string(chunks[0].Mode)
+	}
+
+	_, err := bot.Send(upload)
+	if render.IsParseError(err) {
+		log.Printf("⚠️ Formatting rejected, sending caption as plain text: %v", err)
+		upload.Caption, upload.ParseMode = chunks[0].Plain, ""
+		_, err = bot.Send(upload)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, chunk := range chunks[min(1, len(chunks)):] {
+		sendChunk(bot, tgbotapi.NewMessage(chatID, ""), chunk)
+	}
+	return nil
+}
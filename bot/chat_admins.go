@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Как долго верить списку администраторов чата, полученному из Telegram
+const chatAdminsCacheTTL = 10 * time.Minute
+
+// chatAdminCache кеширует getChatAdministrators, чтобы не спрашивать Telegram на каждую команду
+type chatAdminCache struct {
+	bot     *tgbotapi.BotAPI
+	mu      sync.Mutex
+	entries map[int64]chatAdminEntry
+}
+
+// chatAdminEntry - администраторы одного чата на момент загрузки
+type chatAdminEntry struct {
+	admins   map[int64]bool
+	loadedAt time.Time
+}
+
+// newChatAdminCache создает кеш администраторов чатов
+func newChatAdminCache(bot *tgbotapi.BotAPI) *chatAdminCache {
+	return &chatAdminCache{
+		bot:     bot,
+		entries: make(map[int64]chatAdminEntry),
+	}
+}
+
+// IsChatAdmin проверяет, что пользователь - создатель или администратор чата.
+// При ошибке Telegram прав не дает, но и не запоминает ответ.
+func (c *chatAdminCache) IsChatAdmin(chatID, userID int64) bool {
+	c.mu.Lock()
+	entry, ok := c.entries[chatID]
+	c.mu.Unlock()
+
+	if !ok || time.Since(entry.loadedAt) > chatAdminsCacheTTL {
+		members, err := c.bot.GetChatAdministrators(tgbotapi.ChatAdministratorsConfig{
+			ChatConfig: tgbotapi.ChatConfig{ChatID: chatID},
+		})
+		if err != nil {
+			log.Printf("⚠️ Не удалось получить админов чата %d: %v", chatID, err)
+			return false
+		}
+
+		entry = chatAdminEntry{admins: make(map[int64]bool, len(members)), loadedAt: time.Now()}
+		for _, member := range members {
+			if member.User != nil && (member.IsCreator() || member.IsAdministrator()) {
+				entry.admins[member.User.ID] = true
+			}
+		}
+
+		c.mu.Lock()
+		c.entries[chatID] = entry
+		c.mu.Unlock()
+	}
+
+	return entry.admins[userID]
+}
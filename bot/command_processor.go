@@ -1,9 +1,11 @@
 package bot
 
 import (
+	"fmt"
 	"log"
 
 	"bushlatinga_bot/database"
+	"bushlatinga_bot/render"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/vmkotov/telelog"
@@ -24,7 +26,7 @@ func NewCommandProcessor(dbHandler *database.BotDatabaseHandler, teleLogger tele
 }
 
 // ProcessCommand обрабатывает команду
-func (cp *CommandProcessor) ProcessCommand(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+func (cp *CommandProcessor) ProcessCommand(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, extras *messageExtras) {
 	log.Printf("⚡ Command received: /%s", msg.Command())
 
 	// Логируем команду через telelog
@@ -34,35 +36,32 @@ func (cp *CommandProcessor) ProcessCommand(bot *tgbotapi.BotAPI, msg *tgbotapi.M
 
 	switch msg.Command() {
 	case "start":
-		reply := tgbotapi.NewMessage(msg.Chat.ID,
-			"🌿 *Привет! Я Bushlatinga Bot* — ваш помощник по документам и информации.\n\n"+
-				"Я могу:\n"+
-				"• Сохранять документы\n"+
-				"• Искать информацию\n"+
-				"• Помогать с вопросами\n"+
-				"• Отвечать на упоминания участников\n\n"+
+		reply := (&render.Builder{}).Text("🌿 ").Bold("Привет! Я Bushlatinga Bot").
+			Text(" — ваш помощник по документам и информации.\n\n" +
+				"Я могу:\n" +
+				"• Сохранять документы\n" +
+				"• Искать информацию\n" +
+				"• Помогать с вопросами\n" +
+				"• Отвечать на упоминания участников\n\n" +
 				"Используй /help для списка команд")
-		reply.ParseMode = "Markdown"
-		bot.Send(reply)
+		sendFormatted(bot, msg.Chat.ID, reply, nil)
 
 	case "help":
-		helpText := cp.getHelpText(msg.From.ID)
-		reply := tgbotapi.NewMessage(msg.Chat.ID, helpText)
-		reply.ParseMode = "Markdown"
-		bot.Send(reply)
+		sendFormatted(bot, msg.Chat.ID, cp.getHelpText(msg.From.ID), nil)
 
 	case "about":
-		reply := tgbotapi.NewMessage(msg.Chat.ID,
-			"🤖 *Bushlatinga Bot*\n"+
-				"Версия: 3.0.0 (модульная архитектура)\n"+
-				"Разработчик: @vmkotov\n"+
-				"Технологии: Go + Supabase PostgreSQL\n\n"+
+		reply := (&render.Builder{}).Text("🤖 ").Bold("Bushlatinga Bot").
+			Text("\nВерсия: 3.0.0 (модульная архитектура)\n" +
+				"Разработчик: @vmkotov\n" +
+				"Технологии: Go + Supabase PostgreSQL\n\n" +
 				"Бот для работы с документами и реакцией на упоминания участников.")
-		reply.ParseMode = "Markdown"
-		bot.Send(reply)
+		sendFormatted(bot, msg.Chat.ID, reply, nil)
 
 	case "admin":
-		cp.processAdminCommand(bot, msg)
+		cp.processAdminCommand(bot, msg, extras)
+
+	case "cancel":
+		cp.processCancelCommand(bot, msg)
 
 	default:
 		reply := tgbotapi.NewMessage(msg.Chat.ID, "🤔 Неизвестная команда. Используйте /help для списка команд.")
@@ -71,30 +70,124 @@ func (cp *CommandProcessor) ProcessCommand(bot *tgbotapi.BotAPI, msg *tgbotapi.M
 }
 
 // getHelpText возвращает текст помощи
-func (cp *CommandProcessor) getHelpText(userID int64) string {
-	helpText := "🆘 *Доступные команды:*\n\n" +
+func (cp *CommandProcessor) getHelpText(userID int64) *render.Builder {
+	helpText := (&render.Builder{}).Text("🆘 ").Bold("Доступные команды:").Text("\n\n" +
 		"/start - Начать работу\n" +
 		"/help - Помощь\n" +
-		"/about - О боте\n"
+		"/about - О боте\n")
 
 	// Добавляем админ команду, если пользователь админ
 	if cp.dbHandler != nil && cp.dbHandler.IsAdmin(userID) {
-		helpText += "/admin - Команды администратора\n"
+		helpText.Text("/admin - Команды администратора\n")
+		helpText.Text("/cancel - Отменить пошаговое добавление триггера\n")
 	}
 
-	helpText += "\n*Просто напиши мне вопрос или загрузи документ!*"
-	return helpText
+	return helpText.Text("\n").Bold("Просто напиши мне вопрос или загрузи документ!")
 }
 
 // processAdminCommand обрабатывает админ команды
-func (cp *CommandProcessor) processAdminCommand(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+func (cp *CommandProcessor) processAdminCommand(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, extras *messageExtras) {
 	if cp.dbHandler != nil {
-		response := cp.dbHandler.HandleAdminCommand(msg.From.ID, msg.Text)
-		reply := tgbotapi.NewMessage(msg.Chat.ID, response)
-		reply.ParseMode = "Markdown"
-		bot.Send(reply)
+		response := cp.dbHandler.HandleAdminCommand(database.AdminRequest{
+			UserID:  msg.From.ID,
+			ChatID:  msg.Chat.ID,
+			Command: msg.Text,
+
+			ReplyMedia:          mediaFromMessage(msg.ReplyToMessage),
+			ReplyText:           replyText(msg),
+			ReplySticker:        stickerInfo(msg.ReplyToMessage, extras.reply()),
+			ReplyCustomEmojiIDs: extras.reply().customEmojiIDs(),
+			ReplyUserID:         replyUserID(msg),
+			ReplyFile:           replyFile(bot, msg),
+		})
+
+		// Файл отправляется с текстом ответа в подписи
+		if response.Document != nil {
+			if err := sendAdminDocument(bot, msg.Chat.ID, response.Document, response.Text); err != nil {
+				log.Printf("❌ Error sending admin document: %v", err)
+				bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("❌ Не удалось отправить файл: %v", err)))
+			}
+			return
+		}
+
+		sendAdminReply(bot, msg.Chat.ID, response)
 	} else {
 		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ База данных не подключена. Режим работы: только в памяти.")
 		bot.Send(reply)
 	}
 }
+
+// processCancelCommand отменяет пошаговое добавление триггера (/admin add без аргументов)
+func (cp *CommandProcessor) processCancelCommand(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+	if cp.dbHandler == nil {
+		return
+	}
+
+	text := "🤷 Нечего отменять"
+	cancelled, err := cp.dbHandler.CancelAdminDialog(msg.From.ID, msg.Chat.ID)
+	switch {
+	case err != nil:
+		log.Printf("❌ Error cancelling admin dialog: %v", err)
+		text = fmt.Sprintf("❌ Ошибка: %v", err)
+	case cancelled:
+		text = "✖️ Добавление триггера отменено"
+	}
+	bot.Send(tgbotapi.NewMessage(msg.Chat.ID, text))
+}
+
+// replyText возвращает текст или подпись сообщения, на которое ответили командой
+func replyText(msg *tgbotapi.Message) string {
+	if msg.ReplyToMessage == nil {
+		return ""
+	}
+	if msg.ReplyToMessage.Text != "" {
+		return msg.ReplyToMessage.Text
+	}
+	return msg.ReplyToMessage.Caption
+}
+
+// replyUserID возвращает автора сообщения, на которое ответили командой, или 0
+func replyUserID(msg *tgbotapi.Message) int64 {
+	if msg.ReplyToMessage == nil || msg.ReplyToMessage.From == nil {
+		return 0
+	}
+	return msg.ReplyToMessage.From.ID
+}
+
+// ProcessCallback обрабатывает нажатие кнопки под сообщением бота (листание /admin list и т.п.):
+// обновляет сообщение и отвечает на callback_query, чтобы у кнопки пропали часики
+func (cp *CommandProcessor) ProcessCallback(bot *tgbotapi.BotAPI, query *tgbotapi.CallbackQuery) {
+	log.Printf("🔘 Callback received: %s", query.Data)
+
+	if cp.dbHandler == nil || query.Message == nil {
+		bot.Request(tgbotapi.NewCallback(query.ID, "❌ База данных не подключена"))
+		return
+	}
+
+	response := cp.dbHandler.HandleAdminCallback(database.AdminCallback{
+		UserID: query.From.ID,
+		ChatID: query.Message.Chat.ID,
+		Data:   query.Data,
+	})
+
+	if response.Text != "" {
+		editAdminReply(bot, query.Message, response)
+	}
+
+	if _, err := bot.Request(tgbotapi.NewCallback(query.ID, response.Notice)); err != nil {
+		log.Printf("❌ Error answering callback: %v", err)
+	}
+}
+
+// inlineKeyboard собирает инлайн-клавиатуру из кнопок ответа администратору
+func inlineKeyboard(buttons [][]database.AdminButton) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(buttons))
+	for _, row := range buttons {
+		var keyboardRow []tgbotapi.InlineKeyboardButton
+		for _, button := range row {
+			keyboardRow = append(keyboardRow, tgbotapi.NewInlineKeyboardButtonData(button.Text, button.Data))
+		}
+		rows = append(rows, keyboardRow)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
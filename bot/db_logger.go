@@ -46,7 +46,7 @@ func (dl *DBLogger) LogMessage(msg *tgbotapi.Message) {
 	dl.logToTelegram(msg)
 	
 	// Обновляем статистику
-	dl.updateBotStats()
+	dl.updateBotStats(msg)
 }
 
 // logToDatabase логирует сообщение в базу данных
@@ -140,6 +140,37 @@ func (dl *DBLogger) logToDatabase(msg *tgbotapi.Message) {
 	}
 }
 
+// LogRevision сохраняет новую версию отредактированного сообщения.
+// Исходный текст остается в messages_log, версии нумеруются с 1.
+func (dl *DBLogger) LogRevision(msg *tgbotapi.Message) {
+	if dl.dbHandler == nil || dl.dbHandler.DB() == nil {
+		return
+	}
+
+	messageText := msg.Text
+	if messageText == "" {
+		messageText = msg.Caption
+	}
+
+	query := `
+		INSERT INTO main.message_revisions (bot_id, chat_id, message_id, revision, message_text, edited_at)
+		SELECT $1, $2, $3, COALESCE(MAX(revision), 0) + 1, $4, to_timestamp($5)
+		FROM main.message_revisions
+		WHERE bot_id = $1 AND chat_id = $2 AND message_id = $3
+		ON CONFLICT (bot_id, chat_id, message_id, revision) DO NOTHING
+	`
+
+	_, err := dl.dbHandler.DB().Exec(query,
+		dl.bot.Self.ID, msg.Chat.ID, msg.MessageID, messageText, msg.EditDate,
+	)
+
+	if err != nil {
+		log.Printf("❌ Ошибка сохранения правки в БД: %v", err)
+	} else {
+		log.Printf("✅ Правка сохранена в БД: chat_id=%d, message_id=%d", msg.Chat.ID, msg.MessageID)
+	}
+}
+
 // logToTelegram отправляет лог в Telegram чат
 func (dl *DBLogger) logToTelegram(msg *tgbotapi.Message) {
 	if dl.logChatID == 0 || dl.bot == nil {
@@ -293,21 +324,30 @@ func (dl *DBLogger) formatBotInfo() string {
 	)
 }
 
-// updateBotStats обновляет статистику бота
-func (dl *DBLogger) updateBotStats() {
+// updateBotStats обновляет статистику бота: счетчики сообщений и команд.
+// Совпадения триггеров считает обработчик БД, когда находит их.
+func (dl *DBLogger) updateBotStats(msg *tgbotapi.Message) {
 	if dl.dbHandler == nil || dl.dbHandler.DB() == nil {
 		return
 	}
 
+	commands := 0
+	if msg.IsCommand() {
+		commands = 1
+	}
+
 	query := `
-		INSERT INTO main.bot_stats (bot_id, bot_username, updated_at)
-		VALUES ($1, $2, NOW())
+		INSERT INTO main.bot_stats (bot_id, bot_username, total_messages, total_commands, last_message_at, updated_at)
+		VALUES ($1, $2, 1, $3, NOW(), NOW())
 		ON CONFLICT (bot_id) DO UPDATE SET
+			total_messages = COALESCE(main.bot_stats.total_messages, 0) + 1,
+			total_commands = COALESCE(main.bot_stats.total_commands, 0) + EXCLUDED.total_commands,
+			last_message_at = NOW(),
 			updated_at = NOW(),
 			bot_username = EXCLUDED.bot_username
 	`
 
-	_, err := dl.dbHandler.DB().Exec(query, dl.bot.Self.ID, dl.bot.Self.UserName)
+	_, err := dl.dbHandler.DB().Exec(query, dl.bot.Self.ID, dl.bot.Self.UserName, commands)
 	if err != nil {
 		log.Printf("❌ Ошибка обновления статистики: %v", err)
 	}
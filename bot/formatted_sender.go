@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"log"
+
+	"bushlatinga_bot/database"
+	"bushlatinga_bot/render"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Разметка, в которой бот отправляет свои тексты. В HTML экранируются только <, > и &,
+// поэтому "_" и "*" из ключей и ответов триггеров не ломают сообщение.
+const replyMode = render.HTML
+
+// sendAdminReply отправляет текстовый ответ администратору; кнопки - под последним сообщением
+func sendAdminReply(bot *tgbotapi.BotAPI, chatID int64, response database.AdminReply) {
+	var keyboard *tgbotapi.InlineKeyboardMarkup
+	if len(response.Buttons) > 0 {
+		markup := inlineKeyboard(response.Buttons)
+		keyboard = &markup
+	}
+	sendFormatted(bot, chatID, render.Markup(response.Text), keyboard)
+}
+
+// editAdminReply заменяет текст и кнопки сообщения после нажатия кнопки.
+// Не поместившийся в одно сообщение остаток отправляется новыми сообщениями.
+func editAdminReply(bot *tgbotapi.BotAPI, message *tgbotapi.Message, response database.AdminReply) {
+	chunks := render.Markup(response.Text).Render(replyMode, render.MessageLimit)
+	if len(chunks) == 0 {
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageText(message.Chat.ID, message.MessageID, chunks[0].Text)
+	edit.ParseMode = string(chunks[0].Mode)
+	if len(response.Buttons) > 0 {
+		markup := inlineKeyboard(response.Buttons)
+		edit.ReplyMarkup = &markup
+	}
+	_, err := bot.Request(edit)
+	if render.IsParseError(err) {
+		log.Printf("⚠️ Formatting rejected, editing as plain text: %v", err)
+		edit.Text, edit.ParseMode = chunks[0].Plain, ""
+		_, err = bot.Request(edit)
+	}
+	if err != nil {
+		log.Printf("❌ Error editing admin message: %v", err)
+	}
+
+	for _, chunk := range chunks[1:] {
+		sendChunk(bot, tgbotapi.NewMessage(message.Chat.ID, ""), chunk)
+	}
+}
+
+// sendFormatted отправляет сообщение, при необходимости несколькими частями.
+// Клавиатура прикрепляется к последней части.
+func sendFormatted(bot *tgbotapi.BotAPI, chatID int64, message *render.Builder, keyboard *tgbotapi.InlineKeyboardMarkup) {
+	chunks := message.Render(replyMode, render.MessageLimit)
+	for i, chunk := range chunks {
+		msg := tgbotapi.NewMessage(chatID, "")
+		if keyboard != nil && i == len(chunks)-1 {
+			msg.ReplyMarkup = *keyboard
+		}
+		sendChunk(bot, msg, chunk)
+	}
+}
+
+// sendChunk отправляет одну часть сообщения. Если Telegram не принял разметку,
+// та же часть отправляется обычным текстом, чтобы админ все равно увидел ответ.
+func sendChunk(bot *tgbotapi.BotAPI, msg tgbotapi.MessageConfig, chunk render.Chunk) {
+	msg.Text, msg.ParseMode = chunk.Text, string(chunk.Mode)
+	_, err := bot.Send(msg)
+	if render.IsParseError(err) {
+		log.Printf("⚠️ Formatting rejected, sending as plain text: %v", err)
+		msg.Text, msg.ParseMode = chunk.Plain, ""
+		_, err = bot.Send(msg)
+	}
+	if err != nil {
+		log.Printf("❌ Error sending message to chat %d: %v", msg.ChatID, err)
+	}
+}
@@ -1,8 +1,8 @@
 package bot
 
 import (
+	"encoding/json"
 	"log"
-	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/vmkotov/telelog"
@@ -24,41 +24,25 @@ func NewMessageProcessor(dbHandler *database.BotDatabaseHandler, teleLogger tele
 }
 
 // ProcessMessage обрабатывает входящее сообщение
-func (mp *MessageProcessor) ProcessMessage(bot *tgbotapi.BotAPI, msg *tgbotapi.Message) {
+func (mp *MessageProcessor) ProcessMessage(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, extras *messageExtras) {
 	// Пытаемся найти совпадение в именах через БД (если она подключена)
 	if mp.dbHandler != nil {
-		found, response := mp.dbHandler.CheckForNames(msg.Text, msg.From.UserName)
-		if found {
-			log.Printf("✅ Name match found in DB for message: %s", msg.Text)
-
-			// 🔥 ОБРАБОТКА СТИКЕРА ДЛЯ "ЕБ"
-			if strings.HasPrefix(response, "STICKER:") {
-				// 1. Отправляем стикер
-				sticker := tgbotapi.NewSticker(msg.Chat.ID, tgbotapi.FileID(mp.dbHandler.GetEBStickerID()))
-
-				if _, err := bot.Send(sticker); err != nil {
-					log.Printf("❌ Error sending sticker: %v", err)
-				} else {
-					log.Printf("✅ Sticker sent to chat %d", msg.Chat.ID)
-				}
-
-				// 2. Отправляем текст
-				textResponse := strings.TrimPrefix(response, "STICKER:")
-				if textResponse != "" {
-					reply := tgbotapi.NewMessage(msg.Chat.ID, textResponse)
-
-					if _, err := bot.Send(reply); err != nil {
-						log.Printf("❌ Error sending text after sticker: %v", err)
-					}
-				}
-			} else {
-				// Стандартная обработка текстового ответа
-				reply := tgbotapi.NewMessage(msg.Chat.ID, response)
-
-				if _, err := bot.Send(reply); err != nil {
-					log.Printf("❌ Error sending name response: %v", err)
-				}
-			}
+		// Сообщение админа посреди /admin add - шаг диалога, а не повод для ответа
+		dialogReply, inDialog := mp.dbHandler.HandleAdminDialog(database.DialogMessage{
+			UserID: msg.From.ID,
+			ChatID: msg.Chat.ID,
+			Text:   msg.Text,
+			Media:  mediaFromMessage(msg),
+		})
+		if inDialog {
+			sendAdminReply(bot, msg.Chat.ID, dialogReply)
+			return
+		}
+
+		responses := mp.dbHandler.CheckForNames(incomingMessage(msg, extras))
+		if len(responses) > 0 {
+			log.Printf("✅ Name match found in DB for message: %s (%d responses)", msg.Text, len(responses))
+			mp.sendResponses(bot, msg, extras, responses)
 			return
 		}
 	}
@@ -66,3 +50,86 @@ func (mp *MessageProcessor) ProcessMessage(bot *tgbotapi.BotAPI, msg *tgbotapi.M
 	// Если не найдено совпадений в именах - НИЧЕГО НЕ ОТВЕЧАЕМ!
 	log.Printf("📝 No name match found for message: %s", msg.Text)
 }
+
+// ProcessEdit обрабатывает отредактированное сообщение по политике правок чата:
+// отвечает на появившиеся триггеры и удаляет ответы на убранные
+func (mp *MessageProcessor) ProcessEdit(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, extras *messageExtras, previousText string) {
+	if mp.dbHandler == nil {
+		return
+	}
+
+	responses, stale := mp.dbHandler.CheckEditedMessage(incomingMessage(msg, extras), previousText)
+	for _, id := range stale {
+		if _, err := bot.Request(tgbotapi.NewDeleteMessage(msg.Chat.ID, id)); err != nil {
+			log.Printf("❌ Error deleting stale response %d: %v", id, err)
+		} else {
+			log.Printf("🗑 Stale response %d deleted in chat %d", id, msg.Chat.ID)
+		}
+	}
+
+	if len(responses) > 0 {
+		log.Printf("✅ Name match found in edited message: %s (%d responses)", msg.Text, len(responses))
+		mp.sendResponses(bot, msg, extras, responses)
+	}
+}
+
+// incomingMessage собирает для поиска триггеров все, что нужно знать о сообщении
+func incomingMessage(msg *tgbotapi.Message, extras *messageExtras) database.IncomingMessage {
+	// Подписи к фото, GIF и документам проверяются так же, как текст
+	text := msg.Text
+	if text == "" {
+		text = msg.Caption
+	}
+
+	return database.IncomingMessage{
+		ChatID:    msg.Chat.ID,
+		MessageID: msg.MessageID,
+		ChatTitle: msg.Chat.Title,
+		UserID:    msg.From.ID,
+		UserName:  msg.From.UserName,
+		FirstName: msg.From.FirstName,
+		Text:      text,
+
+		Sticker:        stickerInfo(msg, extras),
+		CustomEmojiIDs: extras.customEmojiIDs(),
+	}
+}
+
+// sendResponses отправляет ответы на сообщение с учетом темы форума и режима ответа чата
+func (mp *MessageProcessor) sendResponses(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, extras *messageExtras, responses []database.Reply) {
+	target := sendTarget{ChatID: msg.Chat.ID, ThreadID: extras.topicID()}
+	if mp.dbHandler.GetChatSettings(msg.Chat.ID).ReplyMode == database.ReplyModeReply {
+		target.ReplyTo = msg.MessageID
+	}
+
+	for _, response := range responses {
+		sent := mp.sendResponse(bot, target, response)
+		mp.dbHandler.RememberResponse(msg.Chat.ID, msg.MessageID, response.TriggerID, sent)
+	}
+}
+
+// sendResponse отправляет один ответ на сообщение: все его части по порядку.
+// Возвращает ID отправленных сообщений.
+func (mp *MessageProcessor) sendResponse(bot *tgbotapi.BotAPI, target sendTarget, reply database.Reply) []int {
+	var sent []int
+	for _, part := range reply.Parts {
+		method, params, err := responseRequest(target, part)
+		if err != nil {
+			log.Printf("❌ Error building %s response: %v", part.Type, err)
+			continue
+		}
+
+		resp, err := bot.MakeRequest(method, params)
+		if err != nil {
+			log.Printf("❌ Error sending %s response: %v", part.Type, err)
+			continue
+		}
+		log.Printf("✅ %s response sent to chat %d", part.Type, target.ChatID)
+
+		var message tgbotapi.Message
+		if err := json.Unmarshal(resp.Result, &message); err == nil {
+			sent = append(sent, message.MessageID)
+		}
+	}
+	return sent
+}
@@ -0,0 +1,107 @@
+package bot
+
+import (
+	"fmt"
+
+	"bushlatinga_bot/database"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// sendTarget - куда отправляется ответ: чат, тема форума и сообщение, на которое бот отвечает
+type sendTarget struct {
+	ChatID   int64
+	ThreadID int // message_thread_id темы форума, 0 - без темы
+	ReplyTo  int // ID сообщения для ответа цитатой, 0 - обычное сообщение
+}
+
+// responseRequest превращает часть ответа из БД в метод и параметры Telegram Bot API.
+// Запрос собирается вручную, потому что в tgbotapi v5.5.1 нет message_thread_id.
+func responseRequest(target sendTarget, part database.ResponsePart) (string, tgbotapi.Params, error) {
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", target.ChatID)
+	params.AddNonZero("message_thread_id", target.ThreadID)
+	if target.ReplyTo != 0 {
+		params.AddNonZero("reply_to_message_id", target.ReplyTo)
+		// Если сообщение успели удалить, ответ все равно уйдет
+		params.AddBool("allow_sending_without_reply", true)
+	}
+
+	switch part.Type {
+	case database.ResponseText:
+		params.AddNonEmpty("text", part.Text)
+		return "sendMessage", params, nil
+
+	case database.ResponseSticker:
+		params.AddNonEmpty("sticker", part.FileID)
+		return "sendSticker", params, nil
+
+	case database.ResponsePhoto:
+		params.AddNonEmpty("photo", part.FileID)
+		params.AddNonEmpty("caption", part.Text)
+		return "sendPhoto", params, nil
+
+	case database.ResponseAnimation:
+		params.AddNonEmpty("animation", part.FileID)
+		params.AddNonEmpty("caption", part.Text)
+		return "sendAnimation", params, nil
+
+	case database.ResponseVoice:
+		params.AddNonEmpty("voice", part.FileID)
+		params.AddNonEmpty("caption", part.Text)
+		return "sendVoice", params, nil
+
+	case database.ResponseVideoNote:
+		params.AddNonEmpty("video_note", part.FileID)
+		return "sendVideoNote", params, nil
+
+	case database.ResponseDocument:
+		params.AddNonEmpty("document", part.FileID)
+		params.AddNonEmpty("caption", part.Text)
+		return "sendDocument", params, nil
+
+	case database.ResponseDice:
+		params.AddNonEmpty("emoji", part.Text)
+		return "sendDice", params, nil
+
+	default:
+		return "", nil, fmt.Errorf("неизвестный тип ответа: %s", part.Type)
+	}
+}
+
+// mediaFromMessage извлекает file_id медиа из сообщения, на которое админ ответил командой.
+// Для сообщений без медиа возвращает nil.
+func mediaFromMessage(msg *tgbotapi.Message) *database.ResponsePart {
+	if msg == nil {
+		return nil
+	}
+
+	switch {
+	case msg.Sticker != nil:
+		return &database.ResponsePart{Type: database.ResponseSticker, FileID: msg.Sticker.FileID}
+
+	case len(msg.Photo) > 0:
+		// Последний размер в массиве - самый крупный
+		photo := msg.Photo[len(msg.Photo)-1]
+		return &database.ResponsePart{Type: database.ResponsePhoto, FileID: photo.FileID, Text: msg.Caption}
+
+	// Animation проверяется раньше Document: у GIF Telegram заполняет оба поля
+	case msg.Animation != nil:
+		return &database.ResponsePart{Type: database.ResponseAnimation, FileID: msg.Animation.FileID, Text: msg.Caption}
+
+	case msg.Voice != nil:
+		return &database.ResponsePart{Type: database.ResponseVoice, FileID: msg.Voice.FileID, Text: msg.Caption}
+
+	case msg.VideoNote != nil:
+		return &database.ResponsePart{Type: database.ResponseVideoNote, FileID: msg.VideoNote.FileID}
+
+	case msg.Document != nil:
+		return &database.ResponsePart{Type: database.ResponseDocument, FileID: msg.Document.FileID, Text: msg.Caption}
+
+	case msg.Dice != nil:
+		return &database.ResponsePart{Type: database.ResponseDice, Text: msg.Dice.Emoji}
+
+	default:
+		return nil
+	}
+}
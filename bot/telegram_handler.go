@@ -29,6 +29,13 @@ func NewTelegramHandler(
 	teleLogger telelog.TeleLogger,
 	messageForwarder *MessageForwarder,
 ) *TelegramHandler {
+	// Админы чатов в Telegram могут получить права редактора своего чата
+	if dbHandler != nil {
+		dbHandler.SetChatAdminChecker(newChatAdminCache(bot).IsChatAdmin)
+		// Счетчики совпадений ведутся в строке main.bot_stats этого бота
+		dbHandler.SetBotID(bot.Self.ID)
+	}
+
 	return &TelegramHandler{
 		bot:               bot,
 		dbHandler:         dbHandler,
@@ -63,7 +70,17 @@ func (th *TelegramHandler) HandleWebhook(w http.ResponseWriter, r *http.Request)
 
 	// Обработка сообщения
 	if update.Message != nil {
-		th.processMessage(&update)
+		th.processMessage(&update, parseMessageExtras(body))
+	}
+
+	// Обработка отредактированного сообщения
+	if update.EditedMessage != nil {
+		th.processEditedMessage(update.EditedMessage, parseMessageExtras(body))
+	}
+
+	// Обработка нажатий на кнопки под сообщениями бота
+	if update.CallbackQuery != nil {
+		th.commandProcessor.ProcessCallback(th.bot, update.CallbackQuery)
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -71,7 +88,7 @@ func (th *TelegramHandler) HandleWebhook(w http.ResponseWriter, r *http.Request)
 }
 
 // processMessage обрабатывает сообщение
-func (th *TelegramHandler) processMessage(update *tgbotapi.Update) {
+func (th *TelegramHandler) processMessage(update *tgbotapi.Update, extras *messageExtras) {
 	msg := update.Message
 	
 	chatType := "private"
@@ -98,8 +115,32 @@ func (th *TelegramHandler) processMessage(update *tgbotapi.Update) {
 
 	// Обрабатываем команду или сообщение
 	if msg.IsCommand() {
-		th.commandProcessor.ProcessCommand(th.bot, msg)
+		th.commandProcessor.ProcessCommand(th.bot, msg, extras)
 	} else {
-		th.messageProcessor.ProcessMessage(th.bot, msg)
+		th.messageProcessor.ProcessMessage(th.bot, msg, extras)
+	}
+}
+
+// processEditedMessage обрабатывает отредактированное сообщение:
+// записывает новую версию и по политике чата отвечает на новые триггеры
+func (th *TelegramHandler) processEditedMessage(msg *tgbotapi.Message, extras *messageExtras) {
+	if msg.From == nil || msg.From.ID == th.bot.Self.ID {
+		return
+	}
+
+	// Предыдущую версию берем до записи новой
+	previousText := ""
+	if th.dbHandler != nil {
+		previousText = th.dbHandler.LastMessageText(msg.Chat.ID, msg.MessageID)
+	}
+
+	if th.dbLogger != nil {
+		th.dbLogger.LogRevision(msg)
+	}
+
+	// Правки команд не выполняем повторно
+	if msg.IsCommand() {
+		return
 	}
+	th.messageProcessor.ProcessEdit(th.bot, msg, extras, previousText)
 }
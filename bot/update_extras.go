@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"encoding/json"
+
+	"bushlatinga_bot/database"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// messageExtras - поля сообщения из Bot API, которых нет в tgbotapi v5.5.1.
+// Разбираются из того же JSON вебхука, что и tgbotapi.Update.
+type messageExtras struct {
+	MessageThreadID int  `json:"message_thread_id"`
+	IsTopicMessage  bool `json:"is_topic_message"` // сообщение из темы форума
+
+	Sticker *struct {
+		CustomEmojiID string `json:"custom_emoji_id"`
+	} `json:"sticker"`
+	Entities        []extraEntity  `json:"entities"`
+	CaptionEntities []extraEntity  `json:"caption_entities"`
+	ReplyToMessage  *messageExtras `json:"reply_to_message"`
+}
+
+// extraEntity - сущность текста с полем custom_emoji_id
+type extraEntity struct {
+	Type          string `json:"type"`
+	CustomEmojiID string `json:"custom_emoji_id"`
+}
+
+// parseMessageExtras достает дополнительные поля сообщения (нового или отредактированного)
+// из тела вебхука. Никогда не возвращает nil: при ошибке поля просто пустые.
+func parseMessageExtras(body []byte) *messageExtras {
+	var update struct {
+		Message       *messageExtras `json:"message"`
+		EditedMessage *messageExtras `json:"edited_message"`
+	}
+	if err := json.Unmarshal(body, &update); err != nil {
+		return &messageExtras{}
+	}
+	if update.Message != nil {
+		return update.Message
+	}
+	if update.EditedMessage != nil {
+		return update.EditedMessage
+	}
+	return &messageExtras{}
+}
+
+// reply возвращает поля сообщения, на которое ответили
+func (e *messageExtras) reply() *messageExtras {
+	if e == nil || e.ReplyToMessage == nil {
+		return &messageExtras{}
+	}
+	return e.ReplyToMessage
+}
+
+// topicID возвращает тему форума, в которой написано сообщение, или 0.
+// В обычных группах message_thread_id бывает и у веток ответов, туда отправлять не нужно.
+func (e *messageExtras) topicID() int {
+	if e == nil || !e.IsTopicMessage {
+		return 0
+	}
+	return e.MessageThreadID
+}
+
+// customEmojiIDs возвращает ID кастомных эмодзи из текста и подписи
+func (e *messageExtras) customEmojiIDs() []string {
+	if e == nil {
+		return nil
+	}
+
+	var ids []string
+	for _, entities := range [][]extraEntity{e.Entities, e.CaptionEntities} {
+		for _, entity := range entities {
+			if entity.Type == "custom_emoji" && entity.CustomEmojiID != "" {
+				ids = append(ids, entity.CustomEmojiID)
+			}
+		}
+	}
+	return ids
+}
+
+// stickerInfo собирает свойства стикера для медиа-триггеров; nil, если сообщение не стикер
+func stickerInfo(msg *tgbotapi.Message, extras *messageExtras) *database.StickerInfo {
+	if msg == nil || msg.Sticker == nil {
+		return nil
+	}
+
+	info := &database.StickerInfo{
+		FileUniqueID: msg.Sticker.FileUniqueID,
+		SetName:      msg.Sticker.SetName,
+		Emoji:        msg.Sticker.Emoji,
+	}
+	if extras != nil && extras.Sticker != nil {
+		info.CustomEmojiID = extras.Sticker.CustomEmojiID
+	}
+	return info
+}
@@ -0,0 +1,238 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Команды, для которых хватает роли viewer: они ничего не меняют
+var readOnlyAdminCommands = map[string]bool{
+	"list": true, "список": true, "все": true,
+	"search": true, "найти": true, "поиск": true,
+	"count": true, "количество": true,
+	"stats": true, "статистика": true,
+	"help": true, "помощь": true,
+	"export": true, "экспорт": true,
+	"info": true, "инфо": true,
+	"test": true, "тест": true,
+	"history": true, "история": true,
+	"trash": true, "корзина": true,
+	// Права на откат проверяются по области откатываемого триггера
+	"undo": true, "отменить": true,
+}
+
+// Команды управления администраторами - только для владельца
+var ownerAdminCommands = map[string]bool{
+	"grant": true, "выдать": true,
+	"revoke": true, "отозвать": true,
+	"admins": true, "админы": true,
+}
+
+// authorizeAdminCommand проверяет права на команду. Изменение триггеров требует роли
+// editor во всех чатах области команды, поэтому редактор одного чата не может менять
+// глобальные триггеры. Возвращает текст отказа или пустую строку.
+func (h *BotDatabaseHandler) authorizeAdminCommand(req AdminRequest, subCommand string, args []string) string {
+	role := h.UserRole(req.UserID, req.ChatID)
+	if role == "" {
+		return "❌ У вас нет прав для выполнения этой команды"
+	}
+
+	switch {
+	case subCommand == "" || readOnlyAdminCommands[subCommand]:
+		return ""
+
+	case ownerAdminCommands[subCommand]:
+		// Область назначения проверяется в самой команде
+		if !hasRole(role, RoleOwner) {
+			return "❌ Управлять администраторами может только владелец"
+		}
+		return ""
+
+	case subCommand == "chat" || subCommand == "чат":
+		switch {
+		case len(args) == 0:
+			return ""
+		case strings.EqualFold(args[0], "admins") || strings.EqualFold(args[0], "админы"):
+			if !hasRole(role, RoleOwner) {
+				return "❌ Доверять админам чата может только владелец"
+			}
+			return ""
+		case !hasRole(role, RoleEditor):
+			return "❌ Менять настройки чата может только редактор"
+		}
+		return ""
+
+	case subCommand == "detector" || subCommand == "detectors" || subCommand == "детектор" || subCommand == "детекторы":
+		if len(args) == 0 || strings.EqualFold(args[0], "list") {
+			return ""
+		}
+		args = args[1:]
+	}
+
+	opts, _ := parseAdminOptions(args)
+	scope, err := scopeOption(opts, req.ChatID)
+	if err != nil {
+		// Некорректную область покажет сама команда
+		if !hasRole(role, RoleEditor) {
+			return "❌ Ваша роль позволяет только просматривать триггеры"
+		}
+		return ""
+	}
+	if !h.canEditScope(req.UserID, scope) {
+		return fmt.Sprintf("❌ Нет прав менять триггеры: %s", scope)
+	}
+	return ""
+}
+
+// handleGrantCommand обрабатывает /admin grant: назначение роли пользователю.
+// Пользователь - ID или автор сообщения, на которое ответили командой.
+func (h *BotDatabaseHandler) handleGrantCommand(req AdminRequest, parts []string) string {
+	opts, args := parseAdminOptions(parts)
+	userID, args := adminTargetUser(req, args)
+	if userID == 0 || len(args) != 1 {
+		return "❌ Использование: /admin grant [scope=here|global|ID] <user_id> <owner|editor|viewer>\n" +
+			"Или ответьте на сообщение пользователя: /admin grant <роль>"
+	}
+
+	chatID, errText := h.grantChat(req, opts)
+	if errText != "" {
+		return errText
+	}
+	if err := h.GrantRole(userID, chatID, args[0], req.UserID); err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+	role, _ := ParseRole(args[0])
+	return fmt.Sprintf("✅ Пользователь %d теперь %s (%s)", userID, role, grantScope(chatID))
+}
+
+// handleRevokeCommand обрабатывает /admin revoke: отзыв роли
+func (h *BotDatabaseHandler) handleRevokeCommand(req AdminRequest, parts []string) string {
+	opts, args := parseAdminOptions(parts)
+	userID, args := adminTargetUser(req, args)
+	if userID == 0 || len(args) != 0 {
+		return "❌ Использование: /admin revoke [scope=here|global|ID] <user_id>"
+	}
+
+	chatID, errText := h.grantChat(req, opts)
+	if errText != "" {
+		return errText
+	}
+	if err := h.RevokeRole(userID, chatID); err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+	return fmt.Sprintf("✅ Роль пользователя %d отозвана (%s)", userID, grantScope(chatID))
+}
+
+// adminTargetUser берет пользователя из первого аргумента или из сообщения, на которое ответили
+func adminTargetUser(req AdminRequest, args []string) (int64, []string) {
+	if len(args) > 0 {
+		if id, err := strconv.ParseInt(args[0], 10, 64); err == nil && id > 0 {
+			return id, args[1:]
+		}
+	}
+	return req.ReplyUserID, args
+}
+
+// grantChat определяет чат назначения из опции scope= (один чат или глобально)
+// и проверяет, что вызывающий - владелец в нем
+func (h *BotDatabaseHandler) grantChat(req AdminRequest, opts map[string]string) (int64, string) {
+	scope, err := scopeOption(opts, req.ChatID)
+	if err != nil {
+		return 0, fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+	if len(scope) > 1 {
+		return 0, "❌ Роль назначается на один чат или глобально"
+	}
+
+	var chatID int64
+	if len(scope) == 1 {
+		chatID = scope[0]
+	}
+	if !hasRole(h.UserRole(req.UserID, chatID), RoleOwner) {
+		return 0, fmt.Sprintf("❌ Вы не владелец (%s)", grantScope(chatID))
+	}
+	return chatID, ""
+}
+
+// showAdmins показывает всех назначенных администраторов
+func (h *BotDatabaseHandler) showAdmins() string {
+	grants, err := h.ListAdmins()
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+
+	if len(grants) == 0 {
+		return "👑 Администраторов нет"
+	}
+
+	var result strings.Builder
+	result.WriteString("👑 Администраторы:\n")
+	for _, g := range grants {
+		result.WriteString(fmt.Sprintf("• %d - %s (%s), выдал %d\n", g.UserID, g.Role, grantScope(g.ChatID), g.GrantedBy))
+	}
+	return result.String()
+}
+
+// viewableChats возвращает чаты, в которых у пользователя есть роль: назначенная
+// или, для текущего чата, права его администратора в Telegram. true - роль глобальная, видно все.
+func (h *BotDatabaseHandler) viewableChats(userID, chatID int64) (Scope, bool) {
+	if h.UserRole(userID, 0) != "" {
+		return nil, true
+	}
+
+	var chats Scope
+	h.mu.RLock()
+	for id := range h.roles[userID] {
+		if id != 0 {
+			chats = append(chats, id)
+		}
+	}
+	h.mu.RUnlock()
+
+	if chatID < 0 && !slices.Contains(chats, chatID) && h.UserRole(userID, chatID) != "" {
+		chats = append(chats, chatID)
+	}
+	slices.Sort(chats)
+	return chats, false
+}
+
+// canViewScope проверяет, что пользователь может смотреть триггеры области:
+// глобальные видны всем админам, триггеры чатов - при роли хотя бы в одном из них
+func (h *BotDatabaseHandler) canViewScope(userID, chatID int64, scope Scope) bool {
+	chats, all := h.viewableChats(userID, chatID)
+	if all || scope.IsGlobal() {
+		return true
+	}
+	for _, id := range scope {
+		if slices.Contains(chats, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// clampFilter сужает фильтр list, search, export и trash до чатов, где у пользователя есть роль,
+// чтобы зритель или редактор одного чата не видел триггеры остальных.
+// С глобальной ролью фильтр не меняется.
+func (h *BotDatabaseHandler) clampFilter(userID, chatID int64, filter TriggerFilter) (TriggerFilter, error) {
+	chats, all := h.viewableChats(userID, chatID)
+	if all {
+		return filter, nil
+	}
+
+	switch {
+	case filter.AllScopes:
+		// Глобальные триггеры действуют и в чатах пользователя, поэтому они остаются в выборке
+		filter.AllScopes, filter.Scope = false, chats
+	case filter.Scope.IsGlobal():
+	default:
+		for _, id := range filter.Scope {
+			if !slices.Contains(chats, id) {
+				return TriggerFilter{}, fmt.Errorf("нет прав на чат %d", id)
+			}
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// handleChatCommand обрабатывает /admin chat - настройки текущего чата
+func (h *BotDatabaseHandler) handleChatCommand(chatID int64, args []string) string {
+	if len(args) == 0 {
+		return h.showChatSettings(chatID)
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "policy", "политика":
+		if len(args) < 2 {
+			return "❌ Использование: /admin chat policy <silent|first|priority|all|random> [N]"
+		}
+		maxReplies := 0
+		if len(args) >= 3 {
+			n, err := strconv.Atoi(args[2])
+			if err != nil || n <= 0 {
+				return "❌ N должно быть положительным числом"
+			}
+			maxReplies = n
+		}
+
+		if err := h.SetMultiMatchPolicy(chatID, args[1], maxReplies); err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+		return "✅ Политика обновлена\n\n" + h.showChatSettings(chatID)
+
+	case "reply", "ответ":
+		if len(args) < 2 {
+			return "❌ Использование: /admin chat reply <reply|plain>"
+		}
+		if err := h.SetReplyMode(chatID, args[1]); err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+		return "✅ Режим ответа обновлен\n\n" + h.showChatSettings(chatID)
+
+	case "edits", "правки":
+		if len(args) < 2 {
+			return "❌ Использование: /admin chat edits <ignore|respond|sync>"
+		}
+		if err := h.SetEditPolicy(chatID, args[1]); err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+		return "✅ Политика правок обновлена\n\n" + h.showChatSettings(chatID)
+
+	case "admins", "админы":
+		if len(args) < 2 {
+			return "❌ Использование: /admin chat admins <on|off>"
+		}
+		var enabled bool
+		switch strings.ToLower(args[1]) {
+		case "on", "вкл":
+			enabled = true
+		case "off", "выкл":
+		default:
+			return "❌ Использование: /admin chat admins <on|off>"
+		}
+		if err := h.SetChatAdminsEdit(chatID, enabled); err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+		return "✅ Права админов чата обновлены\n\n" + h.showChatSettings(chatID)
+
+	case "tz", "timezone", "пояс":
+		if len(args) < 2 {
+			return "❌ Использование: /admin chat tz <часовой пояс>\nПример: /admin chat tz Europe/Moscow"
+		}
+		if err := h.SetChatTimezone(chatID, args[1]); err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+		return "✅ Часовой пояс обновлен\n\n" + h.showChatSettings(chatID)
+
+	case "cooldown", "кулдаун", "usercooldown", "пауза":
+		if len(args) < 2 {
+			return "❌ Использование: /admin chat cooldown <30s|off> или /admin chat usercooldown <1m|off>"
+		}
+		cooldown, err := ParseCooldown(args[1])
+		if err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+		if strings.ToLower(args[0]) == "usercooldown" || strings.ToLower(args[0]) == "пауза" {
+			err = h.SetChatCooldowns(chatID, nil, &cooldown)
+		} else {
+			err = h.SetChatCooldowns(chatID, &cooldown, nil)
+		}
+		if err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+		return "✅ Пауза обновлена\n\n" + h.showChatSettings(chatID)
+
+	case "budget", "бюджет":
+		if len(args) == 2 && (strings.ToLower(args[1]) == "off" || args[1] == "0") {
+			if err := h.SetChatBudget(chatID, 0, 0); err != nil {
+				return fmt.Sprintf("❌ Ошибка: %v", err)
+			}
+			return "✅ Бюджет ответов снят\n\n" + h.showChatSettings(chatID)
+		}
+		if len(args) < 3 {
+			return "❌ Использование: /admin chat budget <N> <окно> (например 20 1h) или /admin chat budget off"
+		}
+		count, err := strconv.Atoi(args[1])
+		if err != nil || count <= 0 {
+			return "❌ N должно быть положительным числом"
+		}
+		window, err := ParseCooldown(args[2])
+		if err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+		if err := h.SetChatBudget(chatID, count, window); err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+		return "✅ Бюджет ответов обновлен\n\n" + h.showChatSettings(chatID)
+
+	case "off", "disable", "выкл":
+		if len(args) < 2 {
+			return "❌ Использование: /admin chat off <ключ>"
+		}
+		key := strings.Join(args[1:], " ")
+		if err := h.DisableTriggerInChat(chatID, key); err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+		return fmt.Sprintf("✅ `%s` отключен в этом чате", codeSafe(key))
+
+	case "on", "enable", "вкл":
+		if len(args) < 2 {
+			return "❌ Использование: /admin chat on <ключ>"
+		}
+		key := strings.Join(args[1:], " ")
+		if err := h.EnableTriggerInChat(chatID, key); err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+		return fmt.Sprintf("✅ `%s` снова работает в этом чате", codeSafe(key))
+
+	default:
+		return "❌ Неизвестная настройка. Используйте /admin chat для просмотра настроек"
+	}
+}
+
+// showChatSettings форматирует настройки чата для админа
+func (h *BotDatabaseHandler) showChatSettings(chatID int64) string {
+	s := h.GetChatSettings(chatID)
+
+	policy := s.MultiMatchPolicy
+	if policy == PolicyAll {
+		policy = fmt.Sprintf("%s (до %d ответов)", policy, s.MaxReplies)
+	}
+
+	budget := "нет"
+	if s.BudgetCount > 0 {
+		budget = fmt.Sprintf("%d за %s", s.BudgetCount, formatCooldown(s.BudgetWindow))
+	}
+
+	adminsEdit := "нет"
+	if s.ChatAdminsEdit {
+		adminsEdit = "да"
+	}
+
+	disabled := "нет"
+	if keys, err := h.ListDisabledTriggers(chatID); err != nil {
+		disabled = fmt.Sprintf("ошибка: %v", err)
+	} else if len(keys) > 0 {
+		disabled = strings.Join(keys, ", ")
+	}
+
+	return fmt.Sprintf("⚙️ Настройки чата %d:\n"+
+		"• Несколько совпадений: %s\n"+
+		"• Часовой пояс: %s\n"+
+		"• Режим ответа: %s\n"+
+		"• Правки сообщений: %s\n"+
+		"• Админы чата - редакторы: %s\n"+
+		"• Пауза между ответами: %s, одному участнику: %s\n"+
+		"• Бюджет ответов: %s\n"+
+		"• Отключенные триггеры: %s\n\n"+
+		"Политики: silent - молчать, first - первый по тексту,\n"+
+		"priority - наибольший приоритет, all [N] - все (до N), random - случайный",
+		chatID, policy, s.Timezone, s.ReplyMode, s.EditPolicy, adminsEdit, formatCooldown(s.ChatCooldown), formatCooldown(s.UserCooldown), budget, disabled)
+}
@@ -2,170 +2,607 @@ package database
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// AdminRequest - команда администратора вместе с контекстом, в котором она вызвана
+type AdminRequest struct {
+	UserID  int64  // кто вызвал команду
+	ChatID  int64  // в каком чате
+	Command string // полный текст команды, включая /admin
+
+	// ReplyMedia - медиа из сообщения, на которое ответили командой (стикер, фото и т.п.).
+	// Его file_id становится ответом триггера в /admin add.
+	ReplyMedia *ResponsePart
+
+	// Текст или подпись того же сообщения - что проверяет /admin test
+	ReplyText string
+
+	// Стикер и кастомные эмодзи того же сообщения - ключ триггера в /admin react
+	ReplySticker        *StickerInfo
+	ReplyCustomEmojiIDs []string
+
+	// Автор того же сообщения - кому назначается роль в /admin grant
+	ReplyUserID int64
+
+	// Файл из того же сообщения - источник для /admin import
+	ReplyFile *AttachedFile
+}
+
+// AttachedFile - документ из Telegram. Скачивается только командой, которой он нужен.
+type AttachedFile struct {
+	Name string
+	Size int
+	Load func() ([]byte, error)
+}
+
+// AdminReply - ответ на команду администратора: текст и, для экспорта, файл.
+// В Text `обратные кавычки` выделяют моноширинный текст, остальное бот экранирует сам,
+// поэтому любые символы из ключей и ответов безопасны. Обратные кавычки в них заменяются на '.
+type AdminReply struct {
+	Text     string
+	Document *AdminDocument
+	Buttons  [][]AdminButton // инлайн-клавиатура под сообщением, по рядам
+	Notice   string          // всплывающее уведомление в ответ на нажатие кнопки
+}
+
+// AdminButton - кнопка инлайн-клавиатуры; Data возвращается боту в callback_query
+type AdminButton struct {
+	Text string
+	Data string
+}
+
+// AdminDocument - файл, который бот отправляет админу
+type AdminDocument struct {
+	Name string
+	Data []byte
+}
+
+// actor возвращает автора команды для журнала изменений
+func (r AdminRequest) actor() Actor {
+	return Actor{UserID: r.UserID, ChatID: r.ChatID}
+}
+
+// args возвращает аргументы команды без "/admin"
+func (r AdminRequest) args() string {
+	return strings.TrimSpace(strings.TrimPrefix(r.Command, "/admin"))
+}
+
 // HandleAdminCommand обрабатывает команды администратора для bushlatinga_bot
-func (h *BotDatabaseHandler) HandleAdminCommand(userID int64, command string) string {
-	// Проверяем права администратора
-	if !h.IsAdmin(userID) {
-		return "❌ У вас нет прав для выполнения этой команды"
+func (h *BotDatabaseHandler) HandleAdminCommand(req AdminRequest) AdminReply {
+	parts := splitAdminArgs(req.args())
+
+	subCommand := ""
+	if len(parts) > 0 {
+		subCommand = strings.ToLower(parts[0])
 	}
 
-	// Убираем "/admin " из команды
-	argsStr := strings.TrimSpace(strings.TrimPrefix(command, "/admin"))
-	parts := strings.Fields(argsStr)
+	// Проверяем права: роль пользователя и область, которую меняет команда
+	if denied := h.authorizeAdminCommand(req, subCommand, parts[min(1, len(parts)):]); denied != "" {
+		return AdminReply{Text: denied}
+	}
+
+	// Экспорт и импорт работают с файлами, списки - с кнопками, остальные команды отвечают текстом
+	switch subCommand {
+	case "export", "экспорт":
+		return h.handleExportCommand(req, parts[1:])
+	case "import", "импорт":
+		return AdminReply{Text: h.handleImportCommand(req, parts[1:])}
+	case "list", "список", "все":
+		return h.handleListCommand(req, parts[1:], false)
+	case "search", "найти", "поиск":
+		return h.handleListCommand(req, parts[1:], true)
+	}
+	return AdminReply{Text: h.handleTextCommand(req, subCommand, parts)}
+}
 
+// handleTextCommand выполняет команду администратора с текстовым ответом
+func (h *BotDatabaseHandler) handleTextCommand(req AdminRequest, subCommand string, parts []string) string {
 	if len(parts) == 0 {
 		return h.showAdminHelp()
 	}
 
-	subCommand := strings.ToLower(parts[0])
-
 	switch subCommand {
+	case "grant", "выдать":
+		return h.handleGrantCommand(req, parts[1:])
+
+	case "revoke", "отозвать":
+		return h.handleRevokeCommand(req, parts[1:])
+
+	case "admins", "админы":
+		return h.showAdmins()
+
 	case "add", "добавить":
-		if len(parts) < 3 {
-			return "❌ Использование: /admin add <ключ> <значение>\nПример: /admin add привет Привет!"
-		}
-		key := parts[1]
-		value := strings.Join(parts[2:], " ")
+		return h.handleAddCommand(req, parts[1:], false)
+
+	case "replace", "заменить":
+		return h.handleAddCommand(req, parts[1:], true)
+
+	case "append", "дописать":
+		return h.handleAppendCommand(req, parts[1:])
+
+	case "react", "реакция":
+		return h.handleReactCommand(req, parts[1:])
+
+	case "remove", "удалить", "del":
+		return h.handleRemoveCommand(req, parts[1:])
+
+	case "stats", "статистика":
+		return h.handleStatsCommand(req, parts[1:])
+
+	case "count", "количество":
+		count := h.GetMappingCount()
+		role := h.UserRole(req.UserID, req.ChatID)
+		return fmt.Sprintf("📊 Статистика:\n• Всего фраз: %d\n• Ваша роль: %s", count, role)
 
-		if err := h.AddMapping(key, value); err != nil {
+	case "cooldown", "кулдаун":
+		opts, args := parseAdminOptions(parts[1:])
+		if len(args) != 2 {
+			return "❌ Использование: /admin cooldown [scope=...] <ключ> <10m|1h|off>"
+		}
+		scope, err := scopeOption(opts, req.ChatID)
+		if err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+		cooldown, err := ParseCooldown(args[1])
+		if err != nil {
 			return fmt.Sprintf("❌ Ошибка: %v", err)
 		}
-		return fmt.Sprintf("✅ Добавлено:\n`%s` → `%s`", key, value)
+		if err := h.SetTriggerCooldown(req.actor(), args[0], scope, cooldown); err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+		return fmt.Sprintf("✅ Кулдаун `%s`: %s", codeSafe(args[0]), formatCooldown(cooldown))
 
-	case "remove", "удалить", "del":
-		if len(parts) < 2 {
-			return "❌ Использование: /admin remove <ключ>"
+	case "chance", "шанс":
+		opts, args := parseAdminOptions(parts[1:])
+		if len(args) != 2 {
+			return "❌ Использование: /admin chance [scope=...] <ключ> <1-100>"
+		}
+		scope, err := scopeOption(opts, req.ChatID)
+		if err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
 		}
-		key := parts[1]
+		chance, err := ParseProbability(args[1])
+		if err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+		if err := h.SetTriggerChance(req.actor(), args[0], scope, chance); err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+		return fmt.Sprintf("✅ `%s` отвечает с вероятностью %d%%", codeSafe(args[0]), chance)
 
-		if err := h.RemoveMapping(key); err != nil {
+	case "schedule", "расписание":
+		opts, args := parseAdminOptions(parts[1:])
+		if len(args) < 2 {
+			return "❌ Использование: /admin schedule [scope=...] <ключ> <расписание|off>\n" +
+				"Пример: /admin schedule славик пн-пт 09:00-18:00"
+		}
+		scope, err := scopeOption(opts, req.ChatID)
+		if err != nil {
 			return fmt.Sprintf("❌ Ошибка: %v", err)
 		}
-		return fmt.Sprintf("✅ Удалено: `%s`", key)
+		spec := strings.Join(args[1:], " ")
+		if err := h.SetTriggerSchedule(req.actor(), args[0], scope, spec); err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+		if schedule, _ := ParseSchedule(spec); schedule == nil {
+			return fmt.Sprintf("✅ `%s` активен всегда", codeSafe(args[0]))
+		}
+		return fmt.Sprintf("✅ Расписание `%s`: %s (часовой пояс чата)", codeSafe(args[0]), spec)
 
-	case "list", "список", "все":
-		mapping := h.GetMapping()
-		if len(mapping) == 0 {
-			return "📭 База данных пуста. Добавьте фразы через /admin add"
+	case "fuzzy", "нечетко":
+		opts, args := parseAdminOptions(parts[1:])
+		if len(args) != 2 {
+			return "❌ Использование: /admin fuzzy [scope=...] <ключ> <off|on|1|2>"
+		}
+		scope, err := scopeOption(opts, req.ChatID)
+		if err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+		fuzzy, err := ParseFuzzy(args[1])
+		if err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+		if err := h.SetTriggerFuzzy(req.actor(), args[0], scope, fuzzy); err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
 		}
+		return fmt.Sprintf("✅ Нечеткое сравнение `%s`: %s", codeSafe(args[0]), formatFuzzy(fuzzy))
 
-		var result strings.Builder
-		result.WriteString(fmt.Sprintf("📋 Всего записей: %d\n\n", len(mapping)))
+	case "enable", "включить":
+		return h.handleEnableCommand(req, parts[1:], true)
 
-		count := 0
-		for k, v := range mapping {
-			count++
-			safeKey := strings.ReplaceAll(k, "`", "'")
-			safeValue := strings.ReplaceAll(v, "`", "'")
-			result.WriteString(fmt.Sprintf("%d. `%s`\n   → %s\n\n", count, safeKey, safeValue))
-			if count >= 30 {
-				result.WriteString(fmt.Sprintf("\n... и еще %d записей\n", len(mapping)-count))
-				break
-			}
-		}
-		return result.String()
+	case "disable", "выключить":
+		return h.handleEnableCommand(req, parts[1:], false)
 
-	case "search", "найти", "поиск":
-		if len(parts) < 2 {
-			return "❌ Использование: /admin search <текст>"
-		}
-		searchText := strings.Join(parts[1:], " ")
-		results := h.SearchInValues(searchText)
+	case "expire", "срок":
+		return h.handleExpireCommand(req, parts[1:])
 
-		if len(results) == 0 {
-			return fmt.Sprintf("🔍 Не найдено записей содержащих '%s'", searchText)
-		}
+	case "trash", "корзина":
+		return h.handleTrashCommand(req, parts[1:])
 
-		var result strings.Builder
-		result.WriteString(fmt.Sprintf("🔍 Найдено %d записей:\n\n", len(results)))
+	case "history", "история":
+		return h.handleHistoryCommand(req, parts[1:])
 
-		count := 0
-		for k, v := range results {
-			count++
-			safeKey := strings.ReplaceAll(k, "`", "'")
-			safeValue := strings.ReplaceAll(v, "`", "'")
-			result.WriteString(fmt.Sprintf("%d. `%s`\n   → %s\n\n", count, safeKey, safeValue))
-		}
-		return result.String()
+	case "undo", "отменить":
+		return h.handleUndoCommand(req)
 
-	case "count", "количество":
-		count := h.GetMappingCount()
-		return fmt.Sprintf("📊 Статистика:\n• Всего фраз: %d\n• Админ ID: %d", count, h.adminID)
+	case "restore", "восстановить":
+		return h.handleRestoreCommand(req, parts[1:])
+
+	case "chat", "чат":
+		return h.handleChatCommand(req.ChatID, parts[1:])
 
 	case "help", "помощь":
 		return h.showAdminHelp()
 
-	case "export", "экспорт":
-		mapping := h.GetMapping()
-		var result strings.Builder
-		result.WriteString("�� Экспорт данных:\n\n")
-		for k, v := range mapping {
-			safeKey := strings.ReplaceAll(k, "`", "'")
-			safeValue := strings.ReplaceAll(v, "`", "'")
-			result.WriteString(fmt.Sprintf("`%s` → `%s`\n", safeKey, safeValue))
-		}
-		return result.String()
-
 	case "info", "инфо":
-		return "🤖 *bushlatinga_bot v2.0*\n\n" +
+		return "🤖 bushlatinga_bot v2.0\n\n" +
 			"• База данных: Supabase PostgreSQL\n" +
 			"• Схема: bushlatinga_bot (фразы), main (логи)\n" +
 			"• Админ команды: /admin help\n" +
 			"• Фразы сохраняются в облаке\n" +
-			"• Работа напрямую с БД (без кэша)\n" +
-			"• Отвечает только на одно совпадение\n" +
-			"• ЕБ-детектор активен\n\n" +
+			"• Индекс фраз в памяти, синхронизация через LISTEN/NOTIFY\n" +
+			"• Несколько совпадений: по политике чата (/admin chat)\n" +
+			"• Триггеры глобальные или для отдельных чатов (scope=)\n" +
+			"• Детекторы точных слов: /admin detectors\n\n" +
 			"Используйте /admin help для списка команд"
 
+	case "detector", "detectors", "детектор", "детекторы":
+		return h.handleDetectorCommand(req, parts[1:])
+
 	case "test", "тест":
-		// Тестовая команда для проверки ЕБ
-		testMessage := "Тест ЕБ функции"
-		ebFound := CheckForEB(testMessage)
-		ebResult := "❌ Не найдено"
-		if ebFound {
-			ebResult = "✅ Найдено"
+		return h.handleTestCommand(req, parts[1:])
+
+	default:
+		return "❌ Неизвестная команда. Используйте /admin help для списка команд"
+	}
+}
+
+// handleAddCommand обрабатывает /admin add и /admin replace.
+// Если команда отправлена ответом на медиа, ответом триггера становится это медиа.
+// Ключ из нескольких слов берется в кавычки, значение - весь остаток команды с переводами строк.
+// /admin add без ключа начинает пошаговый диалог.
+func (h *BotDatabaseHandler) handleAddCommand(req AdminRequest, parts []string, replace bool) string {
+	opts, args, value := addCommandArgs(req.args(), parts)
+	if len(args) == 0 && req.ReplyMedia == nil && !replace {
+		return h.startAddDialog(req, opts)
+	}
+	if len(args) < 2 && !(len(args) == 1 && req.ReplyMedia != nil) {
+		return "❌ Использование: /admin add [type=text|regex] [mode=substring|word|stem] [priority=N] [weight=N] <ключ> <значение>\n" +
+			"Пример: /admin add mode=stem \"славик привет\" Привет!\n" +
+			"Или ответьте командой /admin add <ключ> [текст] на стикер, фото, GIF, голосовое или документ\n" +
+			"Или /admin add без аргументов - бот спросит ключ, ответы и опции по шагам"
+	}
+
+	return h.saveFromCommand(req, opts, args[0], value, replace)
+}
+
+// addCommandArgs разбирает аргументы /admin add и /admin replace: parts - аргументы после подкоманды,
+// command - вся строка аргументов. Значение берется из command после подкоманды, опций и ключа как есть,
+// с переводами строк.
+func addCommandArgs(command string, parts []string) (map[string]string, []string, string) {
+	opts, args := parseAdminOptions(parts)
+	value := argsTail(command, 1+len(parts)-len(args)+1)
+	return opts, args, value
+}
+
+// saveFromCommand сохраняет ответ триггера с опциями из команды админа
+func (h *BotDatabaseHandler) saveFromCommand(req AdminRequest, opts map[string]string, key, value string, replace bool) string {
+	responseParts, err := buildResponseParts(req.ReplyMedia, value, opts["response"])
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+
+	triggerOpts, err := triggerOptions(opts, req.ChatID, h.chatLocation(req.ChatID))
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+	scope, weight := triggerOpts.Scope, triggerOpts.Weight
+
+	save := h.AddMapping
+	if replace {
+		save = h.ReplaceMapping
+	}
+	if err := save(req.actor(), key, responseParts, triggerOpts); err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+
+	description := codeSafe((&TriggerResponse{Parts: responseParts}).Describe())
+	if replace {
+		return fmt.Sprintf("✅ Ответы заменены (%s):\n`%s` → `%s`", scope, codeSafe(key), description)
+	}
+	return fmt.Sprintf("✅ Добавлен ответ (вес %d, %s):\n`%s` → `%s`", weight, scope, codeSafe(key), description)
+}
+
+// handleReactCommand обрабатывает /admin react: ответом на стикер или сообщение
+// с кастомным эмодзи создает медиа-триггер, ключ берется из этого сообщения
+func (h *BotDatabaseHandler) handleReactCommand(req AdminRequest, parts []string) string {
+	opts, args := parseAdminOptions(parts)
+	if len(args) < 1 {
+		return "❌ Использование: ответьте на стикер командой\n" +
+			"/admin react [type=sticker|sticker_set|emoji|custom_emoji] <ответ>"
+	}
+
+	triggerType := TriggerSticker
+	if opts["type"] != "" {
+		var err error
+		if triggerType, err = ParseTriggerType(opts["type"]); err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+	} else if req.ReplySticker == nil && len(req.ReplyCustomEmojiIDs) > 0 {
+		triggerType = TriggerCustomEmoji
+	}
+
+	key, err := MediaTriggerKey(triggerType, req.ReplySticker, req.ReplyCustomEmojiIDs)
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+
+	// Дальше как обычный /admin add, но медиа из ответа - это ключ, а не ответ
+	opts["type"] = triggerType
+	req.ReplyMedia = nil
+	return h.saveFromCommand(req, opts, key, strings.Join(args, " "), false)
+}
+
+// handleRemoveCommand обрабатывает /admin remove <ключ> [N]: удаляет триггер
+// или только N-й вариант ответа в области команды
+func (h *BotDatabaseHandler) handleRemoveCommand(req AdminRequest, parts []string) string {
+	opts, args := parseAdminOptions(parts)
+	if len(args) < 1 || len(args) > 2 {
+		return "❌ Использование: /admin remove [scope=here|global|ID] <ключ> [номер ответа]"
+	}
+	key := args[0]
+
+	scope, err := scopeOption(opts, req.ChatID)
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+
+	// /admin remove <ключ> <N> удаляет только один вариант ответа
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			return "❌ Номер ответа должен быть числом"
+		}
+		if err := h.RemoveResponse(req.actor(), key, scope, n); err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
 		}
+		return fmt.Sprintf("✅ Удален ответ %d у `%s` (%s)", n, codeSafe(key), scope)
+	}
 
-		return fmt.Sprintf("🧪 Тест ЕБ-детектора:\n"+
-			"Сообщение: '%s'\n"+
-			"Результат: %s\n\n"+
-			"Тест: напишите 'ЕБ' большими буквами", testMessage, ebResult)
+	if err := h.RemoveMapping(req.actor(), key, scope); err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+	return fmt.Sprintf("🗑 Удалено в корзину: `%s` (%s)\nВернуть: /admin restore %s", codeSafe(key), scope, key)
+}
 
+// handleAppendCommand обрабатывает /admin append: дописывает сообщение
+// к последнему ответу триггера, превращая его в последовательность
+func (h *BotDatabaseHandler) handleAppendCommand(req AdminRequest, parts []string) string {
+	opts, args := parseAdminOptions(parts)
+	if len(args) < 2 && !(len(args) == 1 && req.ReplyMedia != nil) {
+		return "❌ Использование: /admin append <ключ> <текст>\nИли ответьте командой /admin append <ключ> на медиа"
+	}
+	key := args[0]
+
+	responseParts, err := buildResponseParts(req.ReplyMedia, strings.Join(args[1:], " "), opts["response"])
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+
+	scope, err := scopeOption(opts, req.ChatID)
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+
+	if err := h.AppendToLastResponse(req.actor(), key, scope, responseParts); err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+	return fmt.Sprintf("✅ Последний ответ `%s` дополнен", codeSafe(key))
+}
+
+// buildResponseParts собирает ответ из медиа, на которое ответили командой, и текста.
+// Текст становится подписью, если медиа ее поддерживает, иначе - отдельным сообщением.
+func buildResponseParts(media *ResponsePart, text, responseType string) ([]ResponsePart, error) {
+	switch strings.ToLower(responseType) {
+	case "", ResponseText, "текст":
+	case ResponseDice, "кубик":
+		emoji := strings.TrimSpace(text)
+		if emoji == "" {
+			emoji = "🎲"
+		}
+		return []ResponsePart{{Type: ResponseDice, Text: emoji}}, nil
 	default:
-		return "❌ Неизвестная команда. Используйте /admin help для списка команд"
+		return nil, fmt.Errorf("опция response поддерживает только text и dice, медиа добавляется ответом на сообщение")
 	}
+
+	if media == nil {
+		return TextParts(text), nil
+	}
+
+	part := *media
+	if text == "" {
+		return []ResponsePart{part}, nil
+	}
+	if part.SupportsCaption() && part.Text == "" {
+		part.Text = text
+		return []ResponsePart{part}, nil
+	}
+	return []ResponsePart{part, {Type: ResponseText, Text: text}}, nil
+}
+
+// formatTriggerEntry форматирует триггер со всеми вариантами ответа для /admin list и search
+func formatTriggerEntry(n int, t *Trigger) string {
+	safeKey := codeSafe(t.Text)
+
+	kind := t.MatchMode
+	if t.Type != TriggerText {
+		kind = t.Type
+	}
+	if !t.Enabled {
+		kind += ", выключен"
+	}
+	if !t.ExpiresAt.IsZero() {
+		if t.expired(time.Now()) {
+			kind += ", срок истек"
+		} else {
+			kind += ", до " + formatExpiry(t.ExpiresAt, time.UTC) + " UTC"
+		}
+	}
+	if t.Priority != 0 {
+		kind += fmt.Sprintf(", приоритет %d", t.Priority)
+	}
+	if !t.ChatIDs.IsGlobal() {
+		kind += ", " + t.ChatIDs.String()
+	}
+	if t.Cooldown > 0 {
+		kind += ", кулдаун " + formatCooldown(t.Cooldown)
+	}
+	if t.Chance < defaultProbability {
+		kind += fmt.Sprintf(", шанс %d%%", t.Chance)
+	}
+	if t.Schedule != nil {
+		kind += ", когда: " + t.Schedule.String()
+	}
+	if t.Fuzzy != FuzzyOff {
+		kind += ", нечетко: " + formatFuzzy(t.Fuzzy)
+	}
+	if t.Hits > 0 {
+		kind += fmt.Sprintf(", срабатываний %d", t.Hits)
+	}
+
+	var entry strings.Builder
+	entry.WriteString(fmt.Sprintf("%d. `%s` [%s]\n", n, safeKey, kind))
+	for i, r := range t.Responses {
+		safeValue := codeSafe(r.Describe())
+		if len(t.Responses) > 1 {
+			entry.WriteString(fmt.Sprintf("   %d) %s", i+1, safeValue))
+		} else {
+			entry.WriteString("   → " + safeValue)
+		}
+		if r.Weight != 1 {
+			entry.WriteString(fmt.Sprintf(" (вес %d)", r.Weight))
+		}
+		entry.WriteString("\n")
+	}
+	entry.WriteString("\n")
+	return entry.String()
 }
 
 func (h *BotDatabaseHandler) showAdminHelp() string {
 	return `🛠️ Команды администратора:
 
 📝 Добавление/удаление:
-/admin add <ключ> <значение> - Добавить новую запись
-/admin remove <ключ> - Удалить запись
+/admin add [type=...] [mode=...] [priority=N] [weight=N] [scope=...] [cooldown=10m] [chance=N] [schedule=...] [fuzzy=on|1|2] [expires=7d] <ключ> <значение> - Добавить вариант ответа
+/admin replace [опции] <ключ> <значение> - Заменить все ответы одним
+/admin append <ключ> [текст] - Дописать сообщение к последнему ответу
+/admin add - Пошагово: бот спросит ключ, ответы и опции (кнопками), /cancel - отменить
+Ключ из нескольких слов - в кавычках: /admin add "доброе утро" Привет!
+Значение может быть многострочным
+
+🖼 Медиа-ответы:
+Ответьте командой /admin add <ключ> [текст] на стикер, фото, GIF,
+голосовое, кружок или документ - бот запомнит его file_id.
+/admin add response=dice <ключ> 🎯 - Ответить кубиком
+/admin remove <ключ> - Удалить запись в корзину
+/admin remove <ключ> <N> - Удалить N-й вариант ответа
+/admin disable <ключ> - Выключить, не удаляя; /admin enable <ключ> - включить
+/admin expire <ключ> <7d|12h|2025-01-31|off> - Временная шутка: после срока ключ выключится сам
+/admin cooldown <ключ> <10m|off> - Не отвечать на ключ в чате чаще, чем раз в 10 минут
+/admin chance <ключ> <1-100> - Отвечать только в N% случаев
+/admin schedule <ключ> <расписание|off> - Когда ключ активен (пн-пт 09:00-18:00)
+/admin fuzzy <ключ> <off|on|1|2> - Находить "slavik", "cлавик", "сла-вик", "славиииик"; 1-2 - еще и с опечатками
+
+🎯 Детекторы:
+/admin detectors - Список детекторов точных слов
+/admin detector add [priority=N] <слово|вариант> <ответ> - Добавить детектор
+/admin detector on|off <ключ> - Включить или выключить детектор
+
+😀 Реакции на стикеры и эмодзи:
+Ответьте на стикер командой /admin react <ответ> - бот будет отвечать на этот стикер
+/admin react type=sticker_set <ответ> - на любой стикер из набора
+/admin react type=emoji <ответ> - на любой стикер с тем же эмодзи
+/admin react type=custom_emoji <ответ> - на кастомный эмодзи из сообщения
+Подписи к фото и GIF проверяются как обычный текст
+
+⚙️ Настройки чата:
+/admin chat - Показать настройки текущего чата
+/admin chat policy <silent|first|priority|all|random> [N] - Что делать при нескольких совпадениях
+/admin chat reply <reply|plain> - Отвечать цитатой на сообщение или обычным сообщением
+/admin chat edits <ignore|respond|sync> - Правки сообщений: не реагировать, отвечать на новые триггеры, еще и удалять ответы на убранные
+/admin chat tz <пояс> - Часовой пояс для {{.date}} и {{.time}}
+/admin chat cooldown <30s|off> - Пауза между любыми ответами бота в чате
+/admin chat usercooldown <1m|off> - Пауза между ответами одному участнику
+/admin chat budget <N> <1h> | off - Не больше N ответов за окно
+/admin chat off <ключ> - Отключить глобальный триггер в этом чате
+/admin chat on <ключ> - Включить его обратно
+/admin chat admins <on|off> - Админы чата в Telegram - редакторы его триггеров
+
+👑 Администраторы (владелец):
+/admin admins - Кому назначены роли
+/admin grant [scope=here|global|ID] <user_id> <owner|editor|viewer> - Назначить роль
+Или ответьте на сообщение пользователя: /admin grant <роль>
+/admin revoke [scope=...] <user_id> - Отозвать роль
+viewer - только просмотр, editor - изменение триггеров, owner - еще и роли
+
+📜 История изменений:
+/admin history <ключ> - Кто и когда менял ключ, с номерами версий
+/admin undo - Отменить свое последнее изменение
+/admin restore <ключ> <версия> - Вернуть ключ к версии из истории
+/admin trash [scope=...] - Корзина: удаленные за последние 30 дней
+/admin restore <ключ> - Вернуть ключ из корзины
 
 🔍 Поиск и просмотр:
-/admin list - Показать все записи (первые 30)
-/admin search <текст> - Найти текст в значениях
+/admin list [scope=...] [type=...] [created=...] [hits=...] - Список по страницам с кнопками
+/admin search [опции list] <текст> - Найти текст в значениях
+Кнопки: ◀️ ▶️ - листать, ✏️ - карточка триггера (выключить, команды изменения), 🗑 - удалить
+Фильтры: created=2024-12-01..2024-12-31 - дата создания, hits=0 - ни разу не срабатывал, hits=10.. - от 10 раз
 /admin count - Показать количество записей
+/admin stats [scope=here|ID|all] [дней] - Популярные триггеры, триггеры без ответов за 30 дней, разбивка по чатам
 
 📁 Экспорт и информация:
-/admin export - Показать все записи для экспорта
+/admin export [scope=...] [json|csv] - Выгрузить триггеры файлом
+Ответьте на файл командой /admin import [merge|replace] - Предпросмотр импорта: что добавится, изменится, удалится
+/admin import [merge|replace] apply - Применить импорт (только если в файле нет ошибок)
 /admin info - Информация о боте
-/admin test - Протестировать ЕБ-детектор
+/admin test <текст> - Как бот ответил бы: найденные триггеры, политика, лимиты и ответ (без отправки)
+Или ответьте командой /admin test на любое сообщение
 /admin help - Эта справка
 
 Примеры:
 /admin add славик Славик абсолютно конченная поебота
+/admin add weight=3 славик Славик, привет
+/admin add mode=stem славик Ответ и на "славику", и на "славиком"
+/admin add type=regex сла+в(?P<who>ик|ян) Снова {{.who}}!
 /admin remove славик
+/admin add scope=global славик Ответ для всех чатов
+/admin list scope=all
 /admin search спасибо
-/admin test
+/admin chat policy all 2
+/admin test привет, славик
 
 📌 Примечания:
-• Бот отвечает только на ОДНО совпадение в сообщении!
-• "ЕБ" проверяется как отдельное слово большими буквами
+• По умолчанию бот отвечает только на ОДНО совпадение в сообщении (политика silent)
+• Детекторы (type=exact) ищут отдельное слово с учетом регистра, например "ЕБ|ЁБ";
+  с приоритетом выше найденных триггеров отвечают вместо них
+• Режимы: substring - подстрока (по умолчанию), word - целое слово,
+  stem - слово с падежными окончаниями
+• type=regex - регулярное выражение без учета регистра,
+  именованные группы (?P<имя>...) подставляются в ответ как {{.имя}}
+• В ответах работают подстановки: {{.first_name}}, {{.username}},
+  {{.trigger}}, {{.match}}, {{.chat}}, {{.date}}, {{.time}},
+  {{.weekday}}, {{.random_member}}
+• scope= - где действует триггер: here (по умолчанию - чат, где дана команда,
+  в личке - глобально), global или ID чатов через запятую; в list и search еще all.
+  Триггер чата с тем же ключом заменяет в этом чате глобальный
+• Расписание - дни недели (пн-пт, сб,вс), часы (22:00-02:00) и даты
+  (2024-12-25..2025-01-08) через пробел, в опции schedule= через ";",
+  в часовом поясе чата
+• Кулдауны и бюджет хранятся в БД: переживают перезапуск и общие для всех реплик
+• У ключа может быть несколько ответов: выбирается случайный с учетом веса,
+  подряд в одном чате один и тот же ответ не повторяется
 • Все фразы хранятся только в БД (никаких фраз по умолчанию)!
-• Работа напрямую с БД (без кэша в памяти)!`
+• Фразы ищутся по индексу в памяти, изменения сразу расходятся по всем репликам`
 }
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// handleDetectorCommand обрабатывает /admin detector - детекторы точных слов
+func (h *BotDatabaseHandler) handleDetectorCommand(req AdminRequest, args []string) string {
+	if len(args) == 0 || strings.ToLower(args[0]) == "list" {
+		return h.showDetectors()
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "add", "добавить":
+		// Детектор - триггер типа exact, остальные опции как у /admin add
+		return h.handleAddCommand(req, append([]string{"type=" + TriggerExact}, args[1:]...), false)
+
+	case "on", "off", "вкл", "выкл":
+		opts, rest := parseAdminOptions(args[1:])
+		if len(rest) != 1 {
+			return "❌ Использование: /admin detector on|off [scope=...] <ключ>"
+		}
+		scope, err := scopeOption(opts, req.ChatID)
+		if err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+
+		action := strings.ToLower(args[0])
+		enabled := action == "on" || action == "вкл"
+		if err := h.SetTriggerEnabled(req.actor(), rest[0], scope, enabled); err != nil {
+			return fmt.Sprintf("❌ Ошибка: %v", err)
+		}
+		if enabled {
+			return fmt.Sprintf("✅ Детектор `%s` включен", codeSafe(rest[0]))
+		}
+		return fmt.Sprintf("✅ Детектор `%s` выключен", codeSafe(rest[0]))
+
+	default:
+		return "❌ Использование: /admin detector [list|add|on|off]"
+	}
+}
+
+// showDetectors форматирует список всех детекторов
+func (h *BotDatabaseHandler) showDetectors() string {
+	detectors, err := h.ListTriggers(TriggerFilter{AllScopes: true, Type: TriggerExact})
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+	if len(detectors) == 0 {
+		return fmt.Sprintf("📭 Детекторов нет. Добавьте: /admin detector add priority=%d ЕБ|ЁБ Ответ", detectorPriority)
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("🎯 Детекторы: %d\n\n", len(detectors)))
+	for i, t := range detectors {
+		result.WriteString(formatTriggerEntry(i+1, t))
+	}
+	return result.String()
+}
@@ -0,0 +1,459 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Пошаговое добавление триггера: /admin add без аргументов спрашивает ключ, затем ответы,
+// затем опции кнопками. Состояние хранится в БД по паре пользователь-чат,
+// поэтому диалог продолжается на любой реплике и после перезапуска.
+// Сообщения админа диалог забирает себе только на шагах ключа и ответов:
+// на шаге опций работают кнопки, а сообщения проверяются на триггеры как обычно.
+const (
+	dialogTimeout      = 15 * time.Minute
+	dialogMaxResponses = 10
+)
+
+// Шаги диалога
+const (
+	dialogStepKey       = "key"       // ждем ключ
+	dialogStepResponses = "responses" // собираем варианты ответа
+	dialogStepOptions   = "options"   // опции кнопками
+)
+
+// Уведомление в канале изменений: диалоги начались или закончились, реплики перечитывают их список
+const dialogsNotifyReason = "dialogs"
+
+// dialogKey - чей диалог и в каком чате
+type dialogKey struct {
+	userID int64
+	chatID int64
+}
+
+// Данные кнопок диалога: dlg:<действие>. Диалог определяется тем, кто и в каком чате нажал.
+const dialogCallbackPrefix = "dlg"
+
+// Действия кнопок диалога
+const (
+	dialogNext   = "next"   // к опциям
+	dialogType   = "type"   // переключить тип триггера
+	dialogMode   = "mode"   // переключить режим сравнения
+	dialogScope  = "scope"  // этот чат или глобально
+	dialogFuzzy  = "fuzzy"  // переключить нечеткое сравнение
+	dialogSave   = "save"   // сохранить триггер
+	dialogCancel = "cancel" // отменить диалог
+)
+
+// Значения, по которым переключаются кнопки опций
+var (
+	dialogTypes  = []string{TriggerText, TriggerRegex}
+	dialogModes  = []string{MatchSubstring, MatchWord, MatchStem}
+	dialogScopes = []string{"here", "global"}
+	dialogFuzzes = []string{"off", "on", "1", "2"}
+)
+
+// DialogMessage - обычное (не командное) сообщение, которое может быть шагом диалога
+type DialogMessage struct {
+	UserID int64
+	ChatID int64
+	Text   string
+	Media  *ResponsePart // стикер, фото и т.п. из самого сообщения
+}
+
+// addDialog - введенные в диалоге данные. Options - опции в том же виде,
+// что и в /admin add, поэтому сохраняются они так же.
+type addDialog struct {
+	Key       string            `json:"key,omitempty"`
+	Responses [][]ResponsePart  `json:"responses,omitempty"`
+	Options   map[string]string `json:"options,omitempty"`
+}
+
+// startAddDialog начинает диалог /admin add; опции из команды становятся начальными
+func (h *BotDatabaseHandler) startAddDialog(req AdminRequest, opts map[string]string) string {
+	if err := h.saveDialog(req.UserID, req.ChatID, dialogStepKey, &addDialog{Options: opts}); err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+	return "🧙 Новый триггер, шаг 1 из 3.\n\n" +
+		"Отправьте ключ - слово или фразу, на которую бот будет отвечать.\n" +
+		"Отменить: /cancel"
+}
+
+// HandleAdminDialog обрабатывает сообщение админа, у которого в этом чате идет диалог /admin add.
+// Возвращает false, если диалог не ждет сообщения: тогда оно проверяется на триггеры как обычно.
+// Наличие диалога проверяется по списку в памяти, в БД идут только сообщения из диалогов.
+func (h *BotDatabaseHandler) HandleAdminDialog(m DialogMessage) (AdminReply, bool) {
+	if !h.hasActiveDialog(m.UserID, m.ChatID) {
+		return AdminReply{}, false
+	}
+
+	step, d, err := h.loadDialog(m.UserID, m.ChatID)
+	if err != nil {
+		// Сообщение адресовано диалогу, отвечать на него триггерами не нужно
+		log.Printf("❌ Ошибка загрузки диалога: %v", err)
+		return AdminReply{Text: fmt.Sprintf("❌ Ошибка: %v\nПопробуйте еще раз или /cancel", err)}, true
+	}
+	if d == nil {
+		// Диалог закончился на другой реплике или истек
+		h.forgetDialog(m.UserID, m.ChatID)
+		return AdminReply{}, false
+	}
+
+	switch step {
+	case dialogStepKey:
+		key := strings.TrimSpace(m.Text)
+		if m.Media != nil || key == "" {
+			return AdminReply{Text: "❌ Ключ должен быть текстом. Отправьте ключ или /cancel"}, true
+		}
+		d.Key = key
+		if err := h.saveDialog(m.UserID, m.ChatID, dialogStepResponses, d); err != nil {
+			return AdminReply{Text: fmt.Sprintf("❌ Ошибка: %v", err)}, true
+		}
+		return AdminReply{Text: fmt.Sprintf("🧙 Ключ: `%s`, шаг 2 из 3.\n\n"+
+			"Отправьте ответ: текст (можно в несколько строк), стикер, фото, GIF, голосовое или документ.\n"+
+			"Каждое сообщение - отдельный вариант, бот выбирает случайный.", codeSafe(d.Key))}, true
+
+	case dialogStepResponses:
+		var parts []ResponsePart
+		switch {
+		case m.Media != nil:
+			parts = []ResponsePart{*m.Media}
+		case strings.TrimSpace(m.Text) != "":
+			parts = TextParts(strings.TrimSpace(m.Text))
+		default:
+			return AdminReply{Text: "❌ Такое сообщение не может быть ответом. Отправьте текст или медиа"}, true
+		}
+		if err := validateResponseParts(parts); err != nil {
+			return AdminReply{Text: fmt.Sprintf("❌ Ошибка: %v", err)}, true
+		}
+
+		d.Responses = append(d.Responses, parts)
+		if len(d.Responses) >= dialogMaxResponses {
+			if err := h.saveDialog(m.UserID, m.ChatID, dialogStepOptions, d); err != nil {
+				return AdminReply{Text: fmt.Sprintf("❌ Ошибка: %v", err)}, true
+			}
+			return h.dialogOptions(m.ChatID, d), true
+		}
+		if err := h.saveDialog(m.UserID, m.ChatID, dialogStepResponses, d); err != nil {
+			return AdminReply{Text: fmt.Sprintf("❌ Ошибка: %v", err)}, true
+		}
+		return AdminReply{
+			Text: fmt.Sprintf("✅ Вариант %d добавлен. Отправьте еще один или переходите к опциям", len(d.Responses)),
+			Buttons: [][]AdminButton{{
+				{Text: "▶️ Далее: опции", Data: dialogData(dialogNext)},
+				{Text: "✖️ Отмена", Data: dialogData(dialogCancel)},
+			}},
+		}, true
+
+	default:
+		// Опции выбираются кнопками: сообщение диалогу не адресовано
+		h.forgetDialog(m.UserID, m.ChatID)
+		return AdminReply{}, false
+	}
+}
+
+// handleDialogCallback обрабатывает кнопки диалога /admin add
+func (h *BotDatabaseHandler) handleDialogCallback(cb AdminCallback) AdminReply {
+	action := strings.TrimPrefix(cb.Data, dialogCallbackPrefix+":")
+
+	step, d, err := h.loadDialog(cb.UserID, cb.ChatID)
+	if err != nil {
+		return AdminReply{Notice: fmt.Sprintf("❌ Ошибка: %v", err)}
+	}
+	if d == nil {
+		return AdminReply{Notice: "Диалог завершен или устарел, начните заново: /admin add"}
+	}
+
+	switch {
+	case action == dialogCancel:
+		if _, err := h.CancelAdminDialog(cb.UserID, cb.ChatID); err != nil {
+			return AdminReply{Notice: fmt.Sprintf("❌ Ошибка: %v", err)}
+		}
+		return AdminReply{Text: "✖️ Добавление триггера отменено"}
+
+	case action == dialogNext && step == dialogStepResponses && len(d.Responses) > 0:
+		if err := h.saveDialog(cb.UserID, cb.ChatID, dialogStepOptions, d); err != nil {
+			return AdminReply{Notice: fmt.Sprintf("❌ Ошибка: %v", err)}
+		}
+		return h.dialogOptions(cb.ChatID, d)
+
+	case step != dialogStepOptions:
+		return AdminReply{Notice: "Кнопка устарела"}
+
+	case action == dialogSave:
+		return h.saveDialogTrigger(cb, d)
+	}
+
+	if d.Options == nil {
+		d.Options = make(map[string]string)
+	}
+	switch action {
+	case dialogType:
+		d.Options["type"] = nextOption(dialogTypes, d.Options["type"], TriggerText)
+	case dialogMode:
+		d.Options["mode"] = nextOption(dialogModes, d.Options["mode"], MatchSubstring)
+	case dialogScope:
+		d.Options["scope"] = nextOption(dialogScopes, d.Options["scope"], "here")
+	case dialogFuzzy:
+		d.Options["fuzzy"] = nextOption(dialogFuzzes, d.Options["fuzzy"], "off")
+	default:
+		return AdminReply{Notice: "Кнопка устарела"}
+	}
+	if err := h.saveDialog(cb.UserID, cb.ChatID, dialogStepOptions, d); err != nil {
+		return AdminReply{Notice: fmt.Sprintf("❌ Ошибка: %v", err)}
+	}
+	return h.dialogOptions(cb.ChatID, d)
+}
+
+// saveDialogTrigger сохраняет все варианты ответа из диалога и завершает его.
+// При ошибке (например, некорректное регулярное выражение) диалог остается, опции можно поправить.
+func (h *BotDatabaseHandler) saveDialogTrigger(cb AdminCallback, d *addDialog) AdminReply {
+	triggerOpts, err := triggerOptions(d.Options, cb.ChatID, h.chatLocation(cb.ChatID))
+	if err != nil {
+		return AdminReply{Notice: fmt.Sprintf("❌ Ошибка: %v", err)}
+	}
+	if !h.canEditScope(cb.UserID, triggerOpts.Scope) {
+		return AdminReply{Notice: fmt.Sprintf("❌ Нет прав менять триггеры: %s", triggerOpts.Scope)}
+	}
+
+	by := Actor{UserID: cb.UserID, ChatID: cb.ChatID}
+	var descriptions []string
+	for _, parts := range d.Responses {
+		if err := h.AddMapping(by, d.Key, parts, triggerOpts); err != nil {
+			if len(descriptions) > 0 {
+				// Часть вариантов уже сохранена: повтор добавил бы их второй раз
+				h.CancelAdminDialog(cb.UserID, cb.ChatID)
+				return AdminReply{Text: fmt.Sprintf("⚠️ Сохранено вариантов: %d из %d, дальше ошибка: %v\nОстальные добавьте через /admin add",
+					len(descriptions), len(d.Responses), err)}
+			}
+			return AdminReply{Notice: fmt.Sprintf("❌ Ошибка: %v", err)}
+		}
+		descriptions = append(descriptions, codeSafe((&TriggerResponse{Parts: parts}).Describe()))
+	}
+	if _, err := h.CancelAdminDialog(cb.UserID, cb.ChatID); err != nil {
+		log.Printf("⚠️ Ошибка завершения диалога: %v", err)
+	}
+
+	return AdminReply{
+		Text: fmt.Sprintf("✅ Добавлено ответов: %d (вес %d, %s):\n`%s` → `%s`",
+			len(descriptions), triggerOpts.Weight, triggerOpts.Scope, codeSafe(d.Key), strings.Join(descriptions, "`, `")),
+		Notice: "✅ Сохранено",
+	}
+}
+
+// dialogOptions показывает шаг опций: что уже введено и кнопки, переключающие опции по кругу
+func (h *BotDatabaseHandler) dialogOptions(chatID int64, d *addDialog) AdminReply {
+	opts := d.Options
+	triggerType, err := ParseTriggerType(opts["type"])
+	if err != nil {
+		triggerType = opts["type"]
+	}
+	mode, err := ParseMatchMode(opts["mode"])
+	if err != nil {
+		mode = opts["mode"]
+	}
+	scope := opts["scope"]
+	if parsed, err := ParseScope(scope, chatID); err == nil {
+		scope = parsed.String()
+	}
+	fuzzy := formatFuzzy(FuzzyOff)
+	if parsed, err := ParseFuzzy(opts["fuzzy"]); err == nil {
+		fuzzy = formatFuzzy(parsed)
+	} else if opts["fuzzy"] != "" {
+		fuzzy = opts["fuzzy"]
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("🧙 Ключ: `%s`, вариантов ответа: %d, шаг 3 из 3.\n\n", codeSafe(d.Key), len(d.Responses)))
+	result.WriteString("Кнопки переключают опции, потом нажмите «Сохранить».")
+	var other []string
+	for name, value := range opts {
+		switch name {
+		case "type", "mode", "scope", "fuzzy":
+		default:
+			other = append(other, name+"="+value)
+		}
+	}
+	if len(other) > 0 {
+		slices.Sort(other)
+		result.WriteString("\nОпции из команды: " + codeSafe(strings.Join(other, " ")))
+	}
+
+	buttons := [][]AdminButton{
+		{
+			{Text: "Тип: " + triggerType, Data: dialogData(dialogType)},
+			{Text: "Режим: " + mode, Data: dialogData(dialogMode)},
+		},
+		{{Text: "Нечетко: " + fuzzy, Data: dialogData(dialogFuzzy)}},
+		{
+			{Text: "💾 Сохранить", Data: dialogData(dialogSave)},
+			{Text: "✖️ Отмена", Data: dialogData(dialogCancel)},
+		},
+	}
+	// В личке триггер всегда глобальный, выбирать область нужно только в группе
+	if chatID < 0 {
+		buttons[1] = append(buttons[1], AdminButton{Text: "Область: " + scope, Data: dialogData(dialogScope)})
+	}
+	return AdminReply{Text: result.String(), Buttons: buttons}
+}
+
+// dialogData упаковывает действие кнопки диалога в callback_data
+func dialogData(action string) string {
+	return dialogCallbackPrefix + ":" + action
+}
+
+// nextOption возвращает значение, следующее за current в values; неизвестное считается defaultValue
+func nextOption(values []string, current, defaultValue string) string {
+	if current == "" {
+		current = defaultValue
+	}
+	for i, value := range values {
+		if strings.EqualFold(value, current) {
+			return values[(i+1)%len(values)]
+		}
+	}
+	return values[0]
+}
+
+// codeSafe убирает из текста обратные кавычки: в ответе админу они выделяют `код`
+func codeSafe(text string) string {
+	return strings.ReplaceAll(text, "`", "'")
+}
+
+// CancelAdminDialog завершает диалог пользователя в чате. Возвращает false, если диалога не было.
+func (h *BotDatabaseHandler) CancelAdminDialog(userID, chatID int64) (bool, error) {
+	query := `
+		DELETE FROM bushlatinga_bot.admin_dialogs
+		WHERE user_id = $1 AND chat_id = $2 AND updated_at > NOW() - $3::float8 * INTERVAL '1 second'
+	`
+	res, err := h.db.Exec(query, userID, chatID, dialogTimeout.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("ошибка отмены диалога: %v", err)
+	}
+	h.forgetDialog(userID, chatID)
+	h.publishChange(dialogsNotifyReason)
+
+	deleted, _ := res.RowsAffected()
+	return deleted > 0, nil
+}
+
+// saveDialog сохраняет шаг и данные диалога и продлевает его
+func (h *BotDatabaseHandler) saveDialog(userID, chatID int64, step string, d *addDialog) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения диалога: %v", err)
+	}
+
+	query := `
+		INSERT INTO bushlatinga_bot.admin_dialogs (user_id, chat_id, step, data, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (user_id, chat_id) DO UPDATE
+		SET step = EXCLUDED.step, data = EXCLUDED.data, updated_at = NOW()
+	`
+	if _, err := h.db.Exec(query, userID, chatID, step, string(data)); err != nil {
+		return fmt.Errorf("ошибка сохранения диалога: %v", err)
+	}
+
+	if step == dialogStepOptions {
+		h.forgetDialog(userID, chatID)
+	} else {
+		h.mu.Lock()
+		h.dialogs[dialogKey{userID, chatID}] = time.Now().Add(dialogTimeout)
+		h.mu.Unlock()
+	}
+	h.publishChange(dialogsNotifyReason)
+	return nil
+}
+
+// hasActiveDialog сообщает, ждет ли диалог пользователя в чате его сообщения, по списку в памяти
+func (h *BotDatabaseHandler) hasActiveDialog(userID, chatID int64) bool {
+	h.mu.RLock()
+	expiresAt, ok := h.dialogs[dialogKey{userID, chatID}]
+	h.mu.RUnlock()
+	return ok && time.Now().Before(expiresAt)
+}
+
+// forgetDialog убирает диалог из списка в памяти
+func (h *BotDatabaseHandler) forgetDialog(userID, chatID int64) {
+	h.mu.Lock()
+	delete(h.dialogs, dialogKey{userID, chatID})
+	h.mu.Unlock()
+}
+
+// reloadDialogs перечитывает список незавершенных диалогов, которые ждут сообщения
+// (все шаги, кроме опций). Их немного: список нужен, чтобы не ходить в БД за каждым сообщением в чате.
+func (h *BotDatabaseHandler) reloadDialogs() error {
+	query := `
+		SELECT user_id, chat_id, EXTRACT(EPOCH FROM updated_at + $1::float8 * INTERVAL '1 second' - NOW())
+		FROM bushlatinga_bot.admin_dialogs
+		WHERE updated_at > NOW() - $1::float8 * INTERVAL '1 second' AND step <> $2
+	`
+	rows, err := h.db.Query(query, dialogTimeout.Seconds(), dialogStepOptions)
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки диалогов: %v", err)
+	}
+	defer rows.Close()
+
+	// Срок считается от часов БД, чтобы расхождение часов реплик не продлевало диалоги
+	now := time.Now()
+	dialogs := make(map[dialogKey]time.Time)
+	for rows.Next() {
+		var key dialogKey
+		var left float64
+		if err := rows.Scan(&key.userID, &key.chatID, &left); err != nil {
+			return fmt.Errorf("ошибка чтения диалога: %v", err)
+		}
+		dialogs[key] = now.Add(time.Duration(left * float64(time.Second)))
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка чтения диалогов: %v", err)
+	}
+
+	h.mu.Lock()
+	h.dialogs = dialogs
+	h.mu.Unlock()
+	return nil
+}
+
+// reloadDialogsSafely перечитывает диалоги; при ошибке оставляет прежний список
+func (h *BotDatabaseHandler) reloadDialogsSafely() {
+	if err := h.reloadDialogs(); err != nil {
+		log.Printf("⚠️ Не удалось обновить список диалогов: %v", err)
+	}
+}
+
+// loadDialog загружает незавершенный диалог; nil, если его нет или он истек
+func (h *BotDatabaseHandler) loadDialog(userID, chatID int64) (string, *addDialog, error) {
+	query := `
+		SELECT step, data FROM bushlatinga_bot.admin_dialogs
+		WHERE user_id = $1 AND chat_id = $2 AND updated_at > NOW() - $3::float8 * INTERVAL '1 second'
+	`
+	var step string
+	var data []byte
+	err := h.db.QueryRow(query, userID, chatID, dialogTimeout.Seconds()).Scan(&step, &data)
+	if err == sql.ErrNoRows {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("ошибка загрузки диалога: %v", err)
+	}
+
+	var d addDialog
+	if err := json.Unmarshal(data, &d); err != nil {
+		return "", nil, fmt.Errorf("ошибка чтения диалога: %v", err)
+	}
+	return step, &d, nil
+}
+
+// cleanupAdminDialogs удаляет брошенные диалоги
+func (h *BotDatabaseHandler) cleanupAdminDialogs() {
+	query := "DELETE FROM bushlatinga_bot.admin_dialogs WHERE updated_at < NOW() - $1::float8 * INTERVAL '1 second'"
+	if _, err := h.db.Exec(query, dialogTimeout.Seconds()); err != nil {
+		log.Printf("⚠️ Ошибка очистки диалогов: %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// handleTestCommand обрабатывает /admin test: показывает, как бот ответил бы на текст
+// или на сообщение, на которое ответили командой, ничего не отправляя в чат
+func (h *BotDatabaseHandler) handleTestCommand(req AdminRequest, parts []string) string {
+	msg := IncomingMessage{
+		ChatID: req.ChatID,
+		UserID: req.UserID,
+		Text:   strings.Join(parts, " "),
+	}
+	if msg.Text == "" {
+		msg.Text = req.ReplyText
+		msg.Sticker = req.ReplySticker
+		msg.CustomEmojiIDs = req.ReplyCustomEmojiIDs
+	}
+	if msg.Text == "" && msg.Sticker == nil && len(msg.CustomEmojiIDs) == 0 {
+		return "❌ Использование: /admin test <текст>\nИли ответьте командой /admin test на любое сообщение"
+	}
+
+	return formatExplanation(msg, h.ExplainMessage(msg))
+}
+
+// formatExplanation описывает шаги поиска и выбора ответа для админа
+func formatExplanation(msg IncomingMessage, e *MatchExplanation) string {
+	var result strings.Builder
+	result.WriteString("🧪 Проверка сообщения (в чат ничего не отправлено)\n")
+	if msg.Text != "" {
+		result.WriteString(fmt.Sprintf("Текст: '%s'\n", codeSafe(msg.Text)))
+	}
+	if msg.Sticker != nil {
+		result.WriteString(fmt.Sprintf("Стикер: %s из набора %s\n", msg.Sticker.Emoji, msg.Sticker.SetName))
+	}
+	result.WriteString(fmt.Sprintf("Политика чата: %s\n", e.Policy))
+
+	result.WriteString("\n🔎 Найдено:\n")
+	if len(e.Found) == 0 {
+		result.WriteString("ничего\n")
+	}
+	for _, m := range e.Found {
+		mark := "✅"
+		if !m.Selected {
+			mark = "➖"
+		}
+		where := "стикер или эмодзи"
+		if m.Fragment != "" {
+			where = fmt.Sprintf("'%s' с позиции %d", m.Fragment, m.Start+1)
+		}
+		result.WriteString(fmt.Sprintf("%s `%s` [%s]: %s", mark, codeSafe(m.Trigger.Text), explainKind(m.Trigger), where))
+		if m.Reason != "" {
+			result.WriteString(" - " + m.Reason)
+		}
+		result.WriteString("\n")
+	}
+
+	if len(e.Inactive) > 0 {
+		result.WriteString("\n💤 Нашлись бы, но не действуют:\n")
+		for _, t := range e.Inactive {
+			result.WriteString(fmt.Sprintf("• `%s` - %s\n", codeSafe(t.Trigger.Text), t.Reason))
+		}
+	}
+
+	if e.Note != "" {
+		result.WriteString(fmt.Sprintf("\n📌 Итог: %s\n", e.Note))
+	}
+
+	for _, r := range e.Replies {
+		result.WriteString(fmt.Sprintf("\n💬 Ответ на `%s`:\n", codeSafe(r.Trigger.Text)))
+		if r.Trigger.Chance < defaultProbability {
+			result.WriteString(fmt.Sprintf("• Шанс ответа: %d%%\n", r.Trigger.Chance))
+		}
+		for _, limit := range r.Limits {
+			if limit.Blocked {
+				result.WriteString(fmt.Sprintf("• %s: ⏳ занято еще %s - ответа не будет\n", limit.Name, formatCooldown(limit.Left.Round(time.Second))))
+			} else {
+				result.WriteString(fmt.Sprintf("• %s: свободно\n", limit.Name))
+			}
+		}
+		if r.Options == 0 {
+			result.WriteString("• У триггера нет ответов\n")
+			continue
+		}
+		if r.Options > 1 {
+			result.WriteString(fmt.Sprintf("• Вариантов ответа: %d, например:\n", r.Options))
+		}
+		sample := codeSafe((&TriggerResponse{Parts: r.Sample}).Describe())
+		result.WriteString("   → " + sample + "\n")
+	}
+	return result.String()
+}
+
+// explainKind - тип и режим триггера коротко
+func explainKind(t *Trigger) string {
+	kind := t.MatchMode
+	if t.Type != TriggerText {
+		kind = t.Type
+	}
+	if t.Fuzzy != FuzzyOff {
+		kind += ", нечетко"
+	}
+	if t.Priority != 0 {
+		kind += fmt.Sprintf(", приоритет %d", t.Priority)
+	}
+	return kind
+}
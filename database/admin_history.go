@@ -0,0 +1,92 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Сколько последних версий показывает /admin history
+const historyLimit = 15
+
+// handleHistoryCommand обрабатывает /admin history: журнал изменений триггера
+func (h *BotDatabaseHandler) handleHistoryCommand(req AdminRequest, parts []string) string {
+	opts, args := parseAdminOptions(parts)
+	if len(args) < 1 {
+		return "❌ Использование: /admin history [scope=...] <ключ>"
+	}
+	scope, err := scopeOption(opts, req.ChatID)
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+
+	if !h.canViewScope(req.UserID, req.ChatID, scope) {
+		return fmt.Sprintf("❌ Нет прав смотреть триггеры: %s", scope)
+	}
+
+	key := strings.Join(args, " ")
+	entries, err := h.TriggerHistory(key, scope, historyLimit)
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+	if len(entries) == 0 {
+		return fmt.Sprintf("📭 У `%s` (%s) нет изменений в журнале", codeSafe(key), scope)
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("📜 История `%s` (%s), новые сверху:\n\n", codeSafe(key), scope))
+	for _, e := range entries {
+		result.WriteString(fmt.Sprintf("v%d %s %s - %d (чат %d)\n   %s\n",
+			e.Version, e.CreatedAt.Format("2006-01-02 15:04"), e.Action, e.UserID, e.ChatID, e.New.describe()))
+	}
+	result.WriteString("\nВернуть версию: /admin restore <ключ> <версия>")
+	return result.String()
+}
+
+// handleUndoCommand обрабатывает /admin undo: откат последнего изменения автора команды
+func (h *BotDatabaseHandler) handleUndoCommand(req AdminRequest) string {
+	entry, err := h.LastChangeBy(req.UserID)
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+	// Права могли отозвать после изменения
+	if !h.canEditScope(req.UserID, entry.Scope) {
+		return fmt.Sprintf("❌ Нет прав менять триггеры: %s", entry.Scope)
+	}
+	if err := h.UndoChange(req.actor(), entry); err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+	return fmt.Sprintf("↩️ Отменено: %s `%s` (%s, v%d)\nСейчас: %s", entry.Action, codeSafe(entry.Key), entry.Scope, entry.Version, entry.Old.describe())
+}
+
+// handleRestoreCommand обрабатывает /admin restore: с номером версии возвращает триггер
+// к версии из журнала, без него - достает триггер из корзины
+func (h *BotDatabaseHandler) handleRestoreCommand(req AdminRequest, parts []string) string {
+	opts, args := parseAdminOptions(parts)
+	if len(args) < 1 {
+		return "❌ Использование: /admin restore [scope=...] <ключ> [версия]"
+	}
+	scope, err := scopeOption(opts, req.ChatID)
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+
+	if len(args) >= 2 {
+		if version, err := strconv.Atoi(strings.TrimPrefix(args[len(args)-1], "v")); err == nil {
+			if version < 1 {
+				return "❌ Версия должна быть положительным числом (см. /admin history)"
+			}
+			key := strings.Join(args[:len(args)-1], " ")
+			if err := h.RestoreVersion(req.actor(), key, scope, version); err != nil {
+				return fmt.Sprintf("❌ Ошибка: %v", err)
+			}
+			return fmt.Sprintf("✅ `%s` (%s) восстановлен до версии %d", codeSafe(key), scope, version)
+		}
+	}
+
+	key := strings.Join(args, " ")
+	if err := h.RestoreFromTrash(req.actor(), key, scope); err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+	return fmt.Sprintf("♻️ `%s` (%s) возвращен из корзины", codeSafe(key), scope)
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Сколько триггеров показывает /admin trash
+const trashListLimit = 20
+
+// handleEnableCommand обрабатывает /admin enable и /admin disable: триггер остается в списке,
+// но не срабатывает ни в одном чате своей области
+func (h *BotDatabaseHandler) handleEnableCommand(req AdminRequest, parts []string, enabled bool) string {
+	opts, args := parseAdminOptions(parts)
+	if len(args) < 1 {
+		return "❌ Использование: /admin enable|disable [scope=...] <ключ>"
+	}
+	scope, err := scopeOption(opts, req.ChatID)
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+
+	key := strings.Join(args, " ")
+	if err := h.SetTriggerEnabled(req.actor(), key, scope, enabled); err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+	if enabled {
+		return fmt.Sprintf("▶️ `%s` (%s) включен", codeSafe(key), scope)
+	}
+	return fmt.Sprintf("⏸ `%s` (%s) выключен, включить: /admin enable %s", codeSafe(key), scope, key)
+}
+
+// handleExpireCommand обрабатывает /admin expire: срок, после которого триггер выключится сам
+func (h *BotDatabaseHandler) handleExpireCommand(req AdminRequest, parts []string) string {
+	opts, args := parseAdminOptions(parts)
+	if len(args) < 2 {
+		return "❌ Использование: /admin expire [scope=...] <ключ> <7d|12h|2025-01-31|off>"
+	}
+	scope, err := scopeOption(opts, req.ChatID)
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+
+	location := h.chatLocation(req.ChatID)
+	expiresAt, err := ParseExpiry(args[len(args)-1], time.Now().In(location))
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+
+	key := strings.Join(args[:len(args)-1], " ")
+	if err := h.SetTriggerExpiry(req.actor(), key, scope, expiresAt); err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+	if expiresAt.IsZero() {
+		return fmt.Sprintf("✅ `%s` (%s) действует бессрочно", codeSafe(key), scope)
+	}
+	return fmt.Sprintf("⌛ `%s` (%s) действует до %s (часовой пояс чата), потом выключится", codeSafe(key), scope, formatExpiry(expiresAt, location))
+}
+
+// handleTrashCommand обрабатывает /admin trash: удаленные триггеры, которые еще можно вернуть
+func (h *BotDatabaseHandler) handleTrashCommand(req AdminRequest, parts []string) string {
+	opts, _ := parseAdminOptions(parts)
+	filter, err := scopeFilter(opts, req.ChatID)
+	if err == nil {
+		filter, err = h.clampFilter(req.UserID, req.ChatID, filter)
+	}
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+	filter.Trash = true
+
+	triggers, total, err := h.ListTrash(filter, trashListLimit)
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+	if total == 0 {
+		return fmt.Sprintf("📭 Корзина пуста (%s)", filter)
+	}
+
+	location := h.chatLocation(req.ChatID)
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("🗑 Корзина (%s), всего %d, новые сверху:\n\n", filter, total))
+	for i, t := range triggers {
+		result.WriteString(strings.TrimSuffix(formatTriggerEntry(i+1, t), "\n"))
+		result.WriteString(fmt.Sprintf("   удален %s\n\n", t.DeletedAt.In(location).Format("2006-01-02 15:04")))
+	}
+	if total > len(triggers) {
+		result.WriteString(fmt.Sprintf("...и еще %d\n", total-len(triggers)))
+	}
+	result.WriteString(fmt.Sprintf("\nВернуть: /admin restore [scope=...] <ключ>\nЧерез %d дней триггер удаляется насовсем, но остается в /admin history",
+		int(trashRetention.Hours()/24)))
+	return result.String()
+}
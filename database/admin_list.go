@@ -0,0 +1,336 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Листаемый список /admin list и /admin search. Фильтр сохраняется в БД,
+// чтобы кнопки работали на любой реплике и после перезапуска.
+const (
+	listPageSize        = 10
+	listViewRetention   = 7 * 24 * time.Hour
+	listButtonKeyLength = 24
+)
+
+// Данные кнопок: adm:<действие>:<ID фильтра>:<страница>:<ID триггера>
+const adminCallbackPrefix = "adm"
+
+// Действия кнопок списка
+const (
+	callbackPage    = "p" // показать страницу
+	callbackCard    = "c" // карточка триггера с кнопками изменения
+	callbackToggle  = "t" // включить или выключить триггер
+	callbackDelete  = "d" // спросить подтверждение удаления
+	callbackConfirm = "x" // удалить триггер
+	callbackNoop    = "n" // номер страницы, ничего не делает
+)
+
+// AdminCallback - нажатие кнопки под сообщением бота
+type AdminCallback struct {
+	UserID int64
+	ChatID int64
+	Data   string // callback_data кнопки
+}
+
+// listCallback - разобранные данные кнопки списка
+type listCallback struct {
+	action    string
+	viewID    int64
+	page      int
+	triggerID int64
+}
+
+// data упаковывает кнопку в callback_data (не длиннее 64 байт)
+func (c listCallback) data() string {
+	return fmt.Sprintf("%s:%s:%d:%d:%d", adminCallbackPrefix, c.action, c.viewID, c.page, c.triggerID)
+}
+
+// with возвращает кнопку того же списка с другим действием
+func (c listCallback) with(action string, page int, triggerID int64) listCallback {
+	return listCallback{action: action, viewID: c.viewID, page: page, triggerID: triggerID}
+}
+
+// parseListCallback разбирает callback_data кнопки списка
+func parseListCallback(data string) (listCallback, bool) {
+	fields := strings.Split(data, ":")
+	if len(fields) != 5 || fields[0] != adminCallbackPrefix {
+		return listCallback{}, false
+	}
+
+	c := listCallback{action: fields[1]}
+	var err error
+	if c.viewID, err = strconv.ParseInt(fields[2], 10, 64); err != nil {
+		return listCallback{}, false
+	}
+	if c.page, err = strconv.Atoi(fields[3]); err != nil || c.page < 0 {
+		return listCallback{}, false
+	}
+	if c.triggerID, err = strconv.ParseInt(fields[4], 10, 64); err != nil {
+		return listCallback{}, false
+	}
+	return c, true
+}
+
+// handleListCommand обрабатывает /admin list и /admin search: сохраняет фильтр
+// и показывает первую страницу с кнопками
+func (h *BotDatabaseHandler) handleListCommand(req AdminRequest, parts []string, search bool) AdminReply {
+	opts, args := parseAdminOptions(parts)
+	if search && len(args) < 1 {
+		return AdminReply{Text: "❌ Использование: /admin search [scope=here|global|all] [type=...] [created=...] [hits=...] <текст>"}
+	}
+
+	filter, err := filterOption(opts, req.ChatID)
+	if err == nil {
+		filter, err = h.clampFilter(req.UserID, req.ChatID, filter)
+	}
+	if err != nil {
+		return AdminReply{Text: fmt.Sprintf("❌ Ошибка: %v", err)}
+	}
+	if search {
+		filter.Search = strings.Join(args, " ")
+	}
+
+	viewID, err := h.saveListView(req.UserID, req.ChatID, filter)
+	if err != nil {
+		return AdminReply{Text: fmt.Sprintf("❌ Ошибка: %v", err)}
+	}
+	return h.listPage(listCallback{action: callbackPage, viewID: viewID}, filter)
+}
+
+// HandleAdminCallback обрабатывает нажатие кнопки под списком триггеров или в диалоге /admin add.
+// Возвращает новый текст и кнопки сообщения; пустой текст - сообщение не меняется.
+func (h *BotDatabaseHandler) HandleAdminCallback(cb AdminCallback) AdminReply {
+	if strings.HasPrefix(cb.Data, dialogCallbackPrefix+":") {
+		return h.handleDialogCallback(cb)
+	}
+
+	c, ok := parseListCallback(cb.Data)
+	if !ok {
+		return AdminReply{Notice: "Кнопка устарела"}
+	}
+	if c.action == callbackNoop {
+		return AdminReply{}
+	}
+	if !hasRole(h.UserRole(cb.UserID, cb.ChatID), RoleViewer) {
+		return AdminReply{Notice: "❌ У вас нет доступа к командам администратора"}
+	}
+
+	owner, filter, err := h.loadListView(c.viewID)
+	if err != nil {
+		return AdminReply{Notice: "Список устарел, вызовите /admin list заново"}
+	}
+	// Список листает только тот, кто его вызвал, и только в пределах своих текущих прав
+	if owner != cb.UserID {
+		return AdminReply{Notice: "❌ Это чужой список, вызовите /admin list сами"}
+	}
+	if filter, err = h.clampFilter(cb.UserID, cb.ChatID, filter); err != nil {
+		return AdminReply{Notice: fmt.Sprintf("❌ Ошибка: %v", err)}
+	}
+	if c.action == callbackPage {
+		return h.listPage(c, filter)
+	}
+
+	t, err := h.triggerByID(c.triggerID)
+	if err != nil {
+		return AdminReply{Notice: fmt.Sprintf("❌ Ошибка: %v", err)}
+	}
+	if t == nil {
+		reply := h.listPage(c.with(callbackPage, c.page, 0), filter)
+		reply.Notice = "Триггер уже удален"
+		return reply
+	}
+	if !h.canViewScope(cb.UserID, cb.ChatID, t.ChatIDs) {
+		return AdminReply{Notice: fmt.Sprintf("❌ Нет прав смотреть триггеры: %s", t.ChatIDs)}
+	}
+
+	if c.action == callbackCard {
+		return h.triggerCard(c, t)
+	}
+
+	// Остальные кнопки меняют триггер: нужны права на его область
+	if !h.canEditScope(cb.UserID, t.ChatIDs) {
+		return AdminReply{Notice: fmt.Sprintf("❌ Нет прав менять триггеры: %s", t.ChatIDs)}
+	}
+	by := Actor{UserID: cb.UserID, ChatID: cb.ChatID}
+
+	switch c.action {
+	case callbackToggle:
+		if err := h.SetTriggerEnabled(by, t.Text, t.ChatIDs, !t.Enabled); err != nil {
+			return AdminReply{Notice: fmt.Sprintf("❌ Ошибка: %v", err)}
+		}
+		t.Enabled = !t.Enabled
+		reply := h.triggerCard(c, t)
+		reply.Notice = "✅ Триггер выключен"
+		if t.Enabled {
+			reply.Notice = "✅ Триггер включен"
+		}
+		return reply
+
+	case callbackDelete:
+		reply := h.triggerCard(c, t)
+		reply.Text += "\n\n❓ Удалить триггер со всеми ответами?"
+		reply.Buttons = [][]AdminButton{{
+			{Text: "🗑 Да, удалить", Data: c.with(callbackConfirm, c.page, t.ID).data()},
+			{Text: "↩️ Отмена", Data: c.with(callbackCard, c.page, t.ID).data()},
+		}}
+		return reply
+
+	case callbackConfirm:
+		if err := h.RemoveMapping(by, t.Text, t.ChatIDs); err != nil {
+			return AdminReply{Notice: fmt.Sprintf("❌ Ошибка: %v", err)}
+		}
+		reply := h.listPage(c.with(callbackPage, c.page, 0), filter)
+		reply.Notice = "🗑 В корзине. Вернуть: /admin undo или /admin restore"
+		return reply
+
+	default:
+		return AdminReply{Notice: "Кнопка устарела"}
+	}
+}
+
+// listPage показывает страницу списка с кнопками изменения и листания
+func (h *BotDatabaseHandler) listPage(c listCallback, filter TriggerFilter) AdminReply {
+	triggers, total, err := h.ListTriggersPage(filter, c.page*listPageSize, listPageSize)
+	if err != nil {
+		return AdminReply{Text: fmt.Sprintf("❌ Ошибка: %v", err)}
+	}
+
+	pages := (total + listPageSize - 1) / listPageSize
+	// После удаления последней записи на странице возвращаемся на предыдущую
+	if c.page > 0 && c.page >= pages {
+		return h.listPage(c.with(callbackPage, max(pages-1, 0), 0), filter)
+	}
+
+	if total == 0 {
+		if filter.Search != "" {
+			return AdminReply{Text: fmt.Sprintf("🔍 Не найдено записей содержащих '%s'", filter.Search)}
+		}
+		return AdminReply{Text: fmt.Sprintf("📭 Нет записей (%s). Добавьте фразы через /admin add", filter)}
+	}
+
+	var result strings.Builder
+	if filter.Search != "" {
+		result.WriteString(fmt.Sprintf("🔍 Найдено %d записей (%s)", total, filter))
+	} else {
+		result.WriteString(fmt.Sprintf("📋 Всего записей: %d (%s)", total, filter))
+	}
+	if pages > 1 {
+		result.WriteString(fmt.Sprintf(", страница %d из %d", c.page+1, pages))
+	}
+	result.WriteString("\n\n")
+
+	var buttons [][]AdminButton
+	for i, t := range triggers {
+		n := c.page*listPageSize + i + 1
+		result.WriteString(formatTriggerEntry(n, t))
+		buttons = append(buttons, []AdminButton{
+			{Text: fmt.Sprintf("✏️ %d. %s", n, truncateRunes(t.Text, listButtonKeyLength)), Data: c.with(callbackCard, c.page, t.ID).data()},
+			{Text: "🗑", Data: c.with(callbackDelete, c.page, t.ID).data()},
+		})
+	}
+
+	if pages > 1 {
+		var nav []AdminButton
+		if c.page > 0 {
+			nav = append(nav, AdminButton{Text: "◀️", Data: c.with(callbackPage, c.page-1, 0).data()})
+		}
+		nav = append(nav, AdminButton{Text: fmt.Sprintf("%d/%d", c.page+1, pages), Data: c.with(callbackNoop, c.page, 0).data()})
+		if c.page < pages-1 {
+			nav = append(nav, AdminButton{Text: "▶️", Data: c.with(callbackPage, c.page+1, 0).data()})
+		}
+		buttons = append(buttons, nav)
+	}
+
+	return AdminReply{Text: result.String(), Buttons: buttons}
+}
+
+// triggerCard показывает триггер целиком с командами для изменения и кнопками
+func (h *BotDatabaseHandler) triggerCard(c listCallback, t *Trigger) AdminReply {
+	scope := "scope=global"
+	if !t.ChatIDs.IsGlobal() {
+		ids := make([]string, len(t.ChatIDs))
+		for i, id := range t.ChatIDs {
+			ids[i] = strconv.FormatInt(id, 10)
+		}
+		scope = "scope=" + strings.Join(ids, ",")
+	}
+	key := codeSafe(t.Text)
+
+	var result strings.Builder
+	result.WriteString("✏️ Триггер:\n\n")
+	result.WriteString(strings.TrimPrefix(formatTriggerEntry(1, t), "1. "))
+	result.WriteString(fmt.Sprintf("Создан: %s, срабатываний: %d\n\n", t.CreatedAt.Format("2006-01-02 15:04"), t.Hits))
+	result.WriteString("Изменить командами:\n")
+	for _, command := range []string{"add", "replace", "remove", "cooldown", "chance", "schedule", "expire", "history"} {
+		result.WriteString(fmt.Sprintf("`/admin %s %s %s`\n", command, scope, key))
+	}
+
+	toggle := AdminButton{Text: "⏸ Выключить", Data: c.with(callbackToggle, c.page, t.ID).data()}
+	if !t.Enabled {
+		toggle.Text = "▶️ Включить"
+	}
+	return AdminReply{
+		Text: strings.TrimRight(result.String(), "\n"),
+		Buttons: [][]AdminButton{
+			{toggle, {Text: "🗑 Удалить", Data: c.with(callbackDelete, c.page, t.ID).data()}},
+			{{Text: "◀️ К списку", Data: c.with(callbackPage, c.page, 0).data()}},
+		},
+	}
+}
+
+// triggerByID загружает триггер с ответами; nil, если его уже нет
+func (h *BotDatabaseHandler) triggerByID(id int64) (*Trigger, error) {
+	triggers, err := h.queryTriggers("id = $1 AND deleted_at IS NULL", "", id)
+	if err != nil || len(triggers) == 0 {
+		return nil, err
+	}
+	return triggers[0], nil
+}
+
+// saveListView сохраняет фильтр списка и возвращает его ID для кнопок
+func (h *BotDatabaseHandler) saveListView(userID, chatID int64, filter TriggerFilter) (int64, error) {
+	data, err := json.Marshal(filter)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка сохранения фильтра: %v", err)
+	}
+
+	var id int64
+	query := "INSERT INTO bushlatinga_bot.admin_list_views (user_id, chat_id, filter) VALUES ($1, $2, $3) RETURNING id"
+	if err := h.db.QueryRow(query, userID, chatID, string(data)).Scan(&id); err != nil {
+		return 0, fmt.Errorf("ошибка сохранения фильтра: %v", err)
+	}
+	return id, nil
+}
+
+// loadListView загружает сохраненный фильтр списка и пользователя, который вызвал список
+func (h *BotDatabaseHandler) loadListView(id int64) (int64, TriggerFilter, error) {
+	var userID int64
+	var data []byte
+	err := h.db.QueryRow("SELECT user_id, filter FROM bushlatinga_bot.admin_list_views WHERE id = $1", id).Scan(&userID, &data)
+	if err == sql.ErrNoRows {
+		return 0, TriggerFilter{}, fmt.Errorf("фильтр %d не найден", id)
+	}
+	if err != nil {
+		return 0, TriggerFilter{}, fmt.Errorf("ошибка загрузки фильтра: %v", err)
+	}
+
+	var filter TriggerFilter
+	if err := json.Unmarshal(data, &filter); err != nil {
+		return 0, TriggerFilter{}, fmt.Errorf("ошибка чтения фильтра: %v", err)
+	}
+	return userID, filter, nil
+}
+
+// cleanupListViews удаляет фильтры старых списков: их кнопки перестают работать
+func (h *BotDatabaseHandler) cleanupListViews() {
+	query := "DELETE FROM bushlatinga_bot.admin_list_views WHERE created_at < NOW() - $1::float8 * INTERVAL '1 second'"
+	if _, err := h.db.Exec(query, listViewRetention.Seconds()); err != nil {
+		log.Printf("⚠️ Ошибка очистки списков: %v", err)
+	}
+}
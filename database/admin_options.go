@@ -0,0 +1,341 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Синонимы имен опций команд администратора: опция пишется как имя=значение
+var adminOptionAliases = map[string]string{
+	"mode":      "mode",
+	"режим":     "mode",
+	"type":      "type",
+	"тип":       "type",
+	"priority":  "priority",
+	"приоритет": "priority",
+	"weight":    "weight",
+	"вес":       "weight",
+	"response":  "response",
+	"ответ":     "response",
+	"scope":     "scope",
+	"область":   "scope",
+	"чаты":      "scope",
+	"cooldown":  "cooldown",
+	"кулдаун":   "cooldown",
+	"chance":    "chance",
+	"шанс":      "chance",
+	"schedule":  "schedule",
+	"когда":     "schedule",
+	"fuzzy":     "fuzzy",
+	"нечетко":   "fuzzy",
+	"created":   "created",
+	"создан":    "created",
+	"hits":      "hits",
+	"сработал":  "hits",
+	"expires":   "expires",
+	"срок":      "expires",
+}
+
+// Кавычки, в которые берется аргумент с пробелами: открывающая → закрывающая
+var adminQuotes = map[rune]rune{'"': '"', '«': '»', '“': '”'}
+
+// adminArg - аргумент команды и позиция в строке, где он закончился
+type adminArg struct {
+	text string
+	end  int
+}
+
+// tokenizeAdminArgs делит строку аргументов по пробелам и переводам строк.
+// Текст в кавычках "...", «...» или “...” не делится и добавляется без кавычек:
+// так задаются ключи из нескольких слов и опции с пробелами (schedule="пн-пт 09:00-18:00").
+// Кавычка без пары остается обычным символом.
+func tokenizeAdminArgs(s string) []adminArg {
+	var args []adminArg
+	i := 0
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if unicode.IsSpace(r) {
+			i += size
+			continue
+		}
+
+		var text strings.Builder
+		for i < len(s) {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if unicode.IsSpace(r) {
+				break
+			}
+			if closing, ok := adminQuotes[r]; ok {
+				if j := strings.IndexRune(s[i+size:], closing); j >= 0 {
+					text.WriteString(s[i+size : i+size+j])
+					i += size + j + utf8.RuneLen(closing)
+					continue
+				}
+			}
+			text.WriteRune(r)
+			i += size
+		}
+		args = append(args, adminArg{text: text.String(), end: i})
+	}
+	return args
+}
+
+// splitAdminArgs делит строку аргументов команды с учетом кавычек
+func splitAdminArgs(s string) []string {
+	args := tokenizeAdminArgs(s)
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = arg.text
+	}
+	return parts
+}
+
+// argsTail возвращает строку аргументов после первых n как есть, с переводами строк,
+// чтобы ответ триггера мог быть многострочным. Если остаток целиком в кавычках, они снимаются.
+func argsTail(s string, n int) string {
+	args := tokenizeAdminArgs(s)
+	if n > len(args) {
+		return ""
+	}
+	if n > 0 {
+		s = s[args[n-1].end:]
+	}
+	tail := strings.TrimSpace(s)
+
+	first, _ := utf8.DecodeRuneInString(tail)
+	last, _ := utf8.DecodeLastRuneInString(tail)
+	if closing, ok := adminQuotes[first]; ok && last == closing {
+		if rest := tokenizeAdminArgs(tail); len(rest) == 1 {
+			return rest[0].text
+		}
+	}
+	return tail
+}
+
+// parseAdminOptions забирает из начала аргументов опции вида имя=значение.
+// Разбор останавливается на первом аргументе, который не является известной опцией,
+// поэтому триггер с "=" внутри можно указать после опций.
+func parseAdminOptions(args []string) (map[string]string, []string) {
+	opts := make(map[string]string)
+
+	for len(args) > 0 {
+		name, value, ok := strings.Cut(args[0], "=")
+		if !ok {
+			break
+		}
+		canonical, known := adminOptionAliases[strings.ToLower(name)]
+		if !known {
+			break
+		}
+		opts[canonical] = value
+		args = args[1:]
+	}
+
+	return opts, args
+}
+
+// scopeOption возвращает область из опции scope=; без опции - текущий чат (в личке - глобально)
+func scopeOption(opts map[string]string, chatID int64) (Scope, error) {
+	return ParseScope(opts["scope"], chatID)
+}
+
+// filterOption возвращает фильтр для list и search.
+// scope=all, а в личке и отсутствие опции - все триггеры без фильтра.
+// type=, created= и hits= дополнительно сужают выборку.
+func filterOption(opts map[string]string, chatID int64) (TriggerFilter, error) {
+	filter, err := scopeFilter(opts, chatID)
+	if err != nil {
+		return TriggerFilter{}, err
+	}
+
+	if value, ok := opts["type"]; ok {
+		if filter.Type, err = ParseTriggerType(value); err != nil {
+			return TriggerFilter{}, err
+		}
+	}
+	if value, ok := opts["created"]; ok {
+		if filter.CreatedFrom, filter.CreatedTo, err = parseDateRange(value); err != nil {
+			return TriggerFilter{}, err
+		}
+	}
+	if value, ok := opts["hits"]; ok {
+		if filter.MinHits, filter.MaxHits, err = parseHitRange(value); err != nil {
+			return TriggerFilter{}, err
+		}
+	}
+	return filter, nil
+}
+
+// scopeFilter - часть фильтра, отвечающая за область
+func scopeFilter(opts map[string]string, chatID int64) (TriggerFilter, error) {
+	value, ok := opts["scope"]
+	switch strings.ToLower(value) {
+	case "all", "все":
+		return TriggerFilter{AllScopes: true}, nil
+	case "":
+		if !ok && chatID > 0 {
+			return TriggerFilter{AllScopes: true}, nil
+		}
+	}
+
+	scope, err := ParseScope(value, chatID)
+	if err != nil {
+		return TriggerFilter{}, err
+	}
+	return TriggerFilter{Scope: scope}, nil
+}
+
+// parseDateRange разбирает диапазон дат created=: 2024-12-25, 2024-12-01..2024-12-31,
+// 2024-12-01.. или ..2024-12-31. Возвращает начало и конец (не включая) диапазона в UTC.
+func parseDateRange(value string) (time.Time, time.Time, error) {
+	fromValue, toValue, isRange := strings.Cut(strings.TrimSpace(value), "..")
+	if !isRange {
+		toValue = fromValue
+	}
+
+	var from, to time.Time
+	for _, bound := range []struct {
+		value  string
+		target *time.Time
+		days   int
+	}{{fromValue, &from, 0}, {toValue, &to, 1}} {
+		if bound.value == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", bound.value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("некорректные даты '%s': ожидается 2024-12-25 или 2024-12-01..2024-12-31", value)
+		}
+		*bound.target = date.AddDate(0, 0, bound.days)
+	}
+	if from.IsZero() && to.IsZero() {
+		return time.Time{}, time.Time{}, fmt.Errorf("некорректные даты '%s': ожидается 2024-12-25 или 2024-12-01..2024-12-31", value)
+	}
+	return from, to, nil
+}
+
+// formatDateRange форматирует диапазон дат так же, как его вводит админ
+func formatDateRange(from, to time.Time) string {
+	var fromValue, toValue string
+	if !from.IsZero() {
+		fromValue = from.Format("2006-01-02")
+	}
+	if !to.IsZero() {
+		toValue = to.AddDate(0, 0, -1).Format("2006-01-02")
+	}
+	if fromValue == toValue {
+		return fromValue
+	}
+	return fromValue + ".." + toValue
+}
+
+// parseHitRange разбирает число срабатываний hits=: 0, 10.., ..5 или 3..10
+func parseHitRange(value string) (int64, *int64, error) {
+	minValue, maxValue, isRange := strings.Cut(strings.TrimSpace(value), "..")
+	if !isRange {
+		maxValue = minValue
+	}
+
+	var minHits int64
+	var maxHits *int64
+	var err error
+	if minValue != "" {
+		if minHits, err = strconv.ParseInt(minValue, 10, 64); err != nil || minHits < 0 {
+			return 0, nil, fmt.Errorf("некорректное число срабатываний '%s': ожидается 0, 10.., ..5 или 3..10", value)
+		}
+	}
+	if maxValue != "" {
+		n, err := strconv.ParseInt(maxValue, 10, 64)
+		if err != nil || n < minHits {
+			return 0, nil, fmt.Errorf("некорректное число срабатываний '%s': ожидается 0, 10.., ..5 или 3..10", value)
+		}
+		maxHits = &n
+	}
+	if minValue == "" && maxValue == "" {
+		return 0, nil, fmt.Errorf("некорректное число срабатываний '%s': ожидается 0, 10.., ..5 или 3..10", value)
+	}
+	return minHits, maxHits, nil
+}
+
+// formatHitRange форматирует диапазон срабатываний так же, как его вводит админ
+func formatHitRange(minHits int64, maxHits *int64) string {
+	switch {
+	case maxHits == nil:
+		return fmt.Sprintf("%d..", minHits)
+	case *maxHits == minHits:
+		return strconv.FormatInt(minHits, 10)
+	case minHits == 0:
+		return fmt.Sprintf("..%d", *maxHits)
+	default:
+		return fmt.Sprintf("%d..%d", minHits, *maxHits)
+	}
+}
+
+// triggerOptions собирает параметры триггера из опций /admin add
+func triggerOptions(opts map[string]string, chatID int64, location *time.Location) (TriggerOptions, error) {
+	scope, err := scopeOption(opts, chatID)
+	if err != nil {
+		return TriggerOptions{}, err
+	}
+
+	triggerOpts := TriggerOptions{MatchMode: opts["mode"], Type: opts["type"], Scope: scope}
+	if _, ok := opts["priority"]; ok {
+		priority, err := intOption(opts, "priority", 0)
+		if err != nil {
+			return TriggerOptions{}, err
+		}
+		triggerOpts.Priority = &priority
+	}
+	if value, ok := opts["cooldown"]; ok {
+		cooldown, err := ParseCooldown(value)
+		if err != nil {
+			return TriggerOptions{}, err
+		}
+		triggerOpts.Cooldown = &cooldown
+	}
+	if value, ok := opts["chance"]; ok {
+		chance, err := ParseProbability(value)
+		if err != nil {
+			return TriggerOptions{}, err
+		}
+		triggerOpts.Chance = &chance
+	}
+	if value, ok := opts["schedule"]; ok {
+		triggerOpts.Schedule = &value
+	}
+	if value, ok := opts["fuzzy"]; ok {
+		fuzzy, err := ParseFuzzy(value)
+		if err != nil {
+			return TriggerOptions{}, err
+		}
+		triggerOpts.Fuzzy = &fuzzy
+	}
+	if value, ok := opts["expires"]; ok {
+		expiresAt, err := ParseExpiry(value, time.Now().In(location))
+		if err != nil {
+			return TriggerOptions{}, err
+		}
+		triggerOpts.ExpiresAt = &expiresAt
+	}
+	if triggerOpts.Weight, err = intOption(opts, "weight", 1); err != nil {
+		return TriggerOptions{}, err
+	}
+	return triggerOpts, nil
+}
+
+// intOption возвращает целочисленную опцию или defaultValue, если она не задана
+func intOption(opts map[string]string, name string, defaultValue int) (int, error) {
+	value, ok := opts[name]
+	if !ok || value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("опция %s должна быть целым числом, получено '%s'", name, value)
+	}
+	return n, nil
+}
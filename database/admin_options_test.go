@@ -0,0 +1,183 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitAdminArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"plain words", "add key value", []string{"add", "key", "value"}},
+		{"quoted key", `add "славик привет" Привет!`, []string{"add", "славик привет", "Привет!"}},
+		{"guillemets", "«ключ с пробелом» ответ", []string{"ключ с пробелом", "ответ"}},
+		{"curly quotes", "“a b” c", []string{"a b", "c"}},
+		{"quoted option value", `schedule="пн-пт 09:00-18:00" key`, []string{"schedule=пн-пт 09:00-18:00", "key"}},
+		{"quotes inside word", `a"b c"d`, []string{"ab cd"}},
+		{"mixed quoted and plain", `key "два слова" и ещё`, []string{"key", "два слова", "и", "ещё"}},
+		{"empty quotes", `key ""`, []string{"key", ""}},
+		{"unclosed quote stays literal", `"ключ ответ`, []string{`"ключ`, "ответ"}},
+		{"unmatched quote pair", `«a"`, []string{`«a"`}},
+		{"newlines and tabs separate", "a\nb\t c", []string{"a", "b", "c"}},
+		{"quoted text keeps newline", "\"a\nb\" c", []string{"a\nb", "c"}},
+		{"empty", "   ", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitAdminArgs(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitAdminArgs(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArgsTail(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		n    int
+		want string
+	}{
+		{"rest as is", "add key value one", 2, "value one"},
+		{"inner spaces kept", "add key   spaced   value  ", 2, "spaced   value"},
+		{"multi-line value", "add key line1\nline2\n\nline4", 2, "line1\nline2\n\nline4"},
+		{"fully quoted value", `add key "whole value"`, 2, "whole value"},
+		{"fully quoted in guillemets", "add key «весь ответ»", 2, "весь ответ"},
+		{"quoted multi-line value", "add key \"multi\nline\"", 2, "multi\nline"},
+		{"several quoted parts stay as is", `add key "a" b "c"`, 2, `"a" b "c"`},
+		{"quoted key is skipped", `add "ключ с пробелом" ответ`, 2, "ответ"},
+		{"unclosed quote", `add key "unclosed value`, 2, `"unclosed value`},
+		{"nothing after key", "add key", 2, ""},
+		{"fewer args than n", "add", 2, ""},
+		{"n is zero", "  add key  ", 0, "add key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := argsTail(tt.in, tt.n); got != tt.want {
+				t.Errorf("argsTail(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAdminOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       []string
+		wantOpts map[string]string
+		wantArgs []string
+	}{
+		{"no options", []string{"key", "value"}, map[string]string{}, []string{"key", "value"}},
+		{"options before key", []string{"mode=stem", "тип=text", "key"}, map[string]string{"mode": "stem", "type": "text"}, []string{"key"}},
+		{"option name is case insensitive", []string{"Mode=word", "key"}, map[string]string{"mode": "word"}, []string{"key"}},
+		{"value may contain equals", []string{"response=a=b", "key"}, map[string]string{"response": "a=b"}, []string{"key"}},
+		{"unknown option starts the key", []string{"a=b", "mode=stem"}, map[string]string{}, []string{"a=b", "mode=stem"}},
+		{"options only", []string{"scope=all"}, map[string]string{"scope": "all"}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, args := parseAdminOptions(tt.in)
+			if !reflect.DeepEqual(opts, tt.wantOpts) {
+				t.Errorf("parseAdminOptions(%q) options = %v, want %v", tt.in, opts, tt.wantOpts)
+			}
+			if len(args) != len(tt.wantArgs) || (len(args) > 0 && !reflect.DeepEqual(args, tt.wantArgs)) {
+				t.Errorf("parseAdminOptions(%q) args = %q, want %q", tt.in, args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestAddCommandArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		command   string
+		wantOpts  map[string]string
+		wantKey   string
+		wantValue string
+	}{
+		{
+			name:      "key and value",
+			command:   "add key value",
+			wantOpts:  map[string]string{},
+			wantKey:   "key",
+			wantValue: "value",
+		},
+		{
+			name:      "options before quoted key",
+			command:   `add mode=stem "славик привет" Привет, как дела?`,
+			wantOpts:  map[string]string{"mode": "stem"},
+			wantKey:   "славик привет",
+			wantValue: "Привет, как дела?",
+		},
+		{
+			name:      "several options and multi-line value",
+			command:   "add type=regex priority=5 key line1\nline2",
+			wantOpts:  map[string]string{"type": "regex", "priority": "5"},
+			wantKey:   "key",
+			wantValue: "line1\nline2",
+		},
+		{
+			name:      "quoted option and fully quoted value",
+			command:   `add schedule="пн-пт 09:00-18:00" key "целиком в кавычках"`,
+			wantOpts:  map[string]string{"schedule": "пн-пт 09:00-18:00"},
+			wantKey:   "key",
+			wantValue: "целиком в кавычках",
+		},
+		{
+			name:      "mixed quoted and plain value",
+			command:   `add key "раз два" три`,
+			wantOpts:  map[string]string{},
+			wantKey:   "key",
+			wantValue: `"раз два" три`,
+		},
+		{
+			name:      "unclosed quote in value",
+			command:   `add «ключ» "незакрытая кавычка`,
+			wantOpts:  map[string]string{},
+			wantKey:   "ключ",
+			wantValue: `"незакрытая кавычка`,
+		},
+		{
+			name:      "unknown option is the key",
+			command:   "add a=b c",
+			wantOpts:  map[string]string{},
+			wantKey:   "a=b",
+			wantValue: "c",
+		},
+		{
+			name:      "key without value",
+			command:   "add mode=word key",
+			wantOpts:  map[string]string{"mode": "word"},
+			wantKey:   "key",
+			wantValue: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, args, value := addCommandArgs(tt.command, splitAdminArgs(tt.command)[1:])
+			if !reflect.DeepEqual(opts, tt.wantOpts) {
+				t.Errorf("options = %v, want %v", opts, tt.wantOpts)
+			}
+			if len(args) == 0 || args[0] != tt.wantKey {
+				t.Errorf("args = %q, want key %q", args, tt.wantKey)
+			}
+			if value != tt.wantValue {
+				t.Errorf("value = %q, want %q", value, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestAddCommandArgsWithoutKey(t *testing.T) {
+	opts, args, value := addCommandArgs("add mode=stem", []string{"mode=stem"})
+	if opts["mode"] != "stem" || len(args) != 0 || value != "" {
+		t.Errorf("addCommandArgs = %v, %q, %q, want mode=stem without key and value", opts, args, value)
+	}
+}
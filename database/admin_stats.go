@@ -0,0 +1,142 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Период /admin stats по умолчанию, в днях
+const defaultStatsDays = 30
+
+// Названия исходов совпадений для /admin stats
+var matchOutcomeNames = []struct{ outcome, name string }{
+	{MatchChance, "вероятность"},
+	{MatchLimited, "лимиты"},
+	{MatchPolicy, "политика чата"},
+	{MatchEmpty, "нет ответа"},
+}
+
+// handleStatsCommand обрабатывает /admin stats: популярные и мертвые триггеры и разбивка по чатам.
+// В группе по умолчанию показывает текущий чат, в личке - все чаты.
+func (h *BotDatabaseHandler) handleStatsCommand(req AdminRequest, parts []string) string {
+	usage := "❌ Использование: /admin stats [scope=here|ID|all] [дней, по умолчанию 30]"
+	opts, args := parseAdminOptions(parts)
+	if len(args) > 1 {
+		return usage
+	}
+
+	filter, err := scopeFilter(opts, req.ChatID)
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+	var chatID int64
+	switch {
+	case filter.AllScopes || filter.Scope.IsGlobal():
+	case len(filter.Scope) == 1:
+		chatID = filter.Scope[0]
+	default:
+		return "❌ Статистика считается по одному чату или по всем (scope=all)"
+	}
+	if chats, all := h.viewableChats(req.UserID, req.ChatID); !all && (chatID == 0 || !slices.Contains(chats, chatID)) {
+		return "❌ Без глобальной роли статистика доступна только по чату, где у вас есть роль: укажите scope=here или scope=ID"
+	}
+
+	days := defaultStatsDays
+	if len(args) == 1 {
+		days, err = strconv.Atoi(strings.TrimRight(strings.ToLower(args[0]), "dд"))
+		if err != nil || days < 1 {
+			return usage
+		}
+	}
+
+	stats, err := h.TriggerUsageStats(chatID, days)
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+	return formatUsageStats(stats, days)
+}
+
+// formatUsageStats форматирует статистику для админа
+func formatUsageStats(s *UsageStats, days int) string {
+	where := "все чаты"
+	if s.ChatID != 0 {
+		where = fmt.Sprintf("чат %d", s.ChatID)
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("📈 Статистика за %d дней (%s)\n\n", days, where))
+
+	var total int64
+	for _, count := range s.Outcomes {
+		total += count
+	}
+	result.WriteString(fmt.Sprintf("Совпадений: %d, ответов: %d\n", total, s.Outcomes[MatchSent]))
+	var suppressed []string
+	for _, o := range matchOutcomeNames {
+		if count := s.Outcomes[o.outcome]; count > 0 {
+			suppressed = append(suppressed, fmt.Sprintf("%s %d", o.name, count))
+		}
+	}
+	if len(suppressed) > 0 {
+		result.WriteString("Без ответа: " + strings.Join(suppressed, ", ") + "\n")
+	}
+
+	if len(s.Top) > 0 {
+		result.WriteString("\n🏆 Чаще всего:\n")
+		for i, u := range s.Top {
+			result.WriteString(fmt.Sprintf("%d. %s - ответов %d", i+1, usageKey(u), u.Sent))
+			if u.Suppressed > 0 {
+				result.WriteString(fmt.Sprintf(", без ответа %d", u.Suppressed))
+			}
+			result.WriteString("\n")
+		}
+	}
+
+	if s.DeadTotal > 0 {
+		result.WriteString(fmt.Sprintf("\n💤 Ни одного ответа за %d дней (%d):\n", days, s.DeadTotal))
+		for _, u := range s.Dead {
+			lastHit := "никогда"
+			if !u.LastHit.IsZero() {
+				lastHit = u.LastHit.Format("2006-01-02")
+			}
+			result.WriteString(fmt.Sprintf("• %s, последний ответ: %s\n", usageKey(u), lastHit))
+		}
+		if s.DeadTotal > len(s.Dead) {
+			result.WriteString(fmt.Sprintf("...и еще %d, все: /admin list hits=0\n", s.DeadTotal-len(s.Dead)))
+		}
+	}
+
+	if len(s.Chats) > 0 {
+		result.WriteString("\n💬 По чатам:\n")
+		for _, c := range s.Chats {
+			title := strconv.FormatInt(c.ChatID, 10)
+			if c.Title != "" {
+				title = fmt.Sprintf("%s (%d)", codeSafe(c.Title), c.ChatID)
+			}
+			result.WriteString(fmt.Sprintf("• %s - ответов %d", title, c.Sent))
+			if c.Suppressed > 0 {
+				result.WriteString(fmt.Sprintf(", без ответа %d", c.Suppressed))
+			}
+			result.WriteString("\n")
+		}
+	}
+
+	if s.HasTotals {
+		result.WriteString(fmt.Sprintf("\nЗа все время: совпадений триггеров %d, детекторов %d", s.NameMatches, s.DetectorMatches))
+	}
+	return strings.TrimRight(result.String(), "\n")
+}
+
+// usageKey форматирует триггер из статистики; удаленный насовсем показывается по ID
+func usageKey(u TriggerUsage) string {
+	if u.Key == "" {
+		return fmt.Sprintf("#%d (удален)", u.ID)
+	}
+	key := fmt.Sprintf("`%s`", codeSafe(u.Key))
+	if !u.Scope.IsGlobal() {
+		key += " (" + u.Scope.String() + ")"
+	}
+	return key
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Сколько изменений и ошибок каждого вида показывать в предпросмотре импорта
+const importPreviewLimit = 20
+
+// handleExportCommand обрабатывает /admin export: выгружает триггеры файлом JSON или CSV
+func (h *BotDatabaseHandler) handleExportCommand(req AdminRequest, parts []string) AdminReply {
+	opts, args := parseAdminOptions(parts)
+	filter, err := filterOption(opts, req.ChatID)
+	if err == nil {
+		filter, err = h.clampFilter(req.UserID, req.ChatID, filter)
+	}
+	if err != nil {
+		return AdminReply{Text: fmt.Sprintf("❌ Ошибка: %v", err)}
+	}
+
+	format := FormatJSON
+	if len(args) > 0 {
+		if format, err = ParseTransferFormat(args[0]); err != nil {
+			return AdminReply{Text: fmt.Sprintf("❌ Ошибка: %v", err)}
+		}
+	}
+
+	data, count, err := h.ExportTriggers(filter, format)
+	if err != nil {
+		return AdminReply{Text: fmt.Sprintf("❌ Ошибка: %v", err)}
+	}
+	if count == 0 {
+		return AdminReply{Text: fmt.Sprintf("📭 Нет триггеров для экспорта (%s)", filter)}
+	}
+
+	return AdminReply{
+		Text: fmt.Sprintf("📦 Экспорт: %d триггеров (%s)\nИмпорт: ответьте на файл командой /admin import", count, filter),
+		Document: &AdminDocument{
+			Name: fmt.Sprintf("triggers-%s.%s", time.Now().Format("20060102-1504"), format),
+			Data: data,
+		},
+	}
+}
+
+// handleImportCommand обрабатывает /admin import ответом на файл.
+// Без apply показывает, что изменится; с apply применяет, если в файле нет ошибок.
+func (h *BotDatabaseHandler) handleImportCommand(req AdminRequest, parts []string) string {
+	usage := "❌ Использование: ответьте на файл JSON или CSV из /admin export командой\n" +
+		"/admin import [merge|replace] [apply]\n" +
+		"merge - добавить и обновить, replace - еще и удалить триггеры тех же областей, которых нет в файле"
+	if req.ReplyFile == nil {
+		return usage
+	}
+
+	mode := ImportMerge
+	apply := false
+	for _, arg := range parts {
+		switch strings.ToLower(arg) {
+		case "apply", "применить":
+			apply = true
+		default:
+			var err error
+			if mode, err = ParseImportMode(arg); err != nil {
+				return usage
+			}
+		}
+	}
+
+	if req.ReplyFile.Size > maxImportFileSize {
+		return fmt.Sprintf("❌ Файл больше %d КБ", maxImportFileSize>>10)
+	}
+	data, err := req.ReplyFile.Load()
+	if err != nil {
+		return fmt.Sprintf("❌ Не удалось скачать файл: %v", err)
+	}
+
+	format := DetectTransferFormat(req.ReplyFile.Name, data)
+	report, err := h.ImportTriggers(req.actor(), data, format, mode, apply)
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка: %v", err)
+	}
+	return formatImportReport(report, apply)
+}
+
+// formatImportReport форматирует предпросмотр или результат импорта
+func formatImportReport(r *ImportReport, apply bool) string {
+	var result strings.Builder
+	switch {
+	case r.Applied:
+		result.WriteString(fmt.Sprintf("✅ Импорт (%s) применен\n", r.Mode))
+	case apply:
+		result.WriteString(fmt.Sprintf("❌ Импорт (%s) не применен: исправьте ошибки в файле\n", r.Mode))
+	default:
+		result.WriteString(fmt.Sprintf("🔍 Предпросмотр импорта (%s), ничего не изменено\n", r.Mode))
+	}
+	result.WriteString(fmt.Sprintf("В файле триггеров: %d, без изменений: %d\n", r.Total, r.Unchanged))
+
+	for _, group := range []struct {
+		title   string
+		changes []ImportChange
+	}{{"➕ Добавятся", r.Added}, {"✏️ Изменятся", r.Changed}, {"➖ Удалятся", r.Removed}} {
+		if len(group.changes) == 0 {
+			continue
+		}
+		result.WriteString(fmt.Sprintf("\n%s (%d):\n", group.title, len(group.changes)))
+		for i, c := range group.changes {
+			if i == importPreviewLimit {
+				result.WriteString(fmt.Sprintf("...и еще %d\n", len(group.changes)-i))
+				break
+			}
+			result.WriteString(fmt.Sprintf("• `%s` (%s)\n", codeSafe(c.Key), c.Scope))
+		}
+	}
+
+	if len(r.Errors) > 0 {
+		result.WriteString(fmt.Sprintf("\n⚠️ Ошибки (%d):\n", len(r.Errors)))
+		for i, e := range r.Errors {
+			if i == importPreviewLimit {
+				result.WriteString(fmt.Sprintf("...и еще %d\n", len(r.Errors)-i))
+				break
+			}
+			result.WriteString(fmt.Sprintf("строка %d: %v\n", e.Line, e.Err))
+		}
+	}
+
+	if !r.Applied && !apply && len(r.Errors) == 0 && len(r.Added)+len(r.Changed)+len(r.Removed) > 0 {
+		result.WriteString(fmt.Sprintf("\nПрименить: ответьте на файл командой /admin import %s apply", r.Mode))
+	}
+	return result.String()
+}
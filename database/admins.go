@@ -0,0 +1,272 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Уведомление в канале изменений: назначения ролей изменились, реплики перечитывают их
+const rolesNotifyReason = "roles"
+
+// Роли администраторов бота (таблица admins)
+const (
+	RoleViewer = "viewer" // смотреть триггеры и настройки
+	RoleEditor = "editor" // менять триггеры и настройки чата
+	RoleOwner  = "owner"  // еще и назначать администраторов
+)
+
+// roleRanks упорядочивает роли: старшая роль включает права младших
+var roleRanks = map[string]int{RoleViewer: 1, RoleEditor: 2, RoleOwner: 3}
+
+// ParseRole приводит введенную роль к значению для БД
+func ParseRole(role string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(role)) {
+	case RoleViewer, "зритель", "читатель":
+		return RoleViewer, nil
+	case RoleEditor, "редактор":
+		return RoleEditor, nil
+	case RoleOwner, "владелец":
+		return RoleOwner, nil
+	default:
+		return "", fmt.Errorf("неизвестная роль '%s' (доступно: owner, editor, viewer)", role)
+	}
+}
+
+// hasRole сообщает, что роль role не ниже required
+func hasRole(role, required string) bool {
+	return roleRanks[role] >= roleRanks[required]
+}
+
+// ChatAdminChecker проверяет, что пользователь - администратор чата в Telegram
+type ChatAdminChecker func(chatID, userID int64) bool
+
+// SetChatAdminChecker подключает проверку администраторов чата через Telegram API.
+// Без нее админы чатов не получают прав, даже если это включено в настройках чата.
+func (h *BotDatabaseHandler) SetChatAdminChecker(checker ChatAdminChecker) {
+	h.mu.Lock()
+	h.chatAdminChecker = checker
+	h.mu.Unlock()
+}
+
+// UserRole возвращает роль пользователя в чате: глобальные назначения, назначения на этот чат
+// и, если включено в настройках чата, права редактора для его администраторов в Telegram.
+// chatID = 0 - только глобальные назначения. Пустая строка - прав нет.
+// Назначения берутся из памяти, см. reloadRoles.
+func (h *BotDatabaseHandler) UserRole(userID, chatID int64) string {
+	h.mu.RLock()
+	role := h.roles[userID][0]
+	if chatRole := h.roles[userID][chatID]; roleRanks[chatRole] > roleRanks[role] {
+		role = chatRole
+	}
+	h.mu.RUnlock()
+
+	if !hasRole(role, RoleEditor) && chatID < 0 && h.GetChatSettings(chatID).ChatAdminsEdit {
+		h.mu.RLock()
+		checker := h.chatAdminChecker
+		h.mu.RUnlock()
+		if checker != nil && checker(chatID, userID) {
+			role = RoleEditor
+		}
+	}
+	return role
+}
+
+// IsAdmin проверяет, есть ли у пользователя хоть какая-то роль (в чате или глобально)
+func (h *BotDatabaseHandler) IsAdmin(userID int64) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.roles[userID]) > 0
+}
+
+// reloadRoles перечитывает назначения ролей. Роли проверяются на каждую команду и кнопку,
+// поэтому держатся в памяти и обновляются по уведомлению после grant и revoke.
+func (h *BotDatabaseHandler) reloadRoles() error {
+	rows, err := h.db.Query("SELECT user_id, chat_id, role FROM bushlatinga_bot.admins")
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки ролей: %v", err)
+	}
+	defer rows.Close()
+
+	roles := make(map[int64]map[int64]string)
+	for rows.Next() {
+		var userID, chatID int64
+		var role string
+		if err := rows.Scan(&userID, &chatID, &role); err != nil {
+			return fmt.Errorf("ошибка чтения роли: %v", err)
+		}
+		if roles[userID] == nil {
+			roles[userID] = make(map[int64]string)
+		}
+		roles[userID][chatID] = role
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка чтения ролей: %v", err)
+	}
+
+	h.mu.Lock()
+	h.roles = roles
+	h.mu.Unlock()
+	return nil
+}
+
+// notifyRolesChanged перечитывает роли на этой реплике и сообщает об изменении остальным
+func (h *BotDatabaseHandler) notifyRolesChanged() {
+	if err := h.reloadRoles(); err != nil {
+		log.Printf("❌ Ошибка перезагрузки ролей: %v", err)
+	}
+	h.publishChange(rolesNotifyReason)
+}
+
+// canEditScope проверяет, что пользователь может менять триггеры в области:
+// глобальные - только с глобальной ролью, иначе роль редактора нужна в каждом чате области
+func (h *BotDatabaseHandler) canEditScope(userID int64, scope Scope) bool {
+	if scope.IsGlobal() {
+		return hasRole(h.UserRole(userID, 0), RoleEditor)
+	}
+	for _, chatID := range scope {
+		if !hasRole(h.UserRole(userID, chatID), RoleEditor) {
+			return false
+		}
+	}
+	return true
+}
+
+// bootstrapOwner назначает ownerID глобальным владельцем, если в таблице admins нет ни одного.
+// Так у бота появляется первый владелец, а отозвать его потом можно как любого другого.
+func (h *BotDatabaseHandler) bootstrapOwner(ownerID int64) error {
+	var owners int
+	err := h.db.QueryRow("SELECT COUNT(*) FROM bushlatinga_bot.admins WHERE chat_id = 0 AND role = $1", RoleOwner).Scan(&owners)
+	if err != nil {
+		return fmt.Errorf("ошибка проверки владельцев: %v", err)
+	}
+	if owners > 0 {
+		return nil
+	}
+	if ownerID == 0 {
+		log.Println("⚠️ В таблице admins нет владельца и ADMIN_CHAT_ID не задан: команды /admin никому не доступны")
+		return nil
+	}
+
+	query := `
+		INSERT INTO bushlatinga_bot.admins (user_id, chat_id, role)
+		VALUES ($1, 0, $2)
+		ON CONFLICT (user_id, chat_id) DO UPDATE SET role = EXCLUDED.role
+	`
+	if _, err := h.db.Exec(query, ownerID, RoleOwner); err != nil {
+		return fmt.Errorf("ошибка назначения первого владельца: %v", err)
+	}
+	log.Printf("👑 [bushlatinga_bot] Первый владелец бота: %d", ownerID)
+	return nil
+}
+
+// isLastOwner сообщает, что пользователь - единственный глобальный владелец:
+// его роль нельзя отозвать или понизить, иначе управлять ботом станет некому
+func (h *BotDatabaseHandler) isLastOwner(userID int64) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.roles[userID][0] != RoleOwner {
+		return false
+	}
+	for id, chats := range h.roles {
+		if id != userID && chats[0] == RoleOwner {
+			return false
+		}
+	}
+	return true
+}
+
+// AdminGrant - одно назначение роли из таблицы admins
+type AdminGrant struct {
+	UserID    int64
+	Role      string
+	ChatID    int64 // 0 - во всех чатах
+	GrantedBy int64
+	CreatedAt time.Time
+}
+
+// GrantRole назначает пользователю роль во всех чатах (chatID = 0) или в одном чате
+func (h *BotDatabaseHandler) GrantRole(userID, chatID int64, role string, grantedBy int64) error {
+	role, err := ParseRole(role)
+	if err != nil {
+		return err
+	}
+	if chatID == 0 && role != RoleOwner && h.isLastOwner(userID) {
+		return fmt.Errorf("пользователь %d - единственный владелец, сначала назначьте другого", userID)
+	}
+
+	query := `
+		INSERT INTO bushlatinga_bot.admins (user_id, chat_id, role, granted_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, chat_id) DO UPDATE SET
+			role = EXCLUDED.role,
+			granted_by = EXCLUDED.granted_by,
+			created_at = NOW()
+	`
+	if _, err := h.db.Exec(query, userID, chatID, role, grantedBy); err != nil {
+		return fmt.Errorf("ошибка назначения роли: %v", err)
+	}
+
+	log.Printf("✅ [bushlatinga_bot] Пользователь %d получил роль %s (%s) от %d", userID, role, grantScope(chatID), grantedBy)
+	h.notifyRolesChanged()
+	return nil
+}
+
+// RevokeRole отзывает роль пользователя во всех чатах (chatID = 0) или в одном чате
+func (h *BotDatabaseHandler) RevokeRole(userID, chatID int64) error {
+	if chatID == 0 && h.isLastOwner(userID) {
+		return fmt.Errorf("пользователь %d - единственный владелец, сначала назначьте другого", userID)
+	}
+
+	result, err := h.db.Exec("DELETE FROM bushlatinga_bot.admins WHERE user_id = $1 AND chat_id = $2", userID, chatID)
+	if err != nil {
+		return fmt.Errorf("ошибка отзыва роли: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("у пользователя %d нет роли (%s)", userID, grantScope(chatID))
+	}
+
+	log.Printf("✅ [bushlatinga_bot] У пользователя %d отозвана роль (%s)", userID, grantScope(chatID))
+	h.notifyRolesChanged()
+	return nil
+}
+
+// ListAdmins возвращает все назначения ролей
+func (h *BotDatabaseHandler) ListAdmins() ([]AdminGrant, error) {
+	query := `
+		SELECT user_id, role, chat_id, COALESCE(granted_by, 0), created_at
+		FROM bushlatinga_bot.admins
+		ORDER BY chat_id, user_id
+	`
+	rows, err := h.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки администраторов: %v", err)
+	}
+	defer rows.Close()
+
+	var grants []AdminGrant
+	for rows.Next() {
+		var g AdminGrant
+		if err := rows.Scan(&g.UserID, &g.Role, &g.ChatID, &g.GrantedBy, &g.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка чтения администратора: %v", err)
+		}
+		grants = append(grants, g)
+	}
+	return grants, rows.Err()
+}
+
+// SetChatAdminsEdit разрешает или запрещает администраторам чата в Telegram менять его триггеры
+func (h *BotDatabaseHandler) SetChatAdminsEdit(chatID int64, enabled bool) error {
+	return h.saveChatSettings(chatID, map[string]interface{}{"chat_admins_edit": enabled})
+}
+
+// grantScope форматирует область назначения роли
+func grantScope(chatID int64) string {
+	if chatID == 0 {
+		return "все чаты"
+	}
+	return "чат " + strconv.FormatInt(chatID, 10)
+}
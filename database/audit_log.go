@@ -0,0 +1,438 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Действия в журнале изменений триггеров
+const (
+	AuditAdd     = "add"
+	AuditUpdate  = "update"
+	AuditRemove  = "remove"
+	AuditRestore = "restore"
+	AuditUndo    = "undo"
+)
+
+// Actor - кто и из какого чата меняет триггеры (для журнала изменений)
+type Actor struct {
+	UserID int64
+	ChatID int64
+}
+
+// triggerState - полное состояние триггера вместе с ответами, как оно хранится в журнале.
+// nil означает, что триггера нет (до создания или после удаления).
+type triggerState struct {
+	Text            string          `json:"trigger_text"`
+	MatchMode       string          `json:"match_mode"`
+	Type            string          `json:"trigger_type"`
+	Priority        int             `json:"priority"`
+	ChatIDs         []int64         `json:"chat_ids"`
+	CooldownSeconds int             `json:"cooldown_seconds"`
+	Probability     int             `json:"probability"`
+	Schedule        string          `json:"schedule"`
+	Enabled         bool            `json:"enabled"`
+	Fuzzy           int             `json:"fuzzy"`
+	ExpiresAt       *time.Time      `json:"expires_at,omitempty"`
+	Responses       []responseState `json:"responses"`
+}
+
+// responseState - один вариант ответа в состоянии триггера
+type responseState struct {
+	Parts  []ResponsePart `json:"parts"`
+	Weight int            `json:"weight"`
+}
+
+// AuditEntry - одна запись журнала: версия триггера после изменения
+type AuditEntry struct {
+	ID        int64
+	Version   int
+	Action    string
+	UserID    int64
+	ChatID    int64 // чат, из которого выполнена команда
+	CreatedAt time.Time
+	Key       string
+	Type      string
+	Scope     Scope
+	Old, New  *triggerState
+}
+
+// loadTriggerState читает текущее состояние триггера; nil, если его нет или он в корзине
+func loadTriggerState(tx *sql.Tx, id int64) (*triggerState, error) {
+	query := `
+		SELECT trigger_text, match_mode, trigger_type, priority, chat_ids, cooldown_seconds,
+		       probability, schedule, enabled, fuzzy, expires_at
+		FROM bushlatinga_bot.bushlatinga_responses
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	s := &triggerState{}
+	var chatIDs pq.Int64Array
+	var expiresAt sql.NullTime
+	err := tx.QueryRow(query, id).Scan(&s.Text, &s.MatchMode, &s.Type, &s.Priority, &chatIDs, &s.CooldownSeconds,
+		&s.Probability, &s.Schedule, &s.Enabled, &s.Fuzzy, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения триггера для журнала: %v", err)
+	}
+	s.ChatIDs = Scope(chatIDs).normalize()
+	s.ExpiresAt = expiryOf(expiresAt.Time)
+
+	rows, err := tx.Query(`
+		SELECT response_type, COALESCE(file_id, ''), response_text, parts, weight
+		FROM bushlatinga_bot.trigger_responses
+		WHERE trigger_id = $1
+		ORDER BY id
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения ответов для журнала: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var responseType, fileID, text string
+		var partsJSON []byte
+		var r responseState
+		if err := rows.Scan(&responseType, &fileID, &text, &partsJSON, &r.Weight); err != nil {
+			return nil, fmt.Errorf("ошибка чтения ответа для журнала: %v", err)
+		}
+		if r.Parts, err = decodeResponse(responseType, fileID, text, partsJSON); err != nil {
+			return nil, err
+		}
+		s.Responses = append(s.Responses, r)
+	}
+	return s, rows.Err()
+}
+
+// expiryOf приводит срок действия к виду для журнала и сравнения состояний; nil - бессрочно
+func expiryOf(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC().Truncate(time.Second)
+	return &t
+}
+
+// findTriggerID ищет триггер (в том числе в корзине) по точному trigger_text и области; 0, если его нет
+func findTriggerID(tx *sql.Tx, text string, scope Scope) (int64, error) {
+	var id int64
+	err := tx.QueryRow("SELECT id FROM bushlatinga_bot.bushlatinga_responses WHERE trigger_text = $1 AND chat_ids = $2",
+		text, scope.array()).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// recordChange записывает изменение триггера в журнал в той же транзакции, что и само изменение.
+// Версии нумеруются отдельно для каждой пары ключ + область, поэтому история
+// переживает удаление и повторное создание триггера.
+func recordChange(tx *sql.Tx, by Actor, action string, before, after *triggerState) error {
+	current := after
+	if current == nil {
+		current = before
+	}
+	if current == nil {
+		return nil
+	}
+
+	oldJSON, err := stateJSON(before)
+	if err != nil {
+		return err
+	}
+	newJSON, err := stateJSON(after)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO bushlatinga_bot.trigger_audit
+			(trigger_text, trigger_type, chat_ids, version, action, user_id, chat_id, old_value, new_value)
+		SELECT $1, $2, $3, COALESCE(MAX(version), 0) + 1, $4, $5, $6, $7, $8
+		FROM bushlatinga_bot.trigger_audit
+		WHERE trigger_text = $1 AND chat_ids = $3
+	`
+	_, err = tx.Exec(query, current.Text, current.Type, Scope(current.ChatIDs).array(), action,
+		by.UserID, by.ChatID, oldJSON, newJSON)
+	if err != nil {
+		return fmt.Errorf("ошибка записи в журнал: %v", err)
+	}
+	return nil
+}
+
+// stateJSON готовит состояние для колонки JSONB; nil записывается как NULL
+func stateJSON(s *triggerState) (sql.NullString, error) {
+	if s == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("ошибка сериализации триггера: %v", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// auditColumns - колонки trigger_audit в порядке, который ожидает scanAuditEntries
+const auditColumns = "id, version, action, COALESCE(user_id, 0), COALESCE(chat_id, 0), created_at, trigger_text, trigger_type, chat_ids, old_value, new_value"
+
+// scanAuditEntries читает записи журнала
+func scanAuditEntries(rows *sql.Rows) ([]AuditEntry, error) {
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		var chatIDs pq.Int64Array
+		var oldJSON, newJSON []byte
+		if err := rows.Scan(&e.ID, &e.Version, &e.Action, &e.UserID, &e.ChatID, &e.CreatedAt,
+			&e.Key, &e.Type, &chatIDs, &oldJSON, &newJSON); err != nil {
+			return nil, fmt.Errorf("ошибка чтения журнала: %v", err)
+		}
+		e.Scope = Scope(chatIDs)
+		for _, v := range []struct {
+			data  []byte
+			state **triggerState
+		}{{oldJSON, &e.Old}, {newJSON, &e.New}} {
+			if v.data == nil {
+				continue
+			}
+			*v.state = &triggerState{}
+			if err := json.Unmarshal(v.data, *v.state); err != nil {
+				return nil, fmt.Errorf("ошибка чтения версии %d: %v", e.Version, err)
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// TriggerHistory возвращает журнал изменений триггера в области, от новых к старым
+func (h *BotDatabaseHandler) TriggerHistory(key string, scope Scope, limit int) ([]AuditEntry, error) {
+	query := "SELECT " + auditColumns + " FROM bushlatinga_bot.trigger_audit WHERE " +
+		triggerKeyCondition("$1") + " AND chat_ids = $2 ORDER BY version DESC LIMIT $3"
+	rows, err := h.db.Query(query, strings.TrimSpace(key), scope.array(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки журнала: %v", err)
+	}
+	return scanAuditEntries(rows)
+}
+
+// LastChangeBy возвращает последнее изменение, сделанное пользователем
+func (h *BotDatabaseHandler) LastChangeBy(userID int64) (*AuditEntry, error) {
+	query := "SELECT " + auditColumns + " FROM bushlatinga_bot.trigger_audit WHERE user_id = $1 ORDER BY id DESC LIMIT 1"
+	rows, err := h.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки журнала: %v", err)
+	}
+	entries, err := scanAuditEntries(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("в журнале нет ваших изменений")
+	}
+	return &entries[0], nil
+}
+
+// UndoChange откатывает изменение из журнала, возвращая триггер в состояние до него.
+// Откат возможен, только пока триггер после этого изменения никто не менял.
+func (h *BotDatabaseHandler) UndoChange(by Actor, entry *AuditEntry) error {
+	return h.applyVersion(by, AuditUndo, entry.Key, entry.Scope, func(tx *sql.Tx) (*triggerState, error) {
+		var latest int
+		err := tx.QueryRow("SELECT MAX(version) FROM bushlatinga_bot.trigger_audit WHERE trigger_text = $1 AND chat_ids = $2",
+			entry.Key, entry.Scope.array()).Scan(&latest)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения журнала: %v", err)
+		}
+		if latest != entry.Version {
+			return nil, fmt.Errorf("после вашего изменения '%s' менялся еще раз (версия %d); используйте /admin restore", entry.Key, latest)
+		}
+		return entry.Old, nil
+	})
+}
+
+// RestoreVersion возвращает триггер в состояние указанной версии из журнала
+func (h *BotDatabaseHandler) RestoreVersion(by Actor, key string, scope Scope, version int) error {
+	query := "SELECT " + auditColumns + " FROM bushlatinga_bot.trigger_audit WHERE " +
+		triggerKeyCondition("$1") + " AND chat_ids = $2 AND version = $3"
+	rows, err := h.db.Query(query, strings.TrimSpace(key), scope.array(), version)
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки журнала: %v", err)
+	}
+	entries, err := scanAuditEntries(rows)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("у '%s' (%s) нет версии %d", key, scope, version)
+	}
+
+	entry := entries[0]
+	return h.applyVersion(by, AuditRestore, entry.Key, entry.Scope, func(*sql.Tx) (*triggerState, error) {
+		return entry.New, nil
+	})
+}
+
+// applyVersion заменяет триггер состоянием, которое вернул target (nil - удалить в корзину),
+// и записывает это в журнал как новую версию
+func (h *BotDatabaseHandler) applyVersion(by Actor, action, text string, scope Scope, target func(*sql.Tx) (*triggerState, error)) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	state, err := target(tx)
+	if err != nil {
+		return err
+	}
+
+	id, err := findTriggerID(tx, text, scope)
+	if err != nil {
+		return fmt.Errorf("ошибка поиска записи: %v", err)
+	}
+	before, err := loadTriggerState(tx, id)
+	if err != nil {
+		return err
+	}
+
+	if state == nil {
+		if err := trashTrigger(tx, by, id); err != nil {
+			return err
+		}
+	} else if id, err = writeTriggerState(tx, state); err != nil {
+		return err
+	}
+
+	after, err := loadTriggerState(tx, id)
+	if err != nil {
+		return err
+	}
+	if err := recordChange(tx, by, action, before, after); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка коммита транзакции: %v", err)
+	}
+
+	log.Printf("✅ [bushlatinga_bot] '%s' [%s]: %s пользователем %d\n", text, scope, action, by.UserID)
+	h.notifyIndexChanged(action + ":" + text)
+	return nil
+}
+
+// writeTriggerState записывает триггер и все его ответы точно как в состоянии
+// (восстановление версии из журнала, импорт). Триггер из корзины при этом возвращается,
+// даже если у него был другой тип, а тип действующего триггера не меняется - это ошибка.
+func writeTriggerState(tx *sql.Tx, s *triggerState) (int64, error) {
+	query := `
+		INSERT INTO bushlatinga_bot.bushlatinga_responses
+			(trigger_text, match_mode, trigger_type, priority, chat_ids, cooldown_seconds, probability, schedule, enabled, fuzzy, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (trigger_text, chat_ids) DO UPDATE SET
+			match_mode = EXCLUDED.match_mode,
+			trigger_type = EXCLUDED.trigger_type,
+			priority = EXCLUDED.priority,
+			cooldown_seconds = EXCLUDED.cooldown_seconds,
+			probability = EXCLUDED.probability,
+			schedule = EXCLUDED.schedule,
+			enabled = EXCLUDED.enabled,
+			fuzzy = EXCLUDED.fuzzy,
+			expires_at = EXCLUDED.expires_at,
+			deleted_at = NULL,
+			deleted_by = NULL,
+			updated_at = NOW()
+		WHERE bushlatinga_responses.trigger_type = EXCLUDED.trigger_type
+		   OR bushlatinga_responses.deleted_at IS NOT NULL
+		RETURNING id
+	`
+
+	var expiresAt sql.NullTime
+	if s.ExpiresAt != nil {
+		expiresAt = sql.NullTime{Time: *s.ExpiresAt, Valid: true}
+	}
+
+	var id int64
+	err := tx.QueryRow(query, s.Text, s.MatchMode, s.Type, s.Priority, Scope(s.ChatIDs).array(), s.CooldownSeconds,
+		s.Probability, s.Schedule, s.Enabled, s.Fuzzy, expiresAt).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, errTypeChanged(s.Text, s.Type)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("ошибка записи триггера: %v", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM bushlatinga_bot.trigger_responses WHERE trigger_id = $1", id); err != nil {
+		return 0, fmt.Errorf("ошибка удаления старых ответов: %v", err)
+	}
+	for _, r := range s.Responses {
+		responseType, fileID, text, partsJSON, err := encodeResponse(r.Parts)
+		if err != nil {
+			return 0, err
+		}
+		partsArg := sql.NullString{String: string(partsJSON), Valid: partsJSON != nil}
+		_, err = tx.Exec(`
+			INSERT INTO bushlatinga_bot.trigger_responses (trigger_id, response_type, file_id, response_text, parts, weight)
+			VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)
+		`, id, responseType, fileID, text, partsArg, r.Weight)
+		if err != nil {
+			return 0, fmt.Errorf("ошибка записи ответа: %v", err)
+		}
+	}
+	return id, nil
+}
+
+// describe кратко описывает состояние триггера для /admin history
+func (s *triggerState) describe() string {
+	if s == nil {
+		return "удален"
+	}
+
+	var responses []string
+	for _, r := range s.Responses {
+		responses = append(responses, codeSafe((&TriggerResponse{Parts: r.Parts}).Describe()))
+	}
+	description := fmt.Sprintf("%s, ответов %d: %s", s.MatchMode, len(s.Responses), strings.Join(responses, " | "))
+	if !s.Enabled {
+		description = "выключен, " + description
+	}
+	if s.ExpiresAt != nil {
+		description = "до " + s.ExpiresAt.Format("2006-01-02 15:04") + " UTC, " + description
+	}
+	if runes := []rune(description); len(runes) > 120 {
+		description = string(runes[:120]) + "…"
+	}
+	return description
+}
+
+// auditedChange выполняет change над триггером id внутри транзакции и записывает в журнал
+// состояния до и после. Действие определяется по ним: создание, изменение или удаление.
+func auditedChange(tx *sql.Tx, by Actor, id int64, change func() error) error {
+	before, err := loadTriggerState(tx, id)
+	if err != nil {
+		return err
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	after, err := loadTriggerState(tx, id)
+	if err != nil {
+		return err
+	}
+
+	action := AuditUpdate
+	switch {
+	case before == nil:
+		action = AuditAdd
+	case after == nil:
+		action = AuditRemove
+	}
+	return recordChange(tx, by, action, before, after)
+}
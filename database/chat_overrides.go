@@ -0,0 +1,102 @@
+package database
+
+import (
+	"fmt"
+	"log"
+)
+
+// loadDisabledTriggers читает триггеры, отключенные в отдельных чатах: чат -> ID триггеров
+func (h *BotDatabaseHandler) loadDisabledTriggers() (map[int64]map[int64]bool, error) {
+	rows, err := h.db.Query("SELECT chat_id, trigger_id FROM bushlatinga_bot.chat_disabled_triggers")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки отключенных триггеров: %v", err)
+	}
+	defer rows.Close()
+
+	disabled := make(map[int64]map[int64]bool)
+	for rows.Next() {
+		var chatID, triggerID int64
+		if err := rows.Scan(&chatID, &triggerID); err != nil {
+			return nil, fmt.Errorf("ошибка чтения отключенного триггера: %v", err)
+		}
+		if disabled[chatID] == nil {
+			disabled[chatID] = make(map[int64]bool)
+		}
+		disabled[chatID][triggerID] = true
+	}
+	return disabled, rows.Err()
+}
+
+// DisableTriggerInChat отключает в чате все триггеры с ключом, которые в нем действуют.
+// Сами триггеры не меняются и продолжают работать в остальных чатах.
+func (h *BotDatabaseHandler) DisableTriggerInChat(chatID int64, key string) error {
+	query := `
+		INSERT INTO bushlatinga_bot.chat_disabled_triggers (chat_id, trigger_id)
+		SELECT $1, id FROM bushlatinga_bot.bushlatinga_responses
+		WHERE ` + triggerKeyCondition("$2") + `
+		  AND (chat_ids = '{}' OR $1 = ANY(chat_ids)) AND deleted_at IS NULL
+		ON CONFLICT DO NOTHING
+	`
+
+	res, err := h.db.Exec(query, chatID, key)
+	if err != nil {
+		return fmt.Errorf("ошибка отключения триггера: %v", err)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return fmt.Errorf("ключ '%s' не найден среди действующих в чате или уже отключен", key)
+	}
+
+	log.Printf("✅ [bushlatinga_bot] Чат %d: отключен триггер '%s'", chatID, key)
+	h.notifyIndexChanged(fmt.Sprintf("chat-off:%d:%s", chatID, key))
+	return nil
+}
+
+// EnableTriggerInChat снимает отключение триггеров с ключом в чате
+func (h *BotDatabaseHandler) EnableTriggerInChat(chatID int64, key string) error {
+	query := `
+		DELETE FROM bushlatinga_bot.chat_disabled_triggers
+		WHERE chat_id = $1 AND trigger_id IN (
+			SELECT id FROM bushlatinga_bot.bushlatinga_responses
+			WHERE ` + triggerKeyCondition("$2") + `
+		)
+	`
+
+	res, err := h.db.Exec(query, chatID, key)
+	if err != nil {
+		return fmt.Errorf("ошибка включения триггера: %v", err)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return fmt.Errorf("ключ '%s' не был отключен в этом чате", key)
+	}
+
+	log.Printf("✅ [bushlatinga_bot] Чат %d: включен триггер '%s'", chatID, key)
+	h.notifyIndexChanged(fmt.Sprintf("chat-on:%d:%s", chatID, key))
+	return nil
+}
+
+// ListDisabledTriggers возвращает ключи триггеров, отключенных в чате
+func (h *BotDatabaseHandler) ListDisabledTriggers(chatID int64) ([]string, error) {
+	query := `
+		SELECT r.trigger_text
+		FROM bushlatinga_bot.chat_disabled_triggers d
+		JOIN bushlatinga_bot.bushlatinga_responses r ON r.id = d.trigger_id
+		WHERE d.chat_id = $1 AND r.deleted_at IS NULL
+		ORDER BY r.trigger_text
+	`
+
+	rows, err := h.db.Query(query, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки отключенных триггеров: %v", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("ошибка чтения отключенного триггера: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
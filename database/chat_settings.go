@@ -0,0 +1,243 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Часовой пояс чатов по умолчанию
+const defaultChatTimezone = "Europe/Moscow"
+
+// defaultLocation - пояс defaultChatTimezone, загруженный один раз; без базы поясов - UTC
+var defaultLocation = func() *time.Location {
+	if location, err := time.LoadLocation(defaultChatTimezone); err == nil {
+		return location
+	}
+	return time.UTC
+}()
+
+// Как бот отправляет ответы в чат (колонка reply_mode)
+const (
+	ReplyModePlain = "plain" // обычным сообщением (исходное поведение)
+	ReplyModeReply = "reply" // ответом на сообщение с триггером
+)
+
+// ParseReplyMode приводит введенный админом режим ответа к значению для БД
+func ParseReplyMode(mode string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case ReplyModePlain, "обычно", "off":
+		return ReplyModePlain, nil
+	case ReplyModeReply, "ответом", "on":
+		return ReplyModeReply, nil
+	default:
+		return "", fmt.Errorf("неизвестный режим ответа '%s' (доступно: reply, plain)", mode)
+	}
+}
+
+// ChatSettings - настройки бота для конкретного чата (таблица chat_settings).
+// Для чатов без записи используются значения defaultChatSettings.
+type ChatSettings struct {
+	ChatID           int64
+	MultiMatchPolicy string // что делать, если в сообщении несколько триггеров
+	MaxReplies       int    // лимит ответов для политики all
+	Timezone         string // IANA-имя часового пояса для шаблонов и расписаний
+	ReplyMode        string // отвечать цитатой на сообщение или обычным сообщением
+	EditPolicy       string // как реагировать на отредактированные сообщения
+	ChatAdminsEdit   bool   // админы чата в Telegram - редакторы его триггеров
+
+	// Ограничения частоты ответов, 0 = без ограничения
+	ChatCooldown time.Duration // между любыми ответами в чате
+	UserCooldown time.Duration // между ответами одному и тому же участнику
+	BudgetCount  int           // не больше BudgetCount ответов...
+	BudgetWindow time.Duration // ...за это окно
+
+	location *time.Location // загруженный Timezone, чтобы не искать пояс на каждое сообщение
+}
+
+// defaultChatSettings возвращает настройки чата по умолчанию
+func defaultChatSettings(chatID int64) ChatSettings {
+	return ChatSettings{
+		ChatID:           chatID,
+		MultiMatchPolicy: PolicySilent,
+		MaxReplies:       defaultMaxReplies,
+		Timezone:         defaultChatTimezone,
+		ReplyMode:        ReplyModePlain,
+		EditPolicy:       EditIgnore,
+		location:         defaultLocation,
+	}
+}
+
+// reloadChatSettings перечитывает настройки всех чатов в память
+func (h *BotDatabaseHandler) reloadChatSettings() error {
+	query := `
+		SELECT chat_id, multi_match_policy, max_replies, timezone, reply_mode, edit_policy, chat_admins_edit,
+		       chat_cooldown_seconds, user_cooldown_seconds, budget_count, budget_window_seconds
+		FROM bushlatinga_bot.chat_settings
+	`
+
+	rows, err := h.db.Query(query)
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки настроек чатов: %v", err)
+	}
+	defer rows.Close()
+
+	settings := make(map[int64]ChatSettings)
+	for rows.Next() {
+		var s ChatSettings
+		var chatCooldown, userCooldown, budgetWindow int
+		if err := rows.Scan(&s.ChatID, &s.MultiMatchPolicy, &s.MaxReplies, &s.Timezone, &s.ReplyMode, &s.EditPolicy, &s.ChatAdminsEdit,
+			&chatCooldown, &userCooldown, &s.BudgetCount, &budgetWindow); err != nil {
+			return fmt.Errorf("ошибка чтения настроек чата: %v", err)
+		}
+		s.ChatCooldown = time.Duration(chatCooldown) * time.Second
+		s.UserCooldown = time.Duration(userCooldown) * time.Second
+		s.BudgetWindow = time.Duration(budgetWindow) * time.Second
+		s.location = loadChatLocation(s.ChatID, s.Timezone)
+		settings[s.ChatID] = s
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка чтения настроек чатов: %v", err)
+	}
+
+	h.mu.Lock()
+	h.chatSettings = settings
+	h.mu.Unlock()
+
+	log.Printf("✅ [bushlatinga_bot] Настройки чатов загружены: %d записей", len(settings))
+	return nil
+}
+
+// GetChatSettings возвращает настройки чата из памяти
+func (h *BotDatabaseHandler) GetChatSettings(chatID int64) ChatSettings {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if s, ok := h.chatSettings[chatID]; ok {
+		return s
+	}
+	return defaultChatSettings(chatID)
+}
+
+// SetMultiMatchPolicy меняет политику нескольких совпадений для чата
+func (h *BotDatabaseHandler) SetMultiMatchPolicy(chatID int64, policy string, maxReplies int) error {
+	policy, err := ParseMultiMatchPolicy(policy)
+	if err != nil {
+		return err
+	}
+	if maxReplies <= 0 {
+		maxReplies = defaultMaxReplies
+	}
+	if maxReplies > maxRepliesLimit {
+		return fmt.Errorf("не больше %d ответов на сообщение", maxRepliesLimit)
+	}
+
+	return h.saveChatSettings(chatID, map[string]interface{}{
+		"multi_match_policy": policy,
+		"max_replies":        maxReplies,
+	})
+}
+
+// SetChatTimezone меняет часовой пояс чата
+func (h *BotDatabaseHandler) SetChatTimezone(chatID int64, timezone string) error {
+	timezone = strings.TrimSpace(timezone)
+	if _, err := time.LoadLocation(timezone); err != nil || timezone == "" {
+		return fmt.Errorf("неизвестный часовой пояс '%s' (пример: Europe/Moscow, Asia/Yekaterinburg)", timezone)
+	}
+
+	return h.saveChatSettings(chatID, map[string]interface{}{"timezone": timezone})
+}
+
+// SetReplyMode меняет режим отправки ответов в чате
+func (h *BotDatabaseHandler) SetReplyMode(chatID int64, mode string) error {
+	mode, err := ParseReplyMode(mode)
+	if err != nil {
+		return err
+	}
+	return h.saveChatSettings(chatID, map[string]interface{}{"reply_mode": mode})
+}
+
+// SetChatCooldowns меняет паузы между ответами в чате: общую и для одного участника.
+// nil оставляет значение без изменений.
+func (h *BotDatabaseHandler) SetChatCooldowns(chatID int64, chatCooldown, userCooldown *time.Duration) error {
+	values := make(map[string]interface{})
+	if chatCooldown != nil {
+		values["chat_cooldown_seconds"] = int(chatCooldown.Seconds())
+	}
+	if userCooldown != nil {
+		values["user_cooldown_seconds"] = int(userCooldown.Seconds())
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return h.saveChatSettings(chatID, values)
+}
+
+// SetChatBudget ограничивает число ответов бота в чате за окно; count = 0 снимает ограничение
+func (h *BotDatabaseHandler) SetChatBudget(chatID int64, count int, window time.Duration) error {
+	if count < 0 {
+		return fmt.Errorf("лимит ответов не может быть отрицательным")
+	}
+	if count > 0 && window <= 0 {
+		return fmt.Errorf("укажите окно для лимита ответов, например 1h")
+	}
+	return h.saveChatSettings(chatID, map[string]interface{}{
+		"budget_count":          count,
+		"budget_window_seconds": int(window.Seconds()),
+	})
+}
+
+// chatLocation возвращает часовой пояс чата из настроек в памяти
+func (h *BotDatabaseHandler) chatLocation(chatID int64) *time.Location {
+	if location := h.GetChatSettings(chatID).location; location != nil {
+		return location
+	}
+	return defaultLocation
+}
+
+// loadChatLocation загружает часовой пояс чата при чтении настроек; при ошибке - пояс по умолчанию
+func loadChatLocation(chatID int64, timezone string) *time.Location {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		log.Printf("⚠️ Часовой пояс '%s' чата %d не загружен, используется %s: %v", timezone, chatID, defaultLocation, err)
+		return defaultLocation
+	}
+	return location
+}
+
+// saveChatSettings обновляет указанные колонки chat_settings, создавая запись при необходимости.
+// Имена колонок задаются только кодом, значения передаются параметрами.
+func (h *BotDatabaseHandler) saveChatSettings(chatID int64, values map[string]interface{}) error {
+	columns := make([]string, 0, len(values))
+	for column := range values {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	args := []interface{}{chatID}
+	placeholders := []string{"$1"}
+	var updates []string
+	for _, column := range columns {
+		args = append(args, values[column])
+		placeholder := fmt.Sprintf("$%d", len(args))
+		placeholders = append(placeholders, placeholder)
+		updates = append(updates, column+" = "+placeholder)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO bushlatinga_bot.chat_settings (chat_id, %s)
+		VALUES (%s)
+		ON CONFLICT (chat_id)
+		DO UPDATE SET %s, updated_at = NOW()
+	`, strings.Join(columns, ", "), strings.Join(placeholders, ", "), strings.Join(updates, ", "))
+
+	if _, err := h.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("ошибка сохранения настроек чата: %v", err)
+	}
+
+	log.Printf("✅ [bushlatinga_bot] Чат %d: обновлены настройки %s", chatID, strings.Join(columns, ", "))
+	h.notifyIndexChanged(fmt.Sprintf("chat:%d", chatID))
+	return nil
+}
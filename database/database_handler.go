@@ -5,25 +5,37 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
-	_ "github.com/lib/pq"
-)
-
-// ID стикера для "ЕБ"
-const (
-	EBStickerID = "CAACAgIAAxkBAANTaUVkrWrIsoO8kVNAifaUqz16ex4AAqqFAAJVF1hIHdoBVVf89Yg2BA"
+	"github.com/lib/pq"
 )
 
 // BotDatabaseHandler - основной обработчик для bushlatinga_bot
 type BotDatabaseHandler struct {
-	db      *sql.DB
-	mu      sync.RWMutex
-	adminID int64
-	cache   map[string]string // Оставим структуру для совместимости, но не будем использовать
+	db       *sql.DB
+	mu       sync.RWMutex
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	// Состояние в памяти, защищено mu
+	triggers     *triggerSnapshot           // Индекс триггеров
+	chatSettings map[int64]ChatSettings     // Настройки чатов
+	dialogs      map[dialogKey]time.Time    // Незавершенные диалоги /admin add и когда они истекают
+	roles        map[int64]map[int64]string // Роли: пользователь → чат (0 - все чаты) → роль
+
+	listener *pq.Listener // Подписка на изменения от других реплик
+
+	chatAdminChecker ChatAdminChecker // Проверка админов чата в Telegram, защищена mu
+	adminNotifier    AdminNotifier    // Уведомления админам в чат TeleLogger, защищено mu
+	botID            int64            // ID бота для счетчиков main.bot_stats, защищен mu
+
+	recentMu        sync.Mutex
+	recentResponses map[recentResponseKey]int64 // Последний ответ триггера в каждом чате
 }
 
-// NewBotDatabaseHandler создает новый обработчик БД для bushlatinga_bot
-func NewBotDatabaseHandler(adminID int64, connectionString string) (*BotDatabaseHandler, error) {
+// NewBotDatabaseHandler создает новый обработчик БД для bushlatinga_bot.
+// ownerID - первый владелец бота, если владельцев еще нет; 0 - не назначать.
+func NewBotDatabaseHandler(ownerID int64, connectionString string) (*BotDatabaseHandler, error) {
 	// Подключаемся к базе данных
 	db, err := sql.Open("postgres", connectionString)
 	if err != nil {
@@ -39,9 +51,11 @@ func NewBotDatabaseHandler(adminID int64, connectionString string) (*BotDatabase
 	log.Println("✅ [bushlatinga_bot] Успешное подключение к Supabase")
 
 	handler := &BotDatabaseHandler{
-		db:      db,
-		adminID: adminID,
-		cache:   make(map[string]string),
+		db:   db,
+		stop: make(chan struct{}),
+
+		dialogs:         make(map[dialogKey]time.Time),
+		recentResponses: make(map[recentResponseKey]int64),
 	}
 
 	// Инициализируем базу данных
@@ -50,18 +64,27 @@ func NewBotDatabaseHandler(adminID int64, connectionString string) (*BotDatabase
 		return nil, fmt.Errorf("ошибка инициализации БД: %v", err)
 	}
 
-	// Просто проверяем таблицу (не загружаем кэш)
-	err = handler.loadCache()
+	// Назначаем первого владельца, если его еще нет
+	if err := handler.bootstrapOwner(ownerID); err != nil {
+		return nil, err
+	}
+
+	// Загружаем индекс триггеров и настройки чатов в память
+	err = handler.reloadIndex()
 	if err != nil {
-		return nil, fmt.Errorf("ошибка проверки таблицы: %v", err)
+		return nil, fmt.Errorf("ошибка загрузки индекса триггеров: %v", err)
 	}
 
-	return handler, nil
-}
+	// Подписываемся на изменения от других реплик.
+	// Без подписки изменения с других реплик приходят только с периодической синхронизацией.
+	if err := handler.startTriggerListener(connectionString); err != nil {
+		log.Printf("⚠️ LISTEN/NOTIFY недоступен, индекс будет обновляться только по таймеру: %v", err)
+	}
+
+	// Периодическая синхронизация и очистка работают в любом случае
+	go handler.triggerSyncLoop(handler.listener)
 
-// GetEBStickerID возвращает ID стикера для "ЕБ"
-func (h *BotDatabaseHandler) GetEBStickerID() string {
-	return EBStickerID
+	return handler, nil
 }
 
 // DB возвращает указатель на соединение с БД (для использования в main.go)
@@ -69,13 +92,14 @@ func (h *BotDatabaseHandler) DB() *sql.DB {
 	return h.db
 }
 
-// IsAdmin проверяет, является ли пользователь администратором
-func (h *BotDatabaseHandler) IsAdmin(userID int64) bool {
-	return userID == h.adminID
-}
-
-// Close закрывает соединение с БД
+// Close останавливает фоновую синхронизацию и закрывает соединение с БД
 func (h *BotDatabaseHandler) Close() error {
+	h.stopOnce.Do(func() { close(h.stop) })
+
+	if h.listener != nil {
+		h.listener.Close()
+	}
+
 	if h.db != nil {
 		return h.db.Close()
 	}
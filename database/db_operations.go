@@ -4,178 +4,574 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"math/rand"
 	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
 )
 
-// CheckForNames проверяет наличие ровно одного имени в сообщении (ПРЯМО ИЗ БД!)
-func (h *BotDatabaseHandler) CheckForNames(text, userName string) (bool, string) {
-	messageText := strings.ToLower(text)
+// IncomingMessage - то, что нужно знать о входящем сообщении для поиска триггеров
+type IncomingMessage struct {
+	ChatID    int64
+	MessageID int
+	ChatTitle string
+	UserID    int64
+	UserName  string // @username без @
+	FirstName string
+	Text      string // текст сообщения или подпись к медиа
+
+	Sticker        *StickerInfo // nil, если сообщение не стикер
+	CustomEmojiIDs []string     // кастомные эмодзи из текста или подписи
+}
+
+// CheckForNames ищет имена в сообщении (по индексу в памяти) и возвращает ответы.
+// Все вхождения ищутся за один проход автомата; пересекающиеся вхождения
+// сводятся к непересекающимся, а если триггеров несколько, решает политика чата.
+func (h *BotDatabaseHandler) CheckForNames(msg IncomingMessage) []Reply {
+	snapshot := h.triggerSnapshot()
+	settings := h.GetChatSettings(msg.ChatID)
+	found, selected := h.findMatches(snapshot, settings, msg)
+	return h.respond(snapshot, settings, msg, found, selected)
+}
+
+// findMatches возвращает все найденные в сообщении вхождения активных триггеров
+// и те из них, на которые нужно ответить по политике чата
+func (h *BotDatabaseHandler) findMatches(snapshot *triggerSnapshot, settings ChatSettings, msg IncomingMessage) (found, selected []Match) {
+	text := msg.Text
+	runes := lowerRunes(text)
 
-	// ПРОВЕРЯЕМ "ЕБ" ОТДЕЛЬНО
-	if CheckForEB(text) {
-		// Возвращаем специальный маркер для стикера
-		return true, "STICKER:Еген борисыч ла-ла-ла-ла-ла-ла"
+	// Триггер участвует в поиске, если действует в этом чате и сейчас активен по расписанию
+	now := time.Now().In(h.chatLocation(msg.ChatID))
+	active := func(t *Trigger) bool {
+		return snapshot.activeIn(t, msg.ChatID) && t.Schedule.Contains(now) && !t.expired(now)
 	}
 
-	// Получаем все записи из БД
-	query := "SELECT trigger_text, response_text FROM bushlatinga_bot.bushlatinga_responses"
-	rows, err := h.db.Query(query)
-	if err != nil {
-		log.Printf("❌ Ошибка запроса к БД: %v", err)
-		return false, ""
+	// Отбрасываем вхождения неактивных триггеров и не подходящие под режим
+	// триггера (границы слова, окончания)
+	var accepted []Match
+	for _, match := range snapshot.matcher.FindAll(runes) {
+		trigger := snapshot.triggers[match.Pattern]
+		if !active(trigger) {
+			continue
+		}
+		if m, ok := trigger.accept(runes, match); ok {
+			accepted = append(accepted, m)
+		}
 	}
-	defer rows.Close()
 
-	var foundResponse string
-	foundCount := 0
+	// Нечеткие триггеры ищутся по нормализованному тексту, позиции переводятся обратно в исходный
+	if snapshot.fuzzy != nil {
+		folded := foldText(runes)
+		for _, match := range snapshot.fuzzy.FindAll(folded.runes) {
+			trigger := snapshot.triggers[match.Pattern]
+			if !active(trigger) {
+				continue
+			}
+			if m, ok := trigger.accept(folded.runes, match); ok {
+				accepted = append(accepted, folded.original(m))
+			}
+		}
 
-	// Проверяем каждую запись из БД
-	for rows.Next() {
-		var trigger, response string
-		if err := rows.Scan(&trigger, &response); err != nil {
-			log.Printf("❌ Ошибка чтения строки: %v", err)
-			continue
+		if snapshot.typos {
+			words := folded.words()
+			for i, trigger := range snapshot.triggers {
+				if trigger.Fuzzy > FuzzyNormal && active(trigger) {
+					accepted = append(accepted, trigger.findTypos(folded, words, i)...)
+				}
+			}
 		}
+	}
 
-		if strings.Contains(messageText, strings.ToLower(trigger)) {
-			foundCount++
+	// Регулярные выражения проверяются по исходному тексту
+	for i, trigger := range snapshot.triggers {
+		if trigger.re != nil && active(trigger) {
+			accepted = append(accepted, trigger.findRegex(text, i)...)
+		}
+	}
 
-			if foundCount == 1 {
-				// Сохраняем ответ первого найденного имени
-				foundResponse = response
-			} else {
-				// Нашли второе имя - выходим
-				rows.Close()
-				return false, ""
+	// Детекторы ищут точные слова с учетом регистра
+	var detected []Match
+	for i, trigger := range snapshot.triggers {
+		if trigger.Type == TriggerExact && active(trigger) {
+			detected = append(detected, trigger.findExact(text, i)...)
+		}
+	}
+
+	// Триггеры на стикеры и кастомные эмодзи сравниваются со свойствами сообщения;
+	// позиции в тексте у них нет, поэтому они не участвуют в выборе непересекающихся вхождений
+	var media []Match
+	if msg.Sticker != nil || len(msg.CustomEmojiIDs) > 0 {
+		for i, trigger := range snapshot.triggers {
+			if isMediaTrigger(trigger.Type) && active(trigger) && trigger.matchesMedia(msg) {
+				media = append(media, Match{Pattern: i})
 			}
 		}
 	}
 
-	// Отвечаем только если найдено ровно одно имя
-	if foundCount == 1 {
-		return true, foundResponse
+	found = append(append(append(found, accepted...), detected...), media...)
+	if m, ok := exclusiveDetection(snapshot.triggers, detected, append(accepted, media...)); ok {
+		// Детектор важнее всех найденных триггеров: отвечает только он (как раньше "ЕБ")
+		return found, []Match{m}
+	}
+	matches := append(selectNonOverlapping(append(accepted, detected...)), media...)
+	return found, applyMultiMatchPolicy(settings, snapshot.triggers, matches)
+}
+
+// respond готовит ответы на выбранные вхождения: бросает вероятность,
+// проверяет лимиты и выбирает вариант ответа. Каждое совпадение из found
+// записывается в статистику - с ответом или с причиной, по которой его не было.
+func (h *BotDatabaseHandler) respond(snapshot *triggerSnapshot, settings ChatSettings, msg IncomingMessage, found, selected []Match) []Reply {
+	var replies []Reply
+	var matches []matchRecord
+	for _, match := range selected {
+		trigger := snapshot.triggers[match.Pattern]
+		record := matchRecord{trigger: trigger, outcome: MatchSent}
+		switch {
+		// Бросок вероятности - до лимитов, чтобы пропущенный ответ не тратил кулдаун
+		case trigger.Chance < defaultProbability && rand.Intn(defaultProbability) >= trigger.Chance:
+			record.outcome = MatchChance
+		case !h.allowResponse(responseLimits(settings, trigger, msg.ChatID, msg.UserID)):
+			record.outcome = MatchLimited
+		default:
+			if response := h.pickResponse(msg.ChatID, trigger); response != nil {
+				data := h.templateData(msg, trigger, match, response.usesMember)
+				replies = append(replies, Reply{TriggerID: trigger.ID, Parts: response.render(data)})
+			} else {
+				record.outcome = MatchEmpty
+			}
+		}
+		matches = append(matches, record)
 	}
+	matches = append(matches, notSelected(snapshot, found, selected)...)
 
-	return false, ""
+	h.recordHits(replies)
+	h.recordMatches(msg, matches)
+	return replies
 }
 
-// AddMapping добавляет новую запись в маппинг
-func (h *BotDatabaseHandler) AddMapping(key, value string) error {
-	key = strings.ToLower(strings.TrimSpace(key))
+// TriggerOptions - дополнительные параметры триггера, задаваемые в /admin add.
+// Пустые значения при добавлении ответа к существующему триггеру его не меняют.
+type TriggerOptions struct {
+	MatchMode string         // режим сравнения, пустая строка = substring
+	Type      string         // тип триггера, пустая строка = text
+	Priority  *int           // приоритет для политики priority
+	Weight    int            // вес добавляемого ответа, 0 = 1
+	Scope     Scope          // в каких чатах действует триггер, пусто = глобальный
+	Cooldown  *time.Duration // кулдаун триггера в каждом чате
+	Chance    *int           // вероятность ответа в процентах
+	Schedule  *string        // расписание активности, "" = всегда
+	Fuzzy     *int           // нечеткое сравнение: FuzzyOff, FuzzyNormal или число опечаток
+	ExpiresAt *time.Time     // срок действия, нулевое время = бессрочно, nil = не менять
+}
 
-	if key == "" {
-		return fmt.Errorf("ключ не может быть пустым")
+// Максимальная длина trigger_text (VARCHAR(100) в БД)
+const maxTriggerLength = 100
+
+// AddMapping добавляет вариант ответа к триггеру, создавая триггер при необходимости
+func (h *BotDatabaseHandler) AddMapping(by Actor, key string, parts []ResponsePart, opts TriggerOptions) error {
+	return h.saveMapping(by, key, parts, opts, false)
+}
+
+// ReplaceMapping заменяет все варианты ответа триггера одним новым
+func (h *BotDatabaseHandler) ReplaceMapping(by Actor, key string, parts []ResponsePart, opts TriggerOptions) error {
+	return h.saveMapping(by, key, parts, opts, true)
+}
+
+// saveMapping создает или обновляет триггер и добавляет ему ответ в одной транзакции.
+// Состояние до и после записывается в журнал изменений.
+func (h *BotDatabaseHandler) saveMapping(by Actor, key string, parts []ResponsePart, opts TriggerOptions, replace bool) error {
+	if err := validateResponseParts(parts); err != nil {
+		return err
 	}
 
-	query := `
-        INSERT INTO bushlatinga_bot.bushlatinga_responses (trigger_text, response_text) 
-        VALUES ($1, $2)
-        ON CONFLICT (trigger_text) 
-        DO UPDATE SET response_text = $2, updated_at = NOW()
+	weight := opts.Weight
+	if weight == 0 {
+		weight = 1
+	}
+	if weight < 0 {
+		return fmt.Errorf("вес ответа должен быть положительным")
+	}
+
+	if opts.MatchMode != "" {
+		if _, err := ParseMatchMode(opts.MatchMode); err != nil {
+			return err
+		}
+	}
+
+	var schedule sql.NullString
+	if opts.Schedule != nil {
+		parsed, err := ParseSchedule(*opts.Schedule)
+		if err != nil {
+			return err
+		}
+		schedule = sql.NullString{String: parsed.String(), Valid: true}
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Тип берем из опций, а если их нет - у существующего триггера с таким ключом
+	triggerType := TriggerText
+	if opts.Type != "" {
+		if triggerType, err = ParseTriggerType(opts.Type); err != nil {
+			return err
+		}
+	} else if _, existingType, err := findTriggerByKey(tx, key, opts.Scope); err == nil {
+		triggerType = existingType
+	} else if err != sql.ErrNoRows {
+		return fmt.Errorf("ошибка поиска записи: %v", err)
+	}
+
+	key, groups, err := normalizeTriggerKey(triggerType, key)
+	if err != nil {
+		return err
+	}
+
+	if opts.Fuzzy != nil && *opts.Fuzzy != FuzzyOff && triggerType != TriggerText {
+		return fmt.Errorf("нечеткое сравнение доступно только для текстовых триггеров")
+	}
+
+	for _, p := range parts {
+		if err := validateResponseTemplate(p.Text, groups); err != nil {
+			return err
+		}
+	}
+
+	responseType, fileID, text, partsJSON, err := encodeResponse(parts)
+	if err != nil {
+		return err
+	}
+
+	var priority, cooldown, chance, fuzzy sql.NullInt64
+	if opts.Priority != nil {
+		priority = sql.NullInt64{Int64: int64(*opts.Priority), Valid: true}
+	}
+	if opts.Chance != nil {
+		chance = sql.NullInt64{Int64: int64(*opts.Chance), Valid: true}
+	}
+	if opts.Cooldown != nil {
+		cooldown = sql.NullInt64{Int64: int64(opts.Cooldown.Seconds()), Valid: true}
+	}
+	if opts.Fuzzy != nil {
+		fuzzy = sql.NullInt64{Int64: int64(*opts.Fuzzy), Valid: true}
+	}
+
+	upsertQuery := `
+        INSERT INTO bushlatinga_bot.bushlatinga_responses
+            (trigger_text, match_mode, trigger_type, priority, chat_ids, cooldown_seconds, probability, schedule, fuzzy, expires_at) 
+        VALUES ($1, COALESCE(NULLIF($2, ''), 'substring'), $3, COALESCE($4, 0), $5, COALESCE($6, 0),
+            COALESCE($7, 100), COALESCE($8, ''), COALESCE($9, -1), $11::timestamptz)
+        ON CONFLICT (trigger_text, chat_ids) 
+        DO UPDATE SET
+            match_mode = COALESCE(NULLIF($2, ''), bushlatinga_responses.match_mode),
+            priority = COALESCE($4, bushlatinga_responses.priority),
+            cooldown_seconds = COALESCE($6, bushlatinga_responses.cooldown_seconds),
+            probability = COALESCE($7, bushlatinga_responses.probability),
+            schedule = COALESCE($8, bushlatinga_responses.schedule),
+            fuzzy = COALESCE($9, bushlatinga_responses.fuzzy),
+            expires_at = CASE WHEN $10 THEN $11::timestamptz ELSE bushlatinga_responses.expires_at END,
+            updated_at = NOW()
+        WHERE bushlatinga_responses.trigger_type = EXCLUDED.trigger_type
         RETURNING id
     `
 
+	var expiresAt sql.NullTime
+	if opts.ExpiresAt != nil && !opts.ExpiresAt.IsZero() {
+		expiresAt = sql.NullTime{Time: *opts.ExpiresAt, Valid: true}
+	}
+
+	// Триггер с тем же ключом из корзины заменяется новым, а не возвращается со старыми ответами
+	purgeQuery := "DELETE FROM bushlatinga_bot.bushlatinga_responses WHERE trigger_text = $1 AND chat_ids = $2 AND deleted_at IS NOT NULL"
+	if _, err := tx.Exec(purgeQuery, key, opts.Scope.array()); err != nil {
+		return fmt.Errorf("ошибка очистки корзины: %v", err)
+	}
+
+	// Прежнее состояние для журнала: ON CONFLICT молча перезаписывает существующий триггер
+	existingID, err := findTriggerID(tx, key, opts.Scope)
+	if err != nil {
+		return fmt.Errorf("ошибка поиска записи: %v", err)
+	}
+	before, err := loadTriggerState(tx, existingID)
+	if err != nil {
+		return err
+	}
+	if before != nil && before.Type != triggerType {
+		return errTypeChanged(key, triggerType)
+	}
+
 	var id int64
-	err := h.db.QueryRow(query, key, value).Scan(&id)
+	err = tx.QueryRow(upsertQuery, key, opts.MatchMode, triggerType, priority, opts.Scope.array(), cooldown, chance, schedule, fuzzy,
+		opts.ExpiresAt != nil, expiresAt).Scan(&id)
+	if err == sql.ErrNoRows {
+		// Триггер с другим типом успели добавить после проверки выше
+		return errTypeChanged(key, triggerType)
+	}
 	if err != nil {
 		return fmt.Errorf("ошибка добавления записи: %v", err)
 	}
 
-	log.Printf("✅ [bushlatinga_bot] Добавлена запись: '%s' -> '%s' (ID: %d)\n", key, value, id)
+	if replace {
+		if _, err := tx.Exec("DELETE FROM bushlatinga_bot.trigger_responses WHERE trigger_id = $1", id); err != nil {
+			return fmt.Errorf("ошибка удаления старых ответов: %v", err)
+		}
+	}
+
+	insertQuery := `
+		INSERT INTO bushlatinga_bot.trigger_responses (trigger_id, response_type, file_id, response_text, parts, weight)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)
+	`
+	partsArg := sql.NullString{String: string(partsJSON), Valid: partsJSON != nil}
+	if _, err := tx.Exec(insertQuery, id, responseType, fileID, text, partsArg, weight); err != nil {
+		return fmt.Errorf("ошибка добавления ответа: %v", err)
+	}
+
+	after, err := loadTriggerState(tx, id)
+	if err != nil {
+		return err
+	}
+	action := AuditUpdate
+	if before == nil {
+		action = AuditAdd
+	}
+	if err := recordChange(tx, by, action, before, after); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка коммита транзакции: %v", err)
+	}
+
+	log.Printf("✅ [bushlatinga_bot] Добавлена запись: '%s' [%s, %s] -> %s '%s' (ID: %d, вес %d, замена %t)\n", key, triggerType, opts.Scope, responseType, text, id, weight, replace)
+	h.notifyIndexChanged("add:" + key)
 	return nil
 }
 
-// RemoveMapping удаляет запись из маппинга
-func (h *BotDatabaseHandler) RemoveMapping(key string) error {
-	key = strings.ToLower(strings.TrimSpace(key))
+// normalizeTriggerKey проверяет ключ триггера и приводит его к виду для БД.
+// Для регулярных выражений возвращает еще имена групп, доступные в шаблоне ответа.
+func normalizeTriggerKey(triggerType, key string) (string, []string, error) {
+	key = strings.TrimSpace(key)
+	var groups []string
+	switch triggerType {
+	case TriggerRegex:
+		// Регистр в выражении значим (\d и \D), поэтому не приводим к нижнему
+		re, err := compileTriggerRegex(key)
+		if err != nil {
+			return "", nil, err
+		}
+		groups = regexGroupNames(re)
+	case TriggerExact:
+		// Детекторы сравнивают с учетом регистра
+		words, err := parseExactWords(key)
+		if err != nil {
+			return "", nil, err
+		}
+		key = strings.Join(words, "|")
+	case TriggerSticker, TriggerStickerSet, TriggerEmoji, TriggerCustomEmoji:
+		var err error
+		if key, err = normalizeMediaKey(triggerType, key); err != nil {
+			return "", nil, err
+		}
+	default:
+		key = strings.ToLower(key)
+	}
 
-	query := "DELETE FROM bushlatinga_bot.bushlatinga_responses WHERE trigger_text = $1 RETURNING id"
+	if key == "" {
+		return "", nil, fmt.Errorf("ключ не может быть пустым")
+	}
+	if utf8.RuneCountInString(key) > maxTriggerLength {
+		return "", nil, fmt.Errorf("ключ длиннее %d символов", maxTriggerLength)
+	}
+	return key, groups, nil
+}
 
-	var id int64
-	err := h.db.QueryRow(query, key).Scan(&id)
+// RemoveMapping удаляет запись из маппинга в указанной области в корзину
+func (h *BotDatabaseHandler) RemoveMapping(by Actor, key string, scope Scope) error {
+	tx, err := h.db.Begin()
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("ключ '%s' не найден", key)
-		}
-		return fmt.Errorf("ошибка удаления записи: %v", err)
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	id, _, err := findTriggerByKey(tx, key, scope)
+	if err == sql.ErrNoRows {
+		return keyNotFound(key, scope)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка поиска записи: %v", err)
 	}
 
-	log.Printf("✅ [bushlatinga_bot] Удалена запись: '%s' (ID: %d)\n", key, id)
+	err = auditedChange(tx, by, id, func() error {
+		return trashTrigger(tx, by, id)
+	})
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка коммита транзакции: %v", err)
+	}
+
+	log.Printf("✅ [bushlatinga_bot] Запись удалена в корзину: '%s' [%s] (ID: %d)\n", key, scope, id)
+	h.notifyIndexChanged("remove:" + key)
 	return nil
 }
 
-// GetMapping возвращает все записи из БД
-func (h *BotDatabaseHandler) GetMapping() map[string]string {
-	query := "SELECT trigger_text, response_text FROM bushlatinga_bot.bushlatinga_responses"
-	rows, err := h.db.Query(query)
+// SetTriggerCooldown меняет кулдаун существующего триггера; 0 отключает его
+func (h *BotDatabaseHandler) SetTriggerCooldown(by Actor, key string, scope Scope, cooldown time.Duration) error {
+	return h.setTriggerColumn(by, key, scope, "cooldown_seconds", int(cooldown.Seconds()))
+}
+
+// SetTriggerEnabled включает или выключает триггер (и детектор) во всех чатах.
+// Включение снимает истекший срок действия, иначе триггер так и остался бы молчать.
+func (h *BotDatabaseHandler) SetTriggerEnabled(by Actor, key string, scope Scope, enabled bool) error {
+	if !enabled {
+		return h.setTriggerColumn(by, key, scope, "enabled", false)
+	}
+	return h.updateTrigger(by, key, scope, "enabled",
+		"enabled = TRUE, expires_at = CASE WHEN expires_at <= NOW() THEN NULL ELSE expires_at END", true)
+}
+
+// SetTriggerExpiry задает срок действия триггера; нулевое время делает его бессрочным
+func (h *BotDatabaseHandler) SetTriggerExpiry(by Actor, key string, scope Scope, expiresAt time.Time) error {
+	return h.setTriggerColumn(by, key, scope, "expires_at", sql.NullTime{Time: expiresAt, Valid: !expiresAt.IsZero()})
+}
+
+// SetTriggerChance меняет вероятность ответа триггера в процентах
+func (h *BotDatabaseHandler) SetTriggerChance(by Actor, key string, scope Scope, chance int) error {
+	if chance < minProbability || chance > defaultProbability {
+		return fmt.Errorf("вероятность должна быть от %d до %d процентов", minProbability, defaultProbability)
+	}
+	return h.setTriggerColumn(by, key, scope, "probability", chance)
+}
+
+// SetTriggerSchedule меняет расписание активности триггера; пустое расписание - всегда
+func (h *BotDatabaseHandler) SetTriggerSchedule(by Actor, key string, scope Scope, spec string) error {
+	schedule, err := ParseSchedule(spec)
 	if err != nil {
-		log.Printf("❌ Ошибка получения маппинга: %v", err)
-		return make(map[string]string)
+		return err
 	}
-	defer rows.Close()
+	return h.setTriggerColumn(by, key, scope, "schedule", schedule.String())
+}
 
-	result := make(map[string]string)
-	for rows.Next() {
-		var key, value string
-		if err := rows.Scan(&key, &value); err != nil {
-			continue
+// SetTriggerFuzzy меняет нечеткое сравнение текстового триггера
+func (h *BotDatabaseHandler) SetTriggerFuzzy(by Actor, key string, scope Scope, fuzzy int) error {
+	if fuzzy < FuzzyOff || fuzzy > maxFuzzyTypos {
+		return fmt.Errorf("допустимо не больше %d опечаток", maxFuzzyTypos)
+	}
+	if fuzzy != FuzzyOff {
+		_, triggerType, err := findTriggerByKey(h.db, key, scope)
+		if err == nil && triggerType != TriggerText {
+			return fmt.Errorf("нечеткое сравнение доступно только для текстовых триггеров")
 		}
-		result[key] = value
 	}
+	return h.setTriggerColumn(by, key, scope, "fuzzy", fuzzy)
+}
 
-	return result
+// setTriggerColumn меняет одну колонку триггера. Имя колонки задается только кодом.
+func (h *BotDatabaseHandler) setTriggerColumn(by Actor, key string, scope Scope, column string, value interface{}) error {
+	return h.updateTrigger(by, key, scope, column, column+" = $2", value)
 }
 
-// SearchInValues ищет текст в значениях маппинга
-func (h *BotDatabaseHandler) SearchInValues(searchText string) map[string]string {
-	query := "SELECT trigger_text, response_text FROM bushlatinga_bot.bushlatinga_responses WHERE LOWER(response_text) LIKE $1"
+// updateTrigger выполняет SET assignments над триггером, value передается как $2.
+// name - что меняется, для лога и уведомления реплик.
+func (h *BotDatabaseHandler) updateTrigger(by Actor, key string, scope Scope, name, assignments string, value interface{}) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
 
-	rows, err := h.db.Query(query, "%"+strings.ToLower(searchText)+"%")
+	id, _, err := findTriggerByKey(tx, key, scope)
+	if err == sql.ErrNoRows {
+		return keyNotFound(key, scope)
+	}
 	if err != nil {
-		log.Printf("❌ Ошибка поиска: %v", err)
-		return make(map[string]string)
+		return fmt.Errorf("ошибка поиска записи: %v", err)
 	}
-	defer rows.Close()
 
-	results := make(map[string]string)
-	for rows.Next() {
-		var key, value string
-		if err := rows.Scan(&key, &value); err != nil {
-			continue
+	err = auditedChange(tx, by, id, func() error {
+		query := "UPDATE bushlatinga_bot.bushlatinga_responses SET " + assignments + ", updated_at = NOW() WHERE id = $1"
+		if _, err := tx.Exec(query, id, value); err != nil {
+			return fmt.Errorf("ошибка обновления записи: %v", err)
 		}
-		results[key] = value
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка коммита транзакции: %v", err)
 	}
 
-	return results
+	log.Printf("✅ [bushlatinga_bot] '%s' [%s]: %s = %v\n", key, scope, name, value)
+	h.notifyIndexChanged(name + ":" + key)
+	return nil
 }
 
-// GetMappingCount возвращает количество записей в маппинге
-func (h *BotDatabaseHandler) GetMappingCount() int {
-	query := "SELECT COUNT(*) FROM bushlatinga_bot.bushlatinga_responses"
+// ListTriggers возвращает триггеры из БД, подходящие под фильтр, отсортированные по тексту
+func (h *BotDatabaseHandler) ListTriggers(filter TriggerFilter) ([]*Trigger, error) {
+	where, args := filter.sql(1)
+	return h.queryTriggers(where, "trigger_text, chat_ids", args...)
+}
 
-	var count int
-	err := h.db.QueryRow(query).Scan(&count)
+// ListTriggersPage возвращает страницу триггеров под фильтром и их общее количество
+func (h *BotDatabaseHandler) ListTriggersPage(filter TriggerFilter, offset, limit int) ([]*Trigger, int, error) {
+	where, args := filter.sql(1)
+
+	var total int
+	if err := h.db.QueryRow("SELECT COUNT(*) FROM bushlatinga_bot.bushlatinga_responses WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("ошибка подсчета триггеров: %v", err)
+	}
+
+	triggers, err := h.queryTriggers(where, fmt.Sprintf("trigger_text, chat_ids LIMIT %d OFFSET %d", limit, offset), args...)
 	if err != nil {
-		log.Printf("❌ Ошибка подсчета записей: %v", err)
-		return 0
+		return nil, 0, err
 	}
+	return triggers, total, nil
+}
 
-	return count
+// SearchTriggers ищет триггеры, у которых хотя бы один ответ содержит текст
+func (h *BotDatabaseHandler) SearchTriggers(searchText string, filter TriggerFilter) ([]*Trigger, error) {
+	filter.Search = searchText
+	return h.ListTriggers(filter)
 }
 
-// loadCache теперь только проверяет наличие таблицы
-func (h *BotDatabaseHandler) loadCache() error {
-	// Просто проверяем, что таблица существует
-	query := "SELECT COUNT(*) FROM bushlatinga_bot.bushlatinga_responses"
+// recordHits увеличивает счетчики срабатываний триггеров, на которые бот ответил
+func (h *BotDatabaseHandler) recordHits(replies []Reply) {
+	var ids pq.Int64Array
+	for _, r := range replies {
+		if r.TriggerID != 0 {
+			ids = append(ids, r.TriggerID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	query := `
+		UPDATE bushlatinga_bot.bushlatinga_responses
+		SET hit_count = hit_count + 1, last_hit_at = NOW()
+		WHERE id = ANY($1)
+	`
+	if _, err := h.db.Exec(query, ids); err != nil {
+		log.Printf("❌ Ошибка обновления счетчиков срабатываний: %v", err)
+	}
+}
+
+// GetMappingCount возвращает количество записей в маппинге
+func (h *BotDatabaseHandler) GetMappingCount() int {
+	query := "SELECT COUNT(*) FROM bushlatinga_bot.bushlatinga_responses WHERE deleted_at IS NULL"
 
 	var count int
 	err := h.db.QueryRow(query).Scan(&count)
 	if err != nil {
-		// Если таблицы нет, это нормально - она создастся при первой записи
-		log.Println("📝 Таблица bushlatinga_responses еще не содержит данных")
-		return nil
+		log.Printf("❌ Ошибка подсчета записей: %v", err)
+		return 0
 	}
 
-	log.Printf("✅ В таблице bushlatinga_responses найдено %d записей", count)
-	return nil
+	return count
 }
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Детектор - триггер типа exact: точное слово с учетом регистра, например "ЕБ|ЁБ".
+// Варианты слова перечисляются через "|". Детекторы хранятся вместе с обычными триггерами
+// и имеют такие же ответы, область, приоритет и флаг enabled.
+//
+// Если приоритет сработавшего детектора выше, чем у всех найденных обычных триггеров,
+// бот отвечает только детектором, не применяя политику нескольких совпадений.
+const TriggerExact = "exact"
+
+// Приоритет, который рекомендуется детекторам, чтобы они перебивали обычные триггеры
+const detectorPriority = 100
+
+// parseExactWords разбирает ключ детектора на варианты слова
+func parseExactWords(key string) ([]string, error) {
+	var words []string
+	for _, word := range strings.Split(key, "|") {
+		word = strings.TrimSpace(word)
+		if word == "" {
+			return nil, fmt.Errorf("пустой вариант слова в детекторе '%s'", key)
+		}
+		for _, r := range word {
+			if isWordSeparator(r) {
+				return nil, fmt.Errorf("детектор ищет отдельные слова, '%s' содержит разделитель", word)
+			}
+		}
+		words = append(words, word)
+	}
+	return words, nil
+}
+
+// findExact ищет слова сообщения, в точности совпадающие с одним из вариантов детектора
+func (t *Trigger) findExact(text string, pattern int) []Match {
+	if len(t.words) == 0 {
+		return nil
+	}
+
+	var matches []Match
+	runes := []rune(text)
+	for start := 0; start < len(runes); {
+		if isWordSeparator(runes[start]) {
+			start++
+			continue
+		}
+		end := start
+		for end < len(runes) && !isWordSeparator(runes[end]) {
+			end++
+		}
+
+		word := string(runes[start:end])
+		for _, w := range t.words {
+			if word == w {
+				matches = append(matches, Match{Pattern: pattern, Start: start, End: end})
+				break
+			}
+		}
+		start = end
+	}
+	return matches
+}
+
+// exclusiveDetection возвращает вхождение детектора с наибольшим приоритетом,
+// если он важнее всех найденных обычных триггеров
+func exclusiveDetection(triggers []*Trigger, detected, accepted []Match) (Match, bool) {
+	if len(detected) == 0 {
+		return Match{}, false
+	}
+
+	best := detected[0]
+	for _, m := range detected[1:] {
+		if triggers[m.Pattern].Priority > triggers[best.Pattern].Priority {
+			best = m
+		}
+	}
+
+	for _, m := range accepted {
+		if triggers[m.Pattern].Priority >= triggers[best.Pattern].Priority {
+			return Match{}, false
+		}
+	}
+	return best, true
+}
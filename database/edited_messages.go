@@ -0,0 +1,169 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Что делать, когда участник редактирует сообщение (колонка chat_settings.edit_policy)
+const (
+	EditIgnore  = "ignore"  // только записать новую версию
+	EditRespond = "respond" // ответить на триггеры, появившиеся после правки
+	EditSync    = "sync"    // ответить на новые и удалить ответы на исчезнувшие
+)
+
+// Сколько хранить связь сообщения с ответами бота: позже Telegram не дает удалить сообщение бота
+const sentResponsesRetention = 48 * time.Hour
+
+// ParseEditPolicy приводит введенную админом политику правок к значению для БД
+func ParseEditPolicy(policy string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(policy)) {
+	case EditIgnore, "off", "игнор":
+		return EditIgnore, nil
+	case EditRespond, "ответ":
+		return EditRespond, nil
+	case EditSync, "синхронно":
+		return EditSync, nil
+	default:
+		return "", fmt.Errorf("неизвестная политика правок '%s' (доступно: ignore, respond, sync)", policy)
+	}
+}
+
+// SetEditPolicy меняет политику обработки отредактированных сообщений в чате
+func (h *BotDatabaseHandler) SetEditPolicy(chatID int64, policy string) error {
+	policy, err := ParseEditPolicy(policy)
+	if err != nil {
+		return err
+	}
+	return h.saveChatSettings(chatID, map[string]interface{}{"edit_policy": policy})
+}
+
+// LastMessageText возвращает последнюю известную версию текста сообщения:
+// последнюю правку из main.message_revisions или исходный текст из main.messages_log
+func (h *BotDatabaseHandler) LastMessageText(chatID int64, messageID int) string {
+	query := `
+		SELECT COALESCE(message_text, '') FROM (
+			SELECT message_text, revision FROM main.message_revisions
+			WHERE chat_id = $1 AND message_id = $2
+			UNION ALL
+			SELECT message_text, 0 FROM main.messages_log
+			WHERE chat_id = $1 AND message_id = $2
+		) versions
+		ORDER BY revision DESC
+		LIMIT 1
+	`
+
+	var text string
+	if err := h.db.QueryRow(query, chatID, messageID).Scan(&text); err != nil {
+		return ""
+	}
+	return text
+}
+
+// CheckEditedMessage решает, как ответить на отредактированное сообщение.
+// Отвечает только на триггеры, которых не было в предыдущей версии текста;
+// при политике sync также возвращает ID ответов бота, чей триггер из сообщения пропал -
+// их нужно удалить из чата.
+func (h *BotDatabaseHandler) CheckEditedMessage(msg IncomingMessage, previousText string) ([]Reply, []int) {
+	settings := h.GetChatSettings(msg.ChatID)
+	if settings.EditPolicy == EditIgnore {
+		return nil, nil
+	}
+
+	snapshot := h.triggerSnapshot()
+	previous := msg
+	previous.Text = previousText
+	before, _ := h.findMatches(snapshot, settings, previous)
+	found, selected := h.findMatches(snapshot, settings, msg)
+
+	// Триггеры, которые были в сообщении и до правки, уже обработаны
+	had := snapshot.triggerIDs(before)
+	fresh := func(matches []Match) []Match {
+		var result []Match
+		for _, m := range matches {
+			if !had[snapshot.triggers[m.Pattern].ID] {
+				result = append(result, m)
+			}
+		}
+		return result
+	}
+	replies := h.respond(snapshot, settings, msg, fresh(found), fresh(selected))
+
+	var stale []int
+	if settings.EditPolicy == EditSync {
+		stale = h.forgetStaleResponses(msg.ChatID, msg.MessageID, snapshot.triggerIDs(found))
+	}
+	return replies, stale
+}
+
+// triggerIDs возвращает ID триггеров найденных вхождений
+func (s *triggerSnapshot) triggerIDs(matches []Match) map[int64]bool {
+	ids := make(map[int64]bool, len(matches))
+	for _, m := range matches {
+		ids[s.triggers[m.Pattern].ID] = true
+	}
+	return ids
+}
+
+// RememberResponse запоминает сообщения, которыми бот ответил на триггер,
+// чтобы при политике sync удалить их, если триггер уберут из сообщения правкой
+func (h *BotDatabaseHandler) RememberResponse(chatID int64, messageID int, triggerID int64, responseIDs []int) {
+	if triggerID == 0 || len(responseIDs) == 0 || h.GetChatSettings(chatID).EditPolicy != EditSync {
+		return
+	}
+
+	query := `
+		INSERT INTO bushlatinga_bot.sent_responses (chat_id, message_id, trigger_id, response_message_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+	`
+	for _, id := range responseIDs {
+		if _, err := h.db.Exec(query, chatID, messageID, triggerID, id); err != nil {
+			log.Printf("⚠️ Ошибка сохранения ответа %d: %v", id, err)
+		}
+	}
+}
+
+// forgetStaleResponses удаляет записи об ответах на сообщение, чьи триггеры в нем больше не найдены,
+// и возвращает ID этих ответов
+func (h *BotDatabaseHandler) forgetStaleResponses(chatID int64, messageID int, kept map[int64]bool) []int {
+	ids := make(pq.Int64Array, 0, len(kept))
+	for id := range kept {
+		ids = append(ids, id)
+	}
+
+	query := `
+		DELETE FROM bushlatinga_bot.sent_responses
+		WHERE chat_id = $1 AND message_id = $2 AND NOT (trigger_id = ANY($3))
+		RETURNING response_message_id
+	`
+	rows, err := h.db.Query(query, chatID, messageID, ids)
+	if err != nil {
+		log.Printf("⚠️ Ошибка поиска устаревших ответов: %v", err)
+		return nil
+	}
+	defer rows.Close()
+
+	var stale []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			log.Printf("⚠️ Ошибка чтения устаревшего ответа: %v", err)
+			return stale
+		}
+		stale = append(stale, id)
+	}
+	return stale
+}
+
+// cleanupSentResponses удаляет записи об ответах, которые уже нельзя удалить из чата
+func (h *BotDatabaseHandler) cleanupSentResponses() {
+	query := "DELETE FROM bushlatinga_bot.sent_responses WHERE created_at < NOW() - $1::float8 * INTERVAL '1 second'"
+	if _, err := h.db.Exec(query, sentResponsesRetention.Seconds()); err != nil {
+		log.Printf("⚠️ Ошибка очистки ответов: %v", err)
+	}
+}
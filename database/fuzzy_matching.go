@@ -0,0 +1,332 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Нечеткое сравнение текстового триггера (колонка fuzzy):
+// -1 - как есть, 0 - после нормализации текста, 1..2 - нормализация и до N опечаток в слове
+const (
+	FuzzyOff      = -1
+	FuzzyNormal   = 0
+	maxFuzzyTypos = 2
+)
+
+// ParseFuzzy разбирает значение опции fuzzy=: off, on или допустимое число опечаток
+func ParseFuzzy(value string) (int, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "off", "no", "выкл", "нет":
+		return FuzzyOff, nil
+	case "on", "yes", "вкл", "да", "0":
+		return FuzzyNormal, nil
+	}
+
+	typos, err := strconv.Atoi(value)
+	if err != nil || typos < 0 || typos > maxFuzzyTypos {
+		return 0, fmt.Errorf("некорректное значение fuzzy '%s' (доступно: off, on, 1, 2)", value)
+	}
+	return typos, nil
+}
+
+// formatFuzzy форматирует режим нечеткого сравнения для сообщений админу
+func formatFuzzy(fuzzy int) string {
+	switch {
+	case fuzzy < FuzzyNormal:
+		return "нет"
+	case fuzzy == FuzzyNormal:
+		return "нормализация"
+	default:
+		return fmt.Sprintf("нормализация, опечаток до %d", fuzzy)
+	}
+}
+
+// Латинские буквы, похожие на кириллические: "cлавик" с латинской c
+var latinHomoglyphs = map[rune]rune{
+	'a': 'а', 'b': 'в', 'c': 'с', 'e': 'е', 'h': 'н', 'k': 'к',
+	'm': 'м', 'o': 'о', 'p': 'р', 't': 'т', 'x': 'х', 'y': 'у',
+}
+
+// Транслитерация латиницы: сначала более длинные сочетания
+var translitDigraphs = []struct {
+	latin    string
+	cyrillic string
+}{
+	{"shch", "щ"}, {"sch", "щ"}, {"sh", "ш"}, {"ch", "ч"}, {"zh", "ж"}, {"kh", "х"},
+	{"ts", "ц"}, {"ck", "к"}, {"ya", "я"}, {"ja", "я"}, {"yu", "ю"}, {"ju", "ю"},
+	{"yo", "е"}, {"jo", "е"}, {"ye", "е"},
+}
+
+var translitLetters = map[rune]string{
+	'a': "а", 'b': "б", 'c': "к", 'd': "д", 'e': "е", 'f': "ф", 'g': "г", 'h': "х", 'i': "и",
+	'j': "й", 'k': "к", 'l': "л", 'm': "м", 'n': "н", 'o': "о", 'p': "п", 'q': "к", 'r': "р",
+	's': "с", 't': "т", 'u': "у", 'v': "в", 'w': "в", 'x': "кс", 'y': "ы", 'z': "з",
+}
+
+// isInnerSeparator - символ, которым разбивают слово: "сла-вик", "с.л.а.в.и.к".
+// Внутри слова такие символы выбрасываются.
+func isInnerSeparator(r rune) bool {
+	switch r {
+	case '-', '.', '_', '*', '\'', '`', '~', '·', '\u00ad', '\u200b', '\u200c', '\u200d', '\u2060', '\ufeff':
+		return true
+	}
+	return false
+}
+
+// foldedText - текст после нормализации и соответствие его рун исходным.
+// Руна runes[i] получена из исходных рун [starts[i], ends[i]).
+type foldedText struct {
+	runes  []rune
+	starts []int
+	ends   []int
+}
+
+// foldText нормализует текст в нижнем регистре перед нечетким поиском:
+// убирает разделители внутри слов, заменяет латинские двойники кириллицей
+// в словах со смешанным алфавитом, транслитерирует латинские слова,
+// приравнивает ё к е и схлопывает повторы букв ("славиииик" → "славик").
+func foldText(text []rune) *foldedText {
+	f := &foldedText{}
+	for pos := 0; pos < len(text); {
+		if !isFoldedWordRune(text[pos]) {
+			f.add(text[pos], pos, pos+1)
+			pos++
+			continue
+		}
+
+		end := wordEnd(text, pos)
+		f.addWord(text, pos, end)
+		pos = end
+	}
+	return f
+}
+
+// isFoldedWordRune - буква или цифра, из которых состоит слово при нормализации
+func isFoldedWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// wordEnd находит конец слова, начатого в pos; разделители допускаются только между буквами
+func wordEnd(text []rune, pos int) int {
+	end := pos
+	for end < len(text) {
+		if isFoldedWordRune(text[end]) {
+			end++
+			continue
+		}
+		next := end
+		for next < len(text) && isInnerSeparator(text[next]) {
+			next++
+		}
+		if next == end || next == len(text) || !isFoldedWordRune(text[next]) {
+			break
+		}
+		end = next
+	}
+	return end
+}
+
+// addWord нормализует одно слово text[start:end]
+func (f *foldedText) addWord(text []rune, start, end int) {
+	hasCyrillic, hasLatin := false, false
+	for _, r := range text[start:end] {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			hasCyrillic = true
+		case r >= 'a' && r <= 'z':
+			hasLatin = true
+		}
+	}
+
+	for pos := start; pos < end; {
+		r := text[pos]
+		switch {
+		case isInnerSeparator(r):
+			pos++
+			continue
+
+		case hasLatin && hasCyrillic && latinHomoglyphs[r] != 0:
+			// В смешанном слове латиница - скорее всего двойник кириллической буквы
+			f.add(latinHomoglyphs[r], pos, pos+1)
+			pos++
+			continue
+
+		case hasLatin && r >= 'a' && r <= 'z':
+			var prev rune
+			if pos > start {
+				prev = text[pos-1]
+			}
+			if n, cyrillic := transliterate(text[pos:end], prev); cyrillic != "" {
+				for _, c := range cyrillic {
+					f.add(c, pos, pos+n)
+				}
+				pos += n
+				continue
+			}
+		}
+
+		f.add(r, pos, pos+1)
+		pos++
+	}
+}
+
+// transliterate переводит начало латинского слова в кириллицу; prev - предыдущая руна слова.
+// Возвращает количество использованных рун и результат.
+func transliterate(word []rune, prev rune) (int, string) {
+	for _, d := range translitDigraphs {
+		n := len(d.latin)
+		if len(word) >= n && string(word[:n]) == d.latin {
+			return n, d.cyrillic
+		}
+	}
+
+	// y после гласной - это й: "nikolay", "andrey"
+	if word[0] == 'y' && strings.ContainsRune("aeiou", prev) {
+		return 1, "й"
+	}
+	return 1, translitLetters[word[0]]
+}
+
+// add добавляет руну результата, применяя ё→е и схлопывание повторов
+func (f *foldedText) add(r rune, start, end int) {
+	if r == 'ё' {
+		r = 'е'
+	}
+	if n := len(f.runes); n > 0 && f.runes[n-1] == r && unicode.IsLetter(r) {
+		f.ends[n-1] = end
+		return
+	}
+	f.runes = append(f.runes, r)
+	f.starts = append(f.starts, start)
+	f.ends = append(f.ends, end)
+}
+
+// original переводит позиции вхождения в нормализованном тексте в позиции исходного текста
+func (f *foldedText) original(m Match) Match {
+	m.Start, m.End = f.starts[m.Start], f.ends[m.End-1]
+	return m
+}
+
+// foldString нормализует строку целиком (для триггеров)
+func foldString(s string) []rune {
+	return foldText(lowerRunes(s)).runes
+}
+
+// words возвращает границы слов нормализованного текста
+func (f *foldedText) words() [][2]int {
+	var words [][2]int
+	for pos := 0; pos < len(f.runes); {
+		if !isFoldedWordRune(f.runes[pos]) {
+			pos++
+			continue
+		}
+		start := pos
+		for pos < len(f.runes) && isFoldedWordRune(f.runes[pos]) {
+			pos++
+		}
+		words = append(words, [2]int{start, pos})
+	}
+	return words
+}
+
+// splitFoldedWords делит нормализованный триггер на слова
+func splitFoldedWords(runes []rune) [][]rune {
+	var words [][]rune
+	for _, w := range (&foldedText{runes: runes}).words() {
+		words = append(words, runes[w[0]:w[1]])
+	}
+	return words
+}
+
+// fuzzyPattern возвращает шаблон триггера для автомата по нормализованному тексту
+func (t *Trigger) fuzzyPattern() []rune {
+	if t.Type != TriggerText || t.Fuzzy < FuzzyNormal {
+		return nil
+	}
+	if t.MatchMode == MatchStem {
+		return russianStem(t.folded)
+	}
+	return t.folded
+}
+
+// typoLimit - сколько опечаток допускается в слове длиной n: в коротких словах опечатки не ищутся,
+// иначе "оля" совпадала бы с "оль", "олег" и "коля"
+func typoLimit(maxTypos, n int) int {
+	return min(maxTypos, (n-1)/3)
+}
+
+// findTypos ищет последовательности слов, отличающиеся от триггера не больше чем на
+// допустимое число опечаток в каждом слове. Опечатки ищутся только по целым словам,
+// в режиме stem последнее слово сравнивается без падежного окончания.
+func (t *Trigger) findTypos(f *foldedText, words [][2]int, pattern int) []Match {
+	n := len(t.foldedWords)
+	if t.Fuzzy <= FuzzyNormal || n == 0 {
+		return nil
+	}
+
+	var matches []Match
+	for i := 0; i+n <= len(words); i++ {
+		similar := true
+		for k, patternWord := range t.foldedWords {
+			word := f.runes[words[i+k][0]:words[i+k][1]]
+			stem := t.MatchMode == MatchStem && k == n-1
+			if !similarWord(patternWord, word, typoLimit(t.Fuzzy, len(patternWord)), stem) {
+				similar = false
+				break
+			}
+		}
+		if similar {
+			matches = append(matches, f.original(Match{Pattern: pattern, Start: words[i][0], End: words[i+n-1][1]}))
+		}
+	}
+	return matches
+}
+
+// similarWord сравнивает слово триггера со словом сообщения с учетом опечаток
+func similarWord(pattern, word []rune, limit int, stem bool) bool {
+	if !stem {
+		return editDistanceWithin(pattern, word, limit)
+	}
+
+	pattern = russianStem(pattern)
+	for cut := len(word); cut >= 0 && len(word)-cut <= 3; cut-- {
+		if isRussianInflection(word[cut:]) && editDistanceWithin(pattern, word[:cut], limit) {
+			return true
+		}
+	}
+	return false
+}
+
+// editDistanceWithin сообщает, что расстояние Левенштейна между a и b не больше limit.
+// Считает построчно и прекращает, как только вся строка превысила limit.
+func editDistanceWithin(a, b []rune, limit int) bool {
+	if len(a)-len(b) > limit || len(b)-len(a) > limit {
+		return false
+	}
+
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return false
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)] <= limit
+}
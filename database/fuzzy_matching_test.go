@@ -0,0 +1,161 @@
+package database
+
+import "testing"
+
+func TestParseFuzzy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "off", want: FuzzyOff},
+		{value: "Нет", want: FuzzyOff},
+		{value: "ON", want: FuzzyNormal},
+		{value: " да ", want: FuzzyNormal},
+		{value: "0", want: FuzzyNormal},
+		{value: "1", want: 1},
+		{value: "2", want: 2},
+		{value: "3", wantErr: true},
+		{value: "-1", wantErr: true},
+		{value: "", wantErr: true},
+		{value: "много", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFuzzy(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFuzzy(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseFuzzy(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestFoldString(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"lower case", "Славик", "славик"},
+		{"repeated letters collapse", "Славиииик", "славик"},
+		{"hyphen inside word", "сла-вик", "славик"},
+		{"dots between letters", "с.л.а.в.и.к", "славик"},
+		{"zero width space inside word", "сла\u200bвик", "славик"},
+		{"trailing separator is kept", "слава-", "слава-"},
+		{"latin homoglyph in cyrillic word", "cлaвик", "славик"},
+		{"latin word is transliterated", "Slavik", "славик"},
+		{"digraphs", "shchuka", "щука"},
+		{"y after vowel", "nikolay", "николай"},
+		{"x becomes two letters", "xerox", "ксерокс"},
+		{"yo becomes ye", "Ёжик", "ежик"},
+		{"digits do not collapse", "1100", "1100"},
+		{"words and punctuation stay apart", "привет, мир!", "привет, мир!"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(foldString(tt.in)); got != tt.want {
+				t.Errorf("foldString(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFoldedTextOriginal(t *testing.T) {
+	tests := []struct {
+		text       string
+		match      Match // позиции в нормализованном тексте
+		start, end int   // ожидаемые позиции в исходном тексте
+	}{
+		{"славиииик", Match{Start: 0, End: 6}, 0, 9},
+		{"сла-вик", Match{Start: 3, End: 6}, 4, 7},
+		{"эй, slavik!", Match{Start: 4, End: 10}, 4, 10},
+		{"shchuka", Match{Start: 0, End: 1}, 0, 4},
+	}
+
+	for _, tt := range tests {
+		got := foldText(lowerRunes(tt.text)).original(tt.match)
+		if got.Start != tt.start || got.End != tt.end {
+			t.Errorf("original(%q, %d..%d) = %d..%d, want %d..%d",
+				tt.text, tt.match.Start, tt.match.End, got.Start, got.End, tt.start, tt.end)
+		}
+	}
+}
+
+func TestEditDistanceWithin(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  bool
+	}{
+		{"славик", "славик", 0, true},
+		{"славик", "славек", 0, false},
+		{"славик", "славек", 1, true},
+		{"славик", "славк", 1, true},
+		{"славик", "сславик", 1, true},
+		{"славик", "салвик", 1, false},
+		{"славик", "салвик", 2, true},
+		{"кот", "котенок", 2, false},
+		{"", "аб", 2, true},
+		{"", "аб", 1, false},
+		{"abc", "xyz", 2, false},
+	}
+
+	for _, tt := range tests {
+		if got := editDistanceWithin([]rune(tt.a), []rune(tt.b), tt.limit); got != tt.want {
+			t.Errorf("editDistanceWithin(%q, %q, %d) = %v, want %v", tt.a, tt.b, tt.limit, got, tt.want)
+		}
+		if got := editDistanceWithin([]rune(tt.b), []rune(tt.a), tt.limit); got != tt.want {
+			t.Errorf("editDistanceWithin(%q, %q, %d) = %v, want %v", tt.b, tt.a, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestTypoLimit(t *testing.T) {
+	tests := []struct {
+		maxTypos, n, want int
+	}{
+		{2, 1, 0},
+		{2, 3, 0},
+		{2, 4, 1},
+		{2, 6, 1},
+		{2, 7, 2},
+		{2, 20, 2},
+		{1, 20, 1},
+	}
+
+	for _, tt := range tests {
+		if got := typoLimit(tt.maxTypos, tt.n); got != tt.want {
+			t.Errorf("typoLimit(%d, %d) = %d, want %d", tt.maxTypos, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestSimilarWord(t *testing.T) {
+	tests := []struct {
+		pattern, word string
+		limit         int
+		stem          bool
+		want          bool
+	}{
+		{"славик", "славек", 1, false, true},
+		{"славик", "славику", 1, false, true},
+		{"славик", "славикович", 2, false, false},
+		{"славик", "славеку", 1, true, true},
+		{"маша", "машей", 0, true, true},
+		{"маша", "мошей", 1, true, true},
+		{"славик", "славикович", 2, true, false},
+		{"славик", "славиков", 0, true, true},
+		{"оля", "коля", 0, true, false},
+	}
+
+	for _, tt := range tests {
+		if got := similarWord([]rune(tt.pattern), []rune(tt.word), tt.limit, tt.stem); got != tt.want {
+			t.Errorf("similarWord(%q, %q, %d, stem=%v) = %v, want %v", tt.pattern, tt.word, tt.limit, tt.stem, got, tt.want)
+		}
+	}
+}
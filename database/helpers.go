@@ -1,4 +1,12 @@
 package database
 
 // Вспомогательные функции могут быть добавлены здесь
-// Пока файл оставляем пустым для будущих расширений
+
+// truncateRunes обрезает строку до maxRunes символов, не разрывая UTF-8 последовательности
+func truncateRunes(s string, maxRunes int) string {
+	runes := []rune(s)
+	if len(runes) <= maxRunes {
+		return s
+	}
+	return string(runes[:maxRunes])
+}
@@ -0,0 +1,208 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MatchExplanation - разбор того, как бот ответил бы на сообщение, для /admin test.
+// Строится по тому же снимку и тем же шагам, что и CheckForNames, но ничего не меняет:
+// лимиты не расходуются, последний ответ не запоминается, счетчики не растут.
+type MatchExplanation struct {
+	Policy   string
+	Found    []ExplainedMatch  // вхождения активных триггеров в порядке появления
+	Inactive []InactiveTrigger // триггеры, которые нашлись бы, но сейчас не действуют
+	Replies  []ExplainedReply  // триггеры, выбранные политикой чата
+	Note     string            // почему выбраны именно они
+}
+
+// ExplainedMatch - одно вхождение триггера в сообщение
+type ExplainedMatch struct {
+	Trigger  *Trigger
+	Fragment string // совпавший текст, пусто для стикеров и эмодзи
+	Start    int    // позиция в символах
+	Selected bool
+	Reason   string // почему не выбрано
+}
+
+// InactiveTrigger - триггер с вхождением в тексте, который не участвует в поиске
+type InactiveTrigger struct {
+	Trigger *Trigger
+	Reason  string
+}
+
+// ExplainedReply - что было бы с ответом на выбранный триггер
+type ExplainedReply struct {
+	Trigger *Trigger
+	Limits  []LimitState // состояние кулдаунов и бюджета
+	Sample  []ResponsePart
+	Options int // сколько вариантов ответа могло быть выбрано
+}
+
+// LimitState - состояние одного ограничения ответа
+type LimitState struct {
+	Name    string
+	Blocked bool
+	Left    time.Duration // сколько еще ждать, если Blocked
+}
+
+// ExplainMessage прогоняет сообщение через поиск триггеров и политику чата
+// и описывает каждый шаг, не отправляя ответ
+func (h *BotDatabaseHandler) ExplainMessage(msg IncomingMessage) *MatchExplanation {
+	snapshot := h.triggerSnapshot()
+	settings := h.GetChatSettings(msg.ChatID)
+	found, selected := h.findMatches(snapshot, settings, msg)
+
+	e := &MatchExplanation{Policy: settings.MultiMatchPolicy}
+	runes := []rune(msg.Text)
+
+	isSelected := make(map[int]bool)
+	for _, m := range selected {
+		isSelected[m.Pattern] = true
+	}
+
+	// Вхождения, которые перекрыты другими, отбрасываются еще до политики чата
+	var textMatches []Match
+	for _, m := range found {
+		if !isMediaTrigger(snapshot.triggers[m.Pattern].Type) {
+			textMatches = append(textMatches, m)
+		}
+	}
+	type span struct{ pattern, start, end int }
+	kept := make(map[span]bool)
+	for _, m := range selectNonOverlapping(textMatches) {
+		kept[span{m.Pattern, m.Start, m.End}] = true
+	}
+
+	detectorWins := len(selected) == 1 && snapshot.triggers[selected[0].Pattern].Type == TriggerExact &&
+		len(distinctByTrigger(found)) > 1
+
+	shown := make(map[int]bool)
+	for _, m := range found {
+		trigger := snapshot.triggers[m.Pattern]
+		if shown[m.Pattern] && isMediaTrigger(trigger.Type) {
+			continue
+		}
+		shown[m.Pattern] = true
+
+		em := ExplainedMatch{Trigger: trigger, Start: m.Start, Selected: isSelected[m.Pattern]}
+		if !isMediaTrigger(trigger.Type) && m.Start >= 0 && m.End <= len(runes) && m.Start < m.End {
+			em.Fragment = string(runes[m.Start:m.End])
+		}
+		switch {
+		case em.Selected:
+		case detectorWins:
+			em.Reason = "детектор с большим приоритетом отвечает вместо остальных"
+		case !isMediaTrigger(trigger.Type) && !kept[span{m.Pattern, m.Start, m.End}]:
+			em.Reason = "пересекается с более ранним или длинным вхождением"
+		default:
+			em.Reason = "не выбран политикой " + settings.MultiMatchPolicy
+		}
+		e.Found = append(e.Found, em)
+	}
+
+	switch {
+	case len(found) == 0:
+		e.Note = "совпадений нет"
+	case detectorWins:
+		e.Note = "детектор важнее найденных триггеров"
+	case len(selected) == 0 && settings.MultiMatchPolicy == PolicySilent:
+		e.Note = "найдено несколько разных триггеров, политика silent - молчать"
+	case len(selected) == 0:
+		e.Note = "ни одно вхождение не выбрано"
+	}
+
+	e.Inactive = h.inactiveMatches(snapshot, msg)
+
+	for _, m := range selected {
+		trigger := snapshot.triggers[m.Pattern]
+		reply := ExplainedReply{Trigger: trigger, Options: len(trigger.Responses)}
+		for _, limit := range responseLimits(settings, trigger, msg.ChatID, msg.UserID) {
+			reply.Limits = append(reply.Limits, h.limitState(limit))
+		}
+		if len(trigger.Responses) > 0 {
+			response := weightedChoice(trigger.Responses)
+			reply.Sample = response.render(h.templateData(msg, trigger, m, response.usesMember))
+		}
+		e.Replies = append(e.Replies, reply)
+	}
+	return e
+}
+
+// inactiveMatches находит триггеры, которые подошли бы по тексту, но сейчас не действуют в чате.
+// Проверяются только обычные вхождения подстроки - этого хватает, чтобы объяснить молчание бота.
+// Триггеры чатов, где у проверяющего (msg.UserID) нет роли, не показываются.
+func (h *BotDatabaseHandler) inactiveMatches(snapshot *triggerSnapshot, msg IncomingMessage) []InactiveTrigger {
+	now := time.Now().In(h.chatLocation(msg.ChatID))
+
+	var result []InactiveTrigger
+	seen := make(map[int]bool)
+	for _, m := range snapshot.matcher.FindAll(lowerRunes(msg.Text)) {
+		trigger := snapshot.triggers[m.Pattern]
+		if seen[m.Pattern] {
+			continue
+		}
+		seen[m.Pattern] = true
+		if !h.canViewScope(msg.UserID, msg.ChatID, trigger.ChatIDs) {
+			continue
+		}
+
+		reason := ""
+		switch {
+		case !trigger.Enabled:
+			reason = "выключен"
+		case !trigger.ChatIDs.Contains(msg.ChatID):
+			reason = "действует в других чатах: " + trigger.ChatIDs.String()
+		case snapshot.hidden[msg.ChatID][trigger.ID]:
+			reason = "отключен в этом чате или заменен триггером чата"
+		case trigger.expired(now):
+			reason = "срок действия истек " + trigger.ExpiresAt.In(now.Location()).Format("2006-01-02 15:04")
+		case !trigger.Schedule.Contains(now):
+			reason = "вне расписания: " + trigger.Schedule.String()
+		default:
+			continue
+		}
+		result = append(result, InactiveTrigger{Trigger: trigger, Reason: reason})
+	}
+	return result
+}
+
+// limitState читает состояние ограничения, не расходуя его
+func (h *BotDatabaseHandler) limitState(limit rateLimit) LimitState {
+	state := LimitState{Name: limit.describe()}
+
+	var hits int
+	var left float64
+	query := `
+		SELECT hits, EXTRACT(EPOCH FROM window_end - NOW())
+		FROM bushlatinga_bot.rate_limits
+		WHERE key = $1 AND window_end > NOW()
+	`
+	// Нет строки - окно не начато или истекло, ограничение свободно
+	if err := h.db.QueryRow(query, limit.key).Scan(&hits, &left); err != nil {
+		return state
+	}
+	if hits >= limit.limit {
+		state.Blocked = true
+		state.Left = time.Duration(left * float64(time.Second))
+	}
+	return state
+}
+
+// describe называет ограничение для админа по префиксу ключа
+func (l rateLimit) describe() string {
+	kind, _, _ := strings.Cut(l.key, ":")
+	switch kind {
+	case "trigger":
+		return "кулдаун триггера " + formatCooldown(l.window)
+	case "chat":
+		return "пауза чата " + formatCooldown(l.window)
+	case "user":
+		return "пауза участника " + formatCooldown(l.window)
+	case "budget":
+		return fmt.Sprintf("бюджет %d за %s", l.limit, formatCooldown(l.window))
+	default:
+		return l.key
+	}
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Режимы сравнения триггера с текстом сообщения (колонка match_mode)
+const (
+	MatchSubstring = "substring" // подстрока: "ян" найдется в "январь"
+	MatchWord      = "word"      // целое слово, разбиение на слова как у детекторов
+	MatchStem      = "stem"      // слово с учетом падежных окончаний: "славик" → "славику"
+)
+
+// ParseMatchMode приводит введенный админом режим к значению для БД
+func ParseMatchMode(mode string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", MatchSubstring, "подстрока":
+		return MatchSubstring, nil
+	case MatchWord, "слово":
+		return MatchWord, nil
+	case MatchStem, "основа", "склонение":
+		return MatchStem, nil
+	default:
+		return "", fmt.Errorf("неизвестный режим '%s' (доступно: substring, word, stem)", mode)
+	}
+}
+
+// matchPattern возвращает шаблон, который триггер добавляет в автомат
+func (t *Trigger) matchPattern() []rune {
+	if t.Type != TriggerText || t.Fuzzy >= FuzzyNormal {
+		// Регулярные выражения, детекторы и нечеткие триггеры проверяются отдельно
+		return nil
+	}
+	if t.MatchMode == MatchStem {
+		return russianStem(t.lower)
+	}
+	return t.lower
+}
+
+// accept проверяет найденное автоматом вхождение с учетом режима триггера.
+// Для режима stem вхождение расширяется до конца слова вместе с окончанием.
+func (t *Trigger) accept(text []rune, m Match) (Match, bool) {
+	switch t.MatchMode {
+	case MatchWord:
+		return m, isWordStart(text, m.Start) && isWordEnd(text, m.End)
+
+	case MatchStem:
+		if !isWordStart(text, m.Start) {
+			return m, false
+		}
+		end := m.End
+		for end < len(text) && !isWordSeparator(text[end]) {
+			end++
+		}
+		if !isRussianInflection(text[m.End:end]) {
+			return m, false
+		}
+		m.End = end
+		return m, true
+
+	default:
+		return m, true
+	}
+}
+
+// isWordStart - позиция pos находится в начале слова
+func isWordStart(text []rune, pos int) bool {
+	return pos == 0 || isWordSeparator(text[pos-1])
+}
+
+// isWordEnd - позиция pos находится сразу после конца слова
+func isWordEnd(text []rune, pos int) bool {
+	return pos == len(text) || isWordSeparator(text[pos])
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// Политики ответа, когда в сообщении найдено несколько разных триггеров
+const (
+	PolicySilent   = "silent"   // молчать (исходное поведение)
+	PolicyFirst    = "first"    // ответить на первый по тексту
+	PolicyPriority = "priority" // ответить на триггер с наибольшим приоритетом
+	PolicyAll      = "all"      // ответить на все, но не больше max_replies
+	PolicyRandom   = "random"   // ответить на случайный
+)
+
+// Ограничения для политики all
+const (
+	defaultMaxReplies = 3
+	maxRepliesLimit   = 10
+)
+
+// ParseMultiMatchPolicy приводит введенную админом политику к значению для БД
+func ParseMultiMatchPolicy(policy string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(policy)) {
+	case PolicySilent, "молчать":
+		return PolicySilent, nil
+	case PolicyFirst, "первый":
+		return PolicyFirst, nil
+	case PolicyPriority, "приоритет":
+		return PolicyPriority, nil
+	case PolicyAll, "все":
+		return PolicyAll, nil
+	case PolicyRandom, "случайный":
+		return PolicyRandom, nil
+	default:
+		return "", fmt.Errorf("неизвестная политика '%s' (доступно: silent, first, priority, all, random)", policy)
+	}
+}
+
+// distinctByTrigger оставляет по одному (первому) вхождению каждого триггера,
+// сохраняя порядок появления в тексте
+func distinctByTrigger(matches []Match) []Match {
+	seen := make(map[int]bool)
+	var result []Match
+	for _, m := range matches {
+		if !seen[m.Pattern] {
+			seen[m.Pattern] = true
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// applyMultiMatchPolicy выбирает, на какие вхождения отвечать.
+// matches - непересекающиеся вхождения в порядке появления в тексте.
+func applyMultiMatchPolicy(settings ChatSettings, triggers []*Trigger, matches []Match) []Match {
+	distinct := distinctByTrigger(matches)
+	if len(distinct) <= 1 {
+		return distinct
+	}
+
+	switch settings.MultiMatchPolicy {
+	case PolicyFirst:
+		return distinct[:1]
+
+	case PolicyPriority:
+		best := distinct[0]
+		for _, m := range distinct[1:] {
+			if triggers[m.Pattern].Priority > triggers[best.Pattern].Priority {
+				best = m
+			}
+		}
+		return []Match{best}
+
+	case PolicyAll:
+		limit := settings.MaxReplies
+		if limit <= 0 {
+			limit = defaultMaxReplies
+		}
+		if len(distinct) > limit {
+			distinct = distinct[:limit]
+		}
+		return distinct
+
+	case PolicyRandom:
+		return []Match{distinct[rand.Intn(len(distinct))]}
+
+	default:
+		// PolicySilent и неизвестные значения: несколько имен - не отвечаем
+		return nil
+	}
+}
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"sort"
+	"unicode"
+)
+
+// Matcher - автомат Ахо-Корасик над рунами.
+// Строится один раз из всех триггеров и находит все вхождения за один проход по тексту.
+// После построения не изменяется, поэтому безопасен для параллельного использования.
+type Matcher struct {
+	nodes    []acNode
+	patterns []int // длина каждого шаблона в рунах
+}
+
+// acNode - вершина бора
+type acNode struct {
+	next map[rune]int
+	fail int
+	out  []int // индексы шаблонов, заканчивающихся в этой вершине (включая суффиксные)
+}
+
+// Match - одно вхождение шаблона в текст, позиции в рунах: [Start, End)
+type Match struct {
+	Pattern  int
+	Start    int
+	End      int
+	Captures map[string]string // именованные группы, только для regex-триггеров
+}
+
+// NewMatcher строит автомат по списку шаблонов.
+// Индекс шаблона в срезе становится Match.Pattern; пустые шаблоны никогда не совпадают.
+func NewMatcher(patterns [][]rune) *Matcher {
+	m := &Matcher{
+		nodes:    []acNode{{next: map[rune]int{}}},
+		patterns: make([]int, len(patterns)),
+	}
+
+	// 1. Строим бор
+	for id, pattern := range patterns {
+		m.patterns[id] = len(pattern)
+		if len(pattern) == 0 {
+			continue
+		}
+
+		node := 0
+		for _, r := range pattern {
+			child, ok := m.nodes[node].next[r]
+			if !ok {
+				child = len(m.nodes)
+				m.nodes = append(m.nodes, acNode{next: map[rune]int{}})
+				m.nodes[node].next[r] = child
+			}
+			node = child
+		}
+		m.nodes[node].out = append(m.nodes[node].out, id)
+	}
+
+	// 2. Проставляем суффиксные ссылки обходом в ширину
+	queue := make([]int, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		for r, child := range m.nodes[node].next {
+			fail := m.nodes[node].fail
+			for fail != 0 {
+				if _, ok := m.nodes[fail].next[r]; ok {
+					break
+				}
+				fail = m.nodes[fail].fail
+			}
+			if target, ok := m.nodes[fail].next[r]; ok && target != child {
+				fail = target
+			} else {
+				fail = 0
+			}
+
+			m.nodes[child].fail = fail
+			m.nodes[child].out = append(m.nodes[child].out, m.nodes[fail].out...)
+			queue = append(queue, child)
+		}
+	}
+
+	return m
+}
+
+// FindAll возвращает все вхождения всех шаблонов в текст (в том числе пересекающиеся)
+func (m *Matcher) FindAll(text []rune) []Match {
+	if m == nil {
+		return nil
+	}
+
+	var matches []Match
+	node := 0
+	for i, r := range text {
+		for node != 0 {
+			if _, ok := m.nodes[node].next[r]; ok {
+				break
+			}
+			node = m.nodes[node].fail
+		}
+		if next, ok := m.nodes[node].next[r]; ok {
+			node = next
+		}
+
+		for _, id := range m.nodes[node].out {
+			matches = append(matches, Match{
+				Pattern: id,
+				Start:   i + 1 - m.patterns[id],
+				End:     i + 1,
+			})
+		}
+	}
+
+	return matches
+}
+
+// selectNonOverlapping оставляет непересекающиеся вхождения:
+// слева направо, при общем начале предпочитается самое длинное
+func selectNonOverlapping(matches []Match) []Match {
+	sorted := make([]Match, len(matches))
+	copy(sorted, matches)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Start != sorted[j].Start {
+			return sorted[i].Start < sorted[j].Start
+		}
+		return sorted[i].End > sorted[j].End
+	})
+
+	var result []Match
+	lastEnd := 0
+	for _, match := range sorted {
+		if match.Start >= lastEnd {
+			result = append(result, match)
+			lastEnd = match.End
+		}
+	}
+	return result
+}
+
+// lowerRunes переводит строку в нижний регистр по рунам,
+// сохраняя соответствие позиций исходному тексту
+func lowerRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
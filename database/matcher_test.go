@@ -0,0 +1,176 @@
+package database
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMatcherFindAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		text     string
+		want     []Match
+	}{
+		{
+			name:     "classic overlapping patterns",
+			patterns: []string{"he", "she", "his", "hers"},
+			text:     "ushers",
+			want:     []Match{{Pattern: 1, Start: 1, End: 4}, {Pattern: 0, Start: 2, End: 4}, {Pattern: 3, Start: 2, End: 6}},
+		},
+		{
+			name:     "cyrillic positions are in runes",
+			patterns: []string{"славик", "ян"},
+			text:     "привет, славик и ян",
+			want:     []Match{{Pattern: 0, Start: 8, End: 14}, {Pattern: 1, Start: 17, End: 19}},
+		},
+		{
+			name:     "pattern inside another pattern",
+			patterns: []string{"январь", "ян"},
+			text:     "январь",
+			want:     []Match{{Pattern: 1, Start: 0, End: 2}, {Pattern: 0, Start: 0, End: 6}},
+		},
+		{
+			name:     "repeated occurrences",
+			patterns: []string{"аа"},
+			text:     "аааа",
+			want:     []Match{{Pattern: 0, Start: 0, End: 2}, {Pattern: 0, Start: 1, End: 3}, {Pattern: 0, Start: 2, End: 4}},
+		},
+		{
+			name:     "duplicate patterns both match",
+			patterns: []string{"кот", "кот"},
+			text:     "кот",
+			want:     []Match{{Pattern: 0, Start: 0, End: 3}, {Pattern: 1, Start: 0, End: 3}},
+		},
+		{
+			name:     "failure links after partial match",
+			patterns: []string{"abcd", "bce"},
+			text:     "abce",
+			want:     []Match{{Pattern: 1, Start: 1, End: 4}},
+		},
+		{
+			name:     "empty pattern never matches",
+			patterns: []string{"", "б"},
+			text:     "аб",
+			want:     []Match{{Pattern: 1, Start: 1, End: 2}},
+		},
+		{
+			name:     "no match",
+			patterns: []string{"кот"},
+			text:     "собака",
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patterns := make([][]rune, len(tt.patterns))
+			for i, p := range tt.patterns {
+				patterns[i] = []rune(p)
+			}
+
+			got := NewMatcher(patterns).FindAll([]rune(tt.text))
+			sortMatches(got)
+			sortMatches(tt.want)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindAll(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatcherFindAllNil(t *testing.T) {
+	var m *Matcher
+	if got := m.FindAll([]rune("текст")); got != nil {
+		t.Errorf("nil matcher FindAll = %v, want nil", got)
+	}
+}
+
+func TestSelectNonOverlapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		matches []Match
+		want    []Match
+	}{
+		{
+			name:    "empty",
+			matches: nil,
+			want:    nil,
+		},
+		{
+			name:    "disjoint matches are kept in text order",
+			matches: []Match{{Pattern: 1, Start: 5, End: 7}, {Pattern: 0, Start: 0, End: 2}},
+			want:    []Match{{Pattern: 0, Start: 0, End: 2}, {Pattern: 1, Start: 5, End: 7}},
+		},
+		{
+			name:    "longest wins at the same start",
+			matches: []Match{{Pattern: 0, Start: 0, End: 2}, {Pattern: 1, Start: 0, End: 6}},
+			want:    []Match{{Pattern: 1, Start: 0, End: 6}},
+		},
+		{
+			name:    "leftmost wins over a longer overlapping match",
+			matches: []Match{{Pattern: 0, Start: 2, End: 10}, {Pattern: 1, Start: 1, End: 4}},
+			want:    []Match{{Pattern: 1, Start: 1, End: 4}},
+		},
+		{
+			name:    "adjacent matches do not overlap",
+			matches: []Match{{Pattern: 0, Start: 0, End: 3}, {Pattern: 1, Start: 3, End: 5}},
+			want:    []Match{{Pattern: 0, Start: 0, End: 3}, {Pattern: 1, Start: 3, End: 5}},
+		},
+		{
+			name:    "nested match is dropped",
+			matches: []Match{{Pattern: 0, Start: 0, End: 8}, {Pattern: 1, Start: 2, End: 4}, {Pattern: 2, Start: 8, End: 9}},
+			want:    []Match{{Pattern: 0, Start: 0, End: 8}, {Pattern: 2, Start: 8, End: 9}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := append([]Match(nil), tt.matches...)
+			got := selectNonOverlapping(tt.matches)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectNonOverlapping(%v) = %v, want %v", tt.matches, got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.matches, input) {
+				t.Errorf("selectNonOverlapping modified its input: %v", tt.matches)
+			}
+		})
+	}
+}
+
+func TestLowerRunes(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Славик", "славик"},
+		{"ЁЛКА", "ёлка"},
+		{"Hello, МИР!", "hello, мир!"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		got := lowerRunes(tt.in)
+		if string(got) != tt.want {
+			t.Errorf("lowerRunes(%q) = %q, want %q", tt.in, string(got), tt.want)
+		}
+		if len(got) != len([]rune(tt.in)) {
+			t.Errorf("lowerRunes(%q) changed length: %d runes, want %d", tt.in, len(got), len([]rune(tt.in)))
+		}
+	}
+}
+
+// sortMatches упорядочивает вхождения, чтобы сравнение не зависело от порядка обхода автомата
+func sortMatches(matches []Match) {
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.End != b.End {
+			return a.End < b.End
+		}
+		return a.Pattern < b.Pattern
+	})
+}
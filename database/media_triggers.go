@@ -0,0 +1,116 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Типы триггеров на медиа: сравниваются не с текстом, а со свойствами сообщения
+const (
+	TriggerSticker     = "sticker"      // конкретный стикер по file_unique_id
+	TriggerStickerSet  = "sticker_set"  // любой стикер из набора
+	TriggerEmoji       = "emoji"        // стикер с этим эмодзи
+	TriggerCustomEmoji = "custom_emoji" // кастомный эмодзи в тексте или эмодзи-стикер
+)
+
+// StickerInfo - свойства стикера из входящего сообщения
+type StickerInfo struct {
+	FileUniqueID  string // постоянный ID, одинаковый для всех ботов (в отличие от file_id)
+	SetName       string
+	Emoji         string
+	CustomEmojiID string // только у стикеров кастомных эмодзи
+}
+
+// isMediaTrigger - триггер сравнивается со стикером или кастомными эмодзи сообщения
+func isMediaTrigger(triggerType string) bool {
+	switch triggerType {
+	case TriggerSticker, TriggerStickerSet, TriggerEmoji, TriggerCustomEmoji:
+		return true
+	default:
+		return false
+	}
+}
+
+// normalizeEmoji убирает селектор варианта (U+FE0F): "❤️" и "❤" считаются одним эмодзи
+func normalizeEmoji(emoji string) string {
+	return strings.ReplaceAll(strings.TrimSpace(emoji), "️", "")
+}
+
+// normalizeMediaKey проверяет и приводит ключ медиа-триггера к виду для БД
+func normalizeMediaKey(triggerType, key string) (string, error) {
+	key = strings.TrimSpace(key)
+	switch triggerType {
+	case TriggerEmoji:
+		key = normalizeEmoji(key)
+	case TriggerCustomEmoji:
+		for _, r := range key {
+			if r < '0' || r > '9' {
+				return "", fmt.Errorf("custom_emoji_id состоит из цифр, получено '%s'", key)
+			}
+		}
+	case TriggerSticker, TriggerStickerSet:
+		if strings.ContainsAny(key, " \t\n") {
+			return "", fmt.Errorf("ключ %s не может содержать пробелы", triggerType)
+		}
+	}
+	if key == "" {
+		return "", fmt.Errorf("ключ не может быть пустым")
+	}
+	return key, nil
+}
+
+// matchesMedia проверяет медиа-триггер на входящем сообщении
+func (t *Trigger) matchesMedia(msg IncomingMessage) bool {
+	switch t.Type {
+	case TriggerSticker:
+		return msg.Sticker != nil && msg.Sticker.FileUniqueID == t.Text
+	case TriggerStickerSet:
+		// Имена наборов в Telegram уникальны без учета регистра
+		return msg.Sticker != nil && strings.EqualFold(msg.Sticker.SetName, t.Text)
+	case TriggerEmoji:
+		return msg.Sticker != nil && normalizeEmoji(msg.Sticker.Emoji) == t.Text
+	case TriggerCustomEmoji:
+		if msg.Sticker != nil && msg.Sticker.CustomEmojiID == t.Text {
+			return true
+		}
+		for _, id := range msg.CustomEmojiIDs {
+			if id == t.Text {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MediaTriggerKey возвращает ключ медиа-триггера по сообщению, на которое ответил админ
+func MediaTriggerKey(triggerType string, sticker *StickerInfo, customEmojiIDs []string) (string, error) {
+	if triggerType == TriggerCustomEmoji {
+		if sticker != nil && sticker.CustomEmojiID != "" {
+			return sticker.CustomEmojiID, nil
+		}
+		if len(customEmojiIDs) > 0 {
+			return customEmojiIDs[0], nil
+		}
+		return "", fmt.Errorf("в сообщении нет кастомного эмодзи")
+	}
+
+	if sticker == nil {
+		return "", fmt.Errorf("ответьте командой на стикер")
+	}
+	switch triggerType {
+	case TriggerSticker:
+		return sticker.FileUniqueID, nil
+	case TriggerStickerSet:
+		if sticker.SetName == "" {
+			return "", fmt.Errorf("стикер не входит в набор")
+		}
+		return sticker.SetName, nil
+	case TriggerEmoji:
+		if sticker.Emoji == "" {
+			return "", fmt.Errorf("у стикера нет эмодзи")
+		}
+		return normalizeEmoji(sticker.Emoji), nil
+	default:
+		return "", fmt.Errorf("тип %s не относится к медиа (доступно: sticker, sticker_set, emoji, custom_emoji)", triggerType)
+	}
+}
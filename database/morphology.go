@@ -0,0 +1,62 @@
+package database
+
+import "unicode"
+
+// Окончания, которые отбрасываются от триггера при поиске с учетом словоизменения.
+// Порядок важен: сначала более длинные.
+var russianNounEndings = [][]rune{
+	[]rune("ия"), []rune("ие"), []rune("ья"), []rune("ье"),
+	[]rune("а"), []rune("я"), []rune("о"), []rune("е"),
+	[]rune("ь"), []rune("й"), []rune("ы"), []rune("и"),
+}
+
+// Падежные окончания существительных и имен, допустимые после основы
+var russianInflections = map[string]bool{
+	"":  true,
+	"а": true, "я": true, "у": true, "ю": true, "ы": true, "и": true, "е": true, "о": true, "ь": true,
+	"ой": true, "ей": true, "ою": true, "ею": true, "ом": true, "ем": true, "ём": true,
+	"ам": true, "ям": true, "ах": true, "ях": true, "ов": true, "ев": true, "ёв": true,
+	"ами": true, "ями": true, "ий": true, "ия": true, "ию": true, "ие": true, "ии": true,
+	"ья": true, "ью": true, "ье": true, "ьи": true, "ьё": true, "ьем": true, "ьём": true,
+}
+
+// Минимальная длина основы: короче не обрезаем, иначе "оля" превратится в "о"
+const minStemLength = 2
+
+// isWordSeparator - разделитель слов: все символы, кроме букв, цифр и дефиса
+func isWordSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+}
+
+// russianStem отбрасывает от слова окончание именительного падежа.
+// "маша" → "маш", "игорь" → "игор", "славик" → "славик"
+func russianStem(word []rune) []rune {
+	for _, ending := range russianNounEndings {
+		if len(word)-len(ending) < minStemLength {
+			continue
+		}
+		if hasRuneSuffix(word, ending) {
+			return word[:len(word)-len(ending)]
+		}
+	}
+	return word
+}
+
+// isRussianInflection проверяет, является ли хвост слова падежным окончанием
+func isRussianInflection(tail []rune) bool {
+	return russianInflections[string(tail)]
+}
+
+// hasRuneSuffix - аналог strings.HasSuffix для срезов рун
+func hasRuneSuffix(s, suffix []rune) bool {
+	if len(suffix) > len(s) {
+		return false
+	}
+	offset := len(s) - len(suffix)
+	for i, r := range suffix {
+		if s[offset+i] != r {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRussianStem(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"маша", "маш"},
+		{"игорь", "игор"},
+		{"славик", "славик"},
+		{"мария", "мар"},
+		{"наталья", "натал"},
+		{"андрей", "андре"},
+		{"оля", "ол"},
+		{"яна", "ян"},
+		{"ян", "ян"},
+		{"оо", "оо"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := string(russianStem([]rune(tt.word))); got != tt.want {
+			t.Errorf("russianStem(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestIsRussianInflection(t *testing.T) {
+	tests := []struct {
+		tail string
+		want bool
+	}{
+		{"", true},
+		{"у", true},
+		{"ом", true},
+		{"ами", true},
+		{"ьём", true},
+		{"ович", false},
+		{"ка", false},
+		{"варь", false},
+	}
+
+	for _, tt := range tests {
+		if got := isRussianInflection([]rune(tt.tail)); got != tt.want {
+			t.Errorf("isRussianInflection(%q) = %v, want %v", tt.tail, got, tt.want)
+		}
+	}
+}
+
+func TestTriggerAcceptMatchModes(t *testing.T) {
+	tests := []struct {
+		name    string
+		trigger string
+		mode    string
+		text    string
+		want    []string // найденные в тексте слова
+	}{
+		{"substring inside word", "ян", MatchSubstring, "в январе", []string{"ян"}},
+		{"word rejects prefix of longer word", "ян", MatchWord, "в январе", nil},
+		{"word at punctuation", "ян", MatchWord, "привет, ян!", []string{"ян"}},
+		{"word rejects inflected form", "славик", MatchWord, "славику", nil},
+		{"stem accepts nominative", "славик", MatchStem, "славик пришел", []string{"славик"}},
+		{"stem accepts dative", "славик", MatchStem, "скажи славику", []string{"славику"}},
+		{"stem accepts instrumental", "славик", MatchStem, "со славиком", []string{"славиком"}},
+		{"stem rejects patronymic", "славик", MatchStem, "славикович", nil},
+		{"stem rejects word start inside word", "славик", MatchStem, "ярославику", nil},
+		{"stem strips trigger ending", "маша", MatchStem, "с машей и машу", []string{"машей", "машу"}},
+		{"stem ignores unrelated word", "яна", MatchStem, "январь", nil},
+		{"stem keeps hyphenated word whole", "маша", MatchStem, "маша-растеряша", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trigger := &Trigger{Text: tt.trigger, MatchMode: tt.mode, Type: TriggerText, Fuzzy: FuzzyOff}
+			trigger.compile("")
+			text := lowerRunes(tt.text)
+
+			var got []string
+			for _, m := range NewMatcher([][]rune{trigger.matchPattern()}).FindAll(text) {
+				if m, ok := trigger.accept(text, m); ok {
+					got = append(got, string(text[m.Start:m.End]))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s trigger %q in %q = %q, want %q", tt.mode, tt.trigger, tt.text, got, tt.want)
+			}
+		})
+	}
+}
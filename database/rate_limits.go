@@ -0,0 +1,121 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Как долго хранить истекшие окна лимитов перед очисткой
+const rateLimitRetention = 24 * time.Hour
+
+// rateLimit - одно ограничение: не больше limit ответов за window по ключу key.
+// Кулдаун - частный случай с limit = 1.
+type rateLimit struct {
+	key    string
+	window time.Duration
+	limit  int
+}
+
+// responseLimits собирает ограничения, которые должен пройти ответ триггера в чате
+func responseLimits(settings ChatSettings, t *Trigger, chatID, userID int64) []rateLimit {
+	var limits []rateLimit
+	if t.Cooldown > 0 {
+		limits = append(limits, rateLimit{fmt.Sprintf("trigger:%d:%d", t.ID, chatID), t.Cooldown, 1})
+	}
+	if settings.ChatCooldown > 0 {
+		limits = append(limits, rateLimit{fmt.Sprintf("chat:%d", chatID), settings.ChatCooldown, 1})
+	}
+	if settings.UserCooldown > 0 {
+		limits = append(limits, rateLimit{fmt.Sprintf("user:%d:%d", chatID, userID), settings.UserCooldown, 1})
+	}
+	if settings.BudgetCount > 0 && settings.BudgetWindow > 0 {
+		limits = append(limits, rateLimit{fmt.Sprintf("budget:%d", chatID), settings.BudgetWindow, settings.BudgetCount})
+	}
+	return limits
+}
+
+// allowResponse проверяет и сразу учитывает ответ во всех ограничениях.
+// Счетчики хранятся в БД, поэтому переживают перезапуск и общие для всех реплик.
+// Ответ разрешается, только если прошли все ограничения; иначе ни одно не расходуется.
+// При ошибке БД бот не замолкает: ответ разрешается.
+func (h *BotDatabaseHandler) allowResponse(limits []rateLimit) bool {
+	if len(limits) == 0 {
+		return true
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("⚠️ Лимиты ответов не проверены: %v", err)
+		return true
+	}
+	defer tx.Rollback()
+
+	// Окно начинается заново, когда истекло; иначе счетчик растет до limit
+	query := `
+		INSERT INTO bushlatinga_bot.rate_limits (key, window_end, hits)
+		VALUES ($1, NOW() + $2::float8 * INTERVAL '1 second', 1)
+		ON CONFLICT (key) DO UPDATE SET
+			window_end = CASE WHEN rate_limits.window_end <= NOW() THEN EXCLUDED.window_end ELSE rate_limits.window_end END,
+			hits = CASE WHEN rate_limits.window_end <= NOW() THEN 1 ELSE rate_limits.hits + 1 END
+		WHERE rate_limits.window_end <= NOW() OR rate_limits.hits < $3
+		RETURNING hits
+	`
+
+	for _, limit := range limits {
+		var hits int
+		err := tx.QueryRow(query, limit.key, limit.window.Seconds(), limit.limit).Scan(&hits)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				log.Printf("⚠️ Ошибка проверки лимита %s: %v", limit.key, err)
+				return true
+			}
+			log.Printf("⏳ [bushlatinga_bot] Ответ пропущен: лимит %s", limit.key)
+			return false
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("⚠️ Ошибка сохранения лимитов: %v", err)
+	}
+	return true
+}
+
+// cleanupRateLimits удаляет давно истекшие окна, чтобы таблица не росла
+func (h *BotDatabaseHandler) cleanupRateLimits() {
+	query := "DELETE FROM bushlatinga_bot.rate_limits WHERE window_end < NOW() - $1::float8 * INTERVAL '1 second'"
+	if _, err := h.db.Exec(query, rateLimitRetention.Seconds()); err != nil {
+		log.Printf("⚠️ Ошибка очистки лимитов: %v", err)
+	}
+}
+
+// ParseCooldown разбирает длительность из команды админа: 10m, 1h30m, 45s или число секунд.
+// off/0 отключают ограничение.
+func ParseCooldown(value string) (time.Duration, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "off", "no", "0", "выкл", "нет":
+		return 0, nil
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < time.Second {
+		return 0, fmt.Errorf("некорректная длительность '%s' (пример: 30s, 10m, 1h, off)", value)
+	}
+	return d.Truncate(time.Second), nil
+}
+
+// formatCooldown форматирует длительность для сообщений админу
+func formatCooldown(d time.Duration) string {
+	if d <= 0 {
+		return "нет"
+	}
+	return d.String()
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Типы триггеров (колонка trigger_type)
+const (
+	TriggerText  = "text"  // обычный текст, сравнивается в режиме match_mode
+	TriggerRegex = "regex" // регулярное выражение Go (RE2), без учета регистра
+	// TriggerExact - детектор точного слова, см. detectors.go
+)
+
+// ParseTriggerType приводит введенный админом тип триггера к значению для БД
+func ParseTriggerType(triggerType string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(triggerType)) {
+	case "", TriggerText, "текст":
+		return TriggerText, nil
+	case TriggerRegex, "regexp", "re", "регулярка":
+		return TriggerRegex, nil
+	case TriggerExact, "detector", "точно", "детектор":
+		return TriggerExact, nil
+	case TriggerSticker, "стикер":
+		return TriggerSticker, nil
+	case TriggerStickerSet, "set", "pack", "набор":
+		return TriggerStickerSet, nil
+	case TriggerEmoji, "эмодзи":
+		return TriggerEmoji, nil
+	case TriggerCustomEmoji, "custom", "кастом":
+		return TriggerCustomEmoji, nil
+	default:
+		return "", fmt.Errorf("неизвестный тип триггера '%s' (доступно: text, regex, exact, sticker, sticker_set, emoji, custom_emoji)", triggerType)
+	}
+}
+
+// errTypeChanged - ошибка записи триггера поверх существующего с другим типом.
+// Тип молча не меняется: с ним меняется и смысл ключа (текст, регулярка, стикер).
+func errTypeChanged(key, triggerType string) error {
+	return fmt.Errorf("'%s' уже есть с другим типом: тип не меняется, удалите триггер и добавьте его заново с type=%s", key, triggerType)
+}
+
+// compileTriggerRegex компилирует регулярное выражение триггера.
+// Сравнение всегда без учета регистра, как и у текстовых триггеров.
+func compileTriggerRegex(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, fmt.Errorf("некорректное регулярное выражение: %v", err)
+	}
+	if re.MatchString("") {
+		return nil, fmt.Errorf("регулярное выражение совпадает с пустой строкой и сработает на любое сообщение")
+	}
+	return re, nil
+}
+
+// regexGroupNames возвращает имена именованных групп выражения
+func regexGroupNames(re *regexp.Regexp) []string {
+	var names []string
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// findRegex ищет все вхождения регулярного выражения триггера.
+// Позиции переводятся в руны, чтобы их можно было сравнивать с вхождениями автомата.
+func (t *Trigger) findRegex(text string, pattern int) []Match {
+	if t.re == nil {
+		return nil
+	}
+
+	var matches []Match
+	for _, loc := range t.re.FindAllStringSubmatchIndex(text, -1) {
+		captures := make(map[string]string)
+		for i, name := range t.re.SubexpNames() {
+			if name != "" && loc[2*i] >= 0 {
+				captures[name] = text[loc[2*i]:loc[2*i+1]]
+			}
+		}
+
+		matches = append(matches, Match{
+			Pattern:  pattern,
+			Start:    utf8.RuneCountInString(text[:loc[0]]),
+			End:      utf8.RuneCountInString(text[:loc[1]]),
+			Captures: captures,
+		})
+	}
+	return matches
+}
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Переменные, доступные в шаблонах ответов: {{.first_name}}, {{.chat}} и т.д.
+// Значение - описание для справки админа.
+var templateVariables = map[string]string{
+	"first_name":    "имя отправителя",
+	"username":      "@username отправителя (или имя, если username нет)",
+	"trigger":       "сработавший триггер",
+	"match":         "совпавший фрагмент сообщения",
+	"chat":          "название чата",
+	"date":          "дата в часовом поясе чата",
+	"time":          "время в часовом поясе чата",
+	"weekday":       "день недели",
+	"random_member": "случайный участник из писавших в чат за последнюю неделю",
+}
+
+// Ограничения шаблонов: результат не длиннее сообщения Telegram
+const (
+	maxRenderedLength  = 4096
+	randomMemberWindow = "7 days"
+)
+
+var russianWeekdays = [...]string{"воскресенье", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота"}
+
+// parseResponseTemplate разбирает шаблон ответа вида "Привет, {{.first_name}}!".
+// Возвращает nil, если в ответе нет подстановок.
+func parseResponseTemplate(response string) (*template.Template, error) {
+	if !strings.Contains(response, "{{") {
+		return nil, nil
+	}
+
+	tmpl, err := template.New("response").Option("missingkey=error").Parse(response)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка в шаблоне ответа: %v", err)
+	}
+	return tmpl, nil
+}
+
+// validateResponseTemplate проверяет, что шаблон разбирается и ссылается
+// только на известные переменные и именованные группы regex-триггера
+func validateResponseTemplate(response string, groups []string) error {
+	tmpl, err := parseResponseTemplate(response)
+	if err != nil || tmpl == nil {
+		return err
+	}
+
+	sample := make(map[string]string, len(templateVariables)+len(groups))
+	for name := range templateVariables {
+		sample[name] = name
+	}
+	for _, name := range groups {
+		sample[name] = name
+	}
+
+	if err := tmpl.Execute(&strings.Builder{}, sample); err != nil {
+		return fmt.Errorf("шаблон ответа ссылается на неизвестную переменную: %v\nДоступно: %s",
+			err, strings.Join(templateVariableNames(), ", "))
+	}
+	return nil
+}
+
+// templateVariableNames возвращает имена переменных шаблона по алфавиту
+func templateVariableNames() []string {
+	names := make([]string, 0, len(templateVariables))
+	for name := range templateVariables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// renderTemplate выполняет шаблон; при ошибке возвращает fallback
+func renderTemplate(tmpl *template.Template, data map[string]string, fallback string) string {
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		log.Printf("⚠️ Ошибка шаблона ответа: %v", err)
+		return fallback
+	}
+	return truncateRunes(out.String(), maxRenderedLength)
+}
+
+// templateData собирает значения переменных для ответа на конкретное вхождение.
+// Случайный участник запрашивается из БД, только если он нужен шаблону.
+func (h *BotDatabaseHandler) templateData(msg IncomingMessage, t *Trigger, m Match, needMember bool) map[string]string {
+	location := h.chatLocation(msg.ChatID)
+	now := time.Now().In(location)
+
+	username := msg.FirstName
+	if msg.UserName != "" {
+		username = "@" + msg.UserName
+	}
+
+	runes := []rune(msg.Text)
+	matched := ""
+	if m.Start >= 0 && m.End <= len(runes) && m.Start < m.End {
+		matched = string(runes[m.Start:m.End])
+	}
+
+	data := map[string]string{
+		"first_name": msg.FirstName,
+		"username":   username,
+		"trigger":    t.Text,
+		"match":      matched,
+		"chat":       msg.ChatTitle,
+		"date":       now.Format("02.01.2006"),
+		"time":       now.Format("15:04"),
+		"weekday":    russianWeekdays[now.Weekday()],
+	}
+	if needMember {
+		data["random_member"] = h.randomChatMember(msg.ChatID, msg.UserID, msg.FirstName)
+	}
+
+	// Именованные группы regex-триггера дополняют (и при совпадении имен перекрывают) переменные
+	for name, value := range m.Captures {
+		data[name] = value
+	}
+	return data
+}
+
+// randomChatMember выбирает случайного участника чата из недавно писавших, кроме отправителя
+func (h *BotDatabaseHandler) randomChatMember(chatID, excludeUserID int64, fallback string) string {
+	query := `
+		SELECT COALESCE(NULLIF(user_name, ''), '@' || user_username)
+		FROM (
+			SELECT DISTINCT ON (user_id) user_id, user_name, user_username
+			FROM main.messages_log
+			WHERE chat_id = $1 AND user_id <> $2
+			  AND created_at > NOW() - $3::interval
+			ORDER BY user_id, created_at DESC
+		) recent
+		ORDER BY random()
+		LIMIT 1
+	`
+
+	var name sql.NullString
+	err := h.db.QueryRow(query, chatID, excludeUserID, randomMemberWindow).Scan(&name)
+	if err != nil || !name.Valid || name.String == "" {
+		if err != nil && err != sql.ErrNoRows {
+			log.Printf("⚠️ Не удалось выбрать случайного участника чата %d: %v", chatID, err)
+		}
+		return fallback
+	}
+	return name.String
+}
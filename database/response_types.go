@@ -0,0 +1,140 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Типы ответов (колонка response_type в trigger_responses)
+const (
+	ResponseText      = "text"
+	ResponseSticker   = "sticker"
+	ResponsePhoto     = "photo"
+	ResponseAnimation = "animation"
+	ResponseVoice     = "voice"
+	ResponseVideoNote = "video_note"
+	ResponseDocument  = "document"
+	ResponseDice      = "dice"
+	ResponseSequence  = "sequence" // несколько сообщений подряд, части хранятся в parts
+)
+
+// Ограничение на длину последовательности, чтобы ответ не превращался во флуд
+const maxResponseParts = 5
+
+// ResponsePart - одно сообщение, которое бот отправляет в ответ.
+// Медиа хранится по Telegram file_id, Text - текст, подпись к медиа или эмодзи кубика.
+type ResponsePart struct {
+	Type   string `json:"type"`
+	FileID string `json:"file_id,omitempty"`
+	Text   string `json:"text,omitempty"`
+}
+
+// Reply - ответ на сообщение: части отправляются по порядку
+type Reply struct {
+	TriggerID int64 // 0 для ответов, не связанных с триггером из БД
+	Parts     []ResponsePart
+}
+
+// TextParts - ответ из одного текстового сообщения
+func TextParts(text string) []ResponsePart {
+	return []ResponsePart{{Type: ResponseText, Text: text}}
+}
+
+// SupportsCaption - можно ли приложить к части текст подписью, а не отдельным сообщением
+func (p ResponsePart) SupportsCaption() bool {
+	switch p.Type {
+	case ResponsePhoto, ResponseAnimation, ResponseVoice, ResponseDocument:
+		return true
+	default:
+		return false
+	}
+}
+
+// validate проверяет, что часть ответа заполнена согласно своему типу
+func (p ResponsePart) validate() error {
+	switch p.Type {
+	case ResponseText:
+		if strings.TrimSpace(p.Text) == "" {
+			return fmt.Errorf("ответ не может быть пустым")
+		}
+	case ResponseSticker, ResponsePhoto, ResponseAnimation, ResponseVoice, ResponseVideoNote, ResponseDocument:
+		if p.FileID == "" {
+			return fmt.Errorf("для ответа типа %s нужен file_id", p.Type)
+		}
+	case ResponseDice:
+		if p.Text == "" {
+			return fmt.Errorf("для кубика нужен эмодзи: 🎲 🎯 🏀 ⚽ 🎳 🎰")
+		}
+	default:
+		return fmt.Errorf("неизвестный тип ответа '%s'", p.Type)
+	}
+	return nil
+}
+
+// validateResponseParts проверяет ответ целиком
+func validateResponseParts(parts []ResponsePart) error {
+	if len(parts) == 0 {
+		return fmt.Errorf("ответ не может быть пустым")
+	}
+	if len(parts) > maxResponseParts {
+		return fmt.Errorf("в ответе не больше %d сообщений", maxResponseParts)
+	}
+	for _, p := range parts {
+		if err := p.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeResponse раскладывает ответ по колонкам trigger_responses.
+// Одиночное сообщение хранится в response_type/file_id/response_text,
+// последовательность - в parts, а в response_text попадает ее текст для поиска.
+func encodeResponse(parts []ResponsePart) (responseType, fileID, text string, partsJSON []byte, err error) {
+	if len(parts) == 1 {
+		return parts[0].Type, parts[0].FileID, parts[0].Text, nil, nil
+	}
+
+	partsJSON, err = json.Marshal(parts)
+	if err != nil {
+		return "", "", "", nil, fmt.Errorf("ошибка сериализации ответа: %v", err)
+	}
+
+	var texts []string
+	for _, p := range parts {
+		if p.Text != "" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return ResponseSequence, "", strings.Join(texts, "\n"), partsJSON, nil
+}
+
+// decodeResponse собирает части ответа из колонок trigger_responses
+func decodeResponse(responseType, fileID, text string, partsJSON []byte) ([]ResponsePart, error) {
+	if responseType != ResponseSequence {
+		return []ResponsePart{{Type: responseType, FileID: fileID, Text: text}}, nil
+	}
+
+	var parts []ResponsePart
+	if err := json.Unmarshal(partsJSON, &parts); err != nil {
+		return nil, fmt.Errorf("ошибка чтения последовательности: %v", err)
+	}
+	return parts, nil
+}
+
+// Describe - краткое описание ответа для /admin list
+func (r *TriggerResponse) Describe() string {
+	var descriptions []string
+	for _, p := range r.Parts {
+		switch {
+		case p.Type == ResponseText:
+			descriptions = append(descriptions, p.Text)
+		case p.Text != "":
+			descriptions = append(descriptions, fmt.Sprintf("[%s] %s", p.Type, p.Text))
+		default:
+			descriptions = append(descriptions, "["+p.Type+"]")
+		}
+	}
+	return strings.Join(descriptions, " + ")
+}
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Вероятность ответа триггера в процентах
+const (
+	defaultProbability = 100
+	minProbability     = 1
+)
+
+// Schedule - окна, в которые триггер активен, в часовом поясе чата.
+// Спецификация - набор частей через пробел или ";": дни недели, часы и даты.
+// Должны выполняться все указанные части, внутри части значения через запятую - любое из них:
+//
+//	пн-пт 09:00-18:00
+//	сб,вс 22:00-02:00
+//	2024-12-25..2025-01-08
+type Schedule struct {
+	weekdays [7]bool // по time.Weekday, учитывается если hasDays
+	hasDays  bool
+	hours    [][2]int // минуты от полуночи [from, to), from > to - окно через полночь
+	dates    [][2]int // даты как YYYYMMDD, включительно
+	spec     string
+}
+
+// Дни недели в спецификации: русские и английские сокращения
+var scheduleWeekdays = map[string]time.Weekday{
+	"пн": time.Monday, "вт": time.Tuesday, "ср": time.Wednesday, "чт": time.Thursday,
+	"пт": time.Friday, "сб": time.Saturday, "вс": time.Sunday,
+	"mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday, "thu": time.Thursday,
+	"fri": time.Friday, "sat": time.Saturday, "sun": time.Sunday,
+}
+
+// ParseSchedule разбирает расписание. Пустая строка и off означают "всегда" (nil).
+func ParseSchedule(spec string) (*Schedule, error) {
+	fields := strings.FieldsFunc(strings.ToLower(spec), func(r rune) bool {
+		return r == ' ' || r == ';'
+	})
+	if len(fields) == 0 || (len(fields) == 1 && (fields[0] == "off" || fields[0] == "всегда")) {
+		return nil, nil
+	}
+
+	s := &Schedule{spec: strings.Join(fields, " ")}
+	for _, field := range fields {
+		var err error
+		switch {
+		case strings.Contains(field, ":"):
+			err = s.parseHours(field)
+		case field[0] >= '0' && field[0] <= '9':
+			err = s.parseDates(field)
+		default:
+			err = s.parseWeekdays(field)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка в расписании '%s': %v", field, err)
+		}
+	}
+	return s, nil
+}
+
+// parseWeekdays разбирает "пн-пт,сб"; диапазон может переходить через воскресенье
+func (s *Schedule) parseWeekdays(field string) error {
+	for _, item := range strings.Split(field, ",") {
+		fromName, toName, isRange := strings.Cut(item, "-")
+		from, ok := scheduleWeekdays[fromName]
+		if !ok {
+			return fmt.Errorf("неизвестный день недели '%s' (пн..вс или mon..sun)", fromName)
+		}
+		to := from
+		if isRange {
+			if to, ok = scheduleWeekdays[toName]; !ok {
+				return fmt.Errorf("неизвестный день недели '%s' (пн..вс или mon..sun)", toName)
+			}
+		}
+
+		for day := from; ; day = (day + 1) % 7 {
+			s.weekdays[day] = true
+			if day == to {
+				break
+			}
+		}
+		s.hasDays = true
+	}
+	return nil
+}
+
+// parseHours разбирает "09:00-18:00,22:00-02:00"
+func (s *Schedule) parseHours(field string) error {
+	for _, item := range strings.Split(field, ",") {
+		fromText, toText, ok := strings.Cut(item, "-")
+		if !ok {
+			return fmt.Errorf("часы указываются диапазоном, например 09:00-18:00")
+		}
+		from, err := time.Parse("15:04", fromText)
+		if err != nil {
+			return fmt.Errorf("некорректное время '%s'", fromText)
+		}
+		to, err := time.Parse("15:04", toText)
+		if err != nil {
+			return fmt.Errorf("некорректное время '%s'", toText)
+		}
+
+		fromMinutes := from.Hour()*60 + from.Minute()
+		toMinutes := to.Hour()*60 + to.Minute()
+		if fromMinutes == toMinutes {
+			return fmt.Errorf("пустой диапазон часов")
+		}
+		s.hours = append(s.hours, [2]int{fromMinutes, toMinutes})
+	}
+	return nil
+}
+
+// parseDates разбирает "2024-12-25..2025-01-08,2025-03-08"
+func (s *Schedule) parseDates(field string) error {
+	for _, item := range strings.Split(field, ",") {
+		fromText, toText, isRange := strings.Cut(item, "..")
+		if !isRange {
+			toText = fromText
+		}
+		from, err := time.Parse("2006-01-02", fromText)
+		if err != nil {
+			return fmt.Errorf("некорректная дата '%s' (формат ГГГГ-ММ-ДД)", fromText)
+		}
+		to, err := time.Parse("2006-01-02", toText)
+		if err != nil {
+			return fmt.Errorf("некорректная дата '%s' (формат ГГГГ-ММ-ДД)", toText)
+		}
+		if to.Before(from) {
+			return fmt.Errorf("конец периода раньше начала")
+		}
+		s.dates = append(s.dates, [2]int{dateKey(from), dateKey(to)})
+	}
+	return nil
+}
+
+// dateKey переводит дату в число YYYYMMDD для сравнения без учета часового пояса
+func dateKey(t time.Time) int {
+	return t.Year()*10000 + int(t.Month())*100 + t.Day()
+}
+
+// Contains сообщает, попадает ли момент (уже в часовом поясе чата) в расписание.
+// nil-расписание активно всегда.
+func (s *Schedule) Contains(t time.Time) bool {
+	if s == nil {
+		return true
+	}
+	if s.hasDays && !s.weekdays[t.Weekday()] {
+		return false
+	}
+
+	if len(s.hours) > 0 {
+		minutes := t.Hour()*60 + t.Minute()
+		inside := false
+		for _, h := range s.hours {
+			if h[0] < h[1] {
+				inside = minutes >= h[0] && minutes < h[1]
+			} else {
+				inside = minutes >= h[0] || minutes < h[1]
+			}
+			if inside {
+				break
+			}
+		}
+		if !inside {
+			return false
+		}
+	}
+
+	if len(s.dates) > 0 {
+		today := dateKey(t)
+		for _, d := range s.dates {
+			if today >= d[0] && today <= d[1] {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// String возвращает нормализованную спецификацию для хранения и показа админу
+func (s *Schedule) String() string {
+	if s == nil {
+		return ""
+	}
+	return s.spec
+}
+
+// ParseProbability разбирает вероятность ответа: "30" или "30%"
+func ParseProbability(value string) (int, error) {
+	p, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(value), "%"))
+	if err != nil || p < minProbability || p > defaultProbability {
+		return 0, fmt.Errorf("вероятность должна быть от %d до %d процентов", minProbability, defaultProbability)
+	}
+	return p, nil
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    string // нормализованная спецификация, "" - всегда (nil)
+		wantErr bool
+	}{
+		{spec: "", want: ""},
+		{spec: "   ", want: ""},
+		{spec: "off", want: ""},
+		{spec: "Всегда", want: ""},
+		{spec: "пн-пт 09:00-18:00", want: "пн-пт 09:00-18:00"},
+		{spec: "СБ,ВС;22:00-02:00", want: "сб,вс 22:00-02:00"},
+		{spec: "mon-fri", want: "mon-fri"},
+		{spec: "2024-12-25..2025-01-08", want: "2024-12-25..2025-01-08"},
+		{spec: "2025-03-08", want: "2025-03-08"},
+		{spec: "-", wantErr: true},
+		{spec: "пн-", wantErr: true},
+		{spec: "-пт", wantErr: true},
+		{spec: ",", wantErr: true},
+		{spec: "понедельник", wantErr: true},
+		{spec: "09:00", wantErr: true},
+		{spec: "09:00-", wantErr: true},
+		{spec: "25:00-26:00", wantErr: true},
+		{spec: "10:00-10:00", wantErr: true},
+		{spec: "2025-13-01", wantErr: true},
+		{spec: "2025-01-08..2024-12-25", wantErr: true},
+		{spec: "2025-01-01..", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseSchedule(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseSchedule(%q) = %q, want error", tt.spec, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSchedule(%q) error: %v", tt.spec, err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("ParseSchedule(%q) = %q, want %q", tt.spec, got.String(), tt.want)
+			}
+			if tt.want == "" && got != nil {
+				t.Errorf("ParseSchedule(%q) = %+v, want nil", tt.spec, got)
+			}
+		})
+	}
+}
+
+func TestScheduleContains(t *testing.T) {
+	// 2025-01-06 - понедельник
+	at := func(day int, clock string) time.Time {
+		c, err := time.Parse("15:04", clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return time.Date(2025, time.January, day, c.Hour(), c.Minute(), 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		at   time.Time
+		want bool
+	}{
+		{"always", "", at(6, "03:00"), true},
+		{"weekday inside hours", "пн-пт 09:00-18:00", at(6, "09:00"), true},
+		{"hours end is exclusive", "пн-пт 09:00-18:00", at(6, "18:00"), false},
+		{"weekend outside weekdays", "пн-пт 09:00-18:00", at(11, "12:00"), false},
+		{"overnight window before midnight", "22:00-02:00", at(6, "23:30"), true},
+		{"overnight window after midnight", "22:00-02:00", at(7, "01:59"), true},
+		{"outside overnight window", "22:00-02:00", at(7, "02:00"), false},
+		{"one of several windows", "09:00-10:00,20:00-21:00", at(6, "20:15"), true},
+		{"weekday range across sunday", "сб-пн", at(5, "12:00"), true},
+		{"weekday range across sunday excludes tuesday", "сб-пн", at(7, "12:00"), false},
+		{"single date", "2025-01-06", at(6, "00:00"), true},
+		{"date range end is inclusive", "2024-12-25..2025-01-08", at(8, "23:59"), true},
+		{"after date range", "2024-12-25..2025-01-08", at(9, "00:00"), false},
+		{"all parts must match", "вт 2025-01-06", at(6, "12:00"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseSchedule(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseSchedule(%q) error: %v", tt.spec, err)
+			}
+			if got := s.Contains(tt.at); got != tt.want {
+				t.Errorf("ParseSchedule(%q).Contains(%v) = %v, want %v", tt.spec, tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProbability(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "30", want: 30},
+		{value: " 30% ", want: 30},
+		{value: "1", want: 1},
+		{value: "100%", want: 100},
+		{value: "0", wantErr: true},
+		{value: "101", wantErr: true},
+		{value: "-5", wantErr: true},
+		{value: "половина", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseProbability(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseProbability(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseProbability(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
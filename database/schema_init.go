@@ -5,6 +5,9 @@ import (
 	"log"
 )
 
+// schemaLockID - ключ advisory-блокировки Postgres, под которой выполняются миграции
+const schemaLockID = 0x6275736c // "busl"
+
 // initializeDatabase создает схемы и таблицы, если их нет
 func (h *BotDatabaseHandler) initializeDatabase() error {
 	// 1. Создаем схему для бота, если не существует
@@ -37,6 +40,324 @@ func (h *BotDatabaseHandler) initializeDatabase() error {
 		COMMENT ON TABLE bushlatinga_bot.bushlatinga_responses IS 'Фразы для бота bushlatinga_bot';
 	`
 
+	// 3.1. Режим сравнения триггера (добавлен позже, поэтому через ALTER)
+	upgradeResponsesMatchModeQuery := `
+		ALTER TABLE bushlatinga_bot.bushlatinga_responses
+		ADD COLUMN IF NOT EXISTS match_mode VARCHAR(20) NOT NULL DEFAULT 'substring';
+		
+		COMMENT ON COLUMN bushlatinga_bot.bushlatinga_responses.match_mode IS 'Режим сравнения: substring, word, stem';
+	`
+
+	// 3.2. Тип триггера: обычный текст или регулярное выражение
+	upgradeResponsesTypeQuery := `
+		ALTER TABLE bushlatinga_bot.bushlatinga_responses
+		ADD COLUMN IF NOT EXISTS trigger_type VARCHAR(20) NOT NULL DEFAULT 'text';
+		
+		COMMENT ON COLUMN bushlatinga_bot.bushlatinga_responses.trigger_type IS 'Тип триггера: text, regex';
+	`
+
+	// 3.3. Приоритет триггера для политики нескольких совпадений
+	upgradeResponsesPriorityQuery := `
+		ALTER TABLE bushlatinga_bot.bushlatinga_responses
+		ADD COLUMN IF NOT EXISTS priority INT NOT NULL DEFAULT 0;
+		
+		COMMENT ON COLUMN bushlatinga_bot.bushlatinga_responses.priority IS 'Приоритет: при политике priority отвечает триггер с наибольшим значением';
+	`
+
+	// 3.4. Настройки бота для отдельных чатов
+	createChatSettingsTableQuery := `
+		CREATE TABLE IF NOT EXISTS bushlatinga_bot.chat_settings (
+			chat_id BIGINT PRIMARY KEY,
+			multi_match_policy VARCHAR(20) NOT NULL DEFAULT 'silent',
+			max_replies INT NOT NULL DEFAULT 3,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			updated_at TIMESTAMPTZ DEFAULT NOW()
+		);
+		
+		COMMENT ON TABLE bushlatinga_bot.chat_settings IS 'Настройки bushlatinga_bot для отдельных чатов';
+	`
+
+	// 3.4.1. Часовой пояс чата для шаблонов ответов
+	upgradeChatSettingsTimezoneQuery := `
+		ALTER TABLE bushlatinga_bot.chat_settings
+		ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'Europe/Moscow';
+	`
+
+	// 3.5. Несколько вариантов ответа на триггер с весами.
+	// Старые ответы из response_text переносятся один раз, после чего колонка очищается.
+	createTriggerResponsesTableQuery := `
+		CREATE TABLE IF NOT EXISTS bushlatinga_bot.trigger_responses (
+			id BIGSERIAL PRIMARY KEY,
+			trigger_id BIGINT NOT NULL REFERENCES bushlatinga_bot.bushlatinga_responses(id) ON DELETE CASCADE,
+			response_text TEXT NOT NULL,
+			weight INT NOT NULL DEFAULT 1 CHECK (weight > 0),
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		);
+		
+		CREATE INDEX IF NOT EXISTS idx_trigger_responses_trigger_id
+		ON bushlatinga_bot.trigger_responses(trigger_id);
+		
+		ALTER TABLE bushlatinga_bot.bushlatinga_responses ALTER COLUMN response_text DROP NOT NULL;
+		
+		INSERT INTO bushlatinga_bot.trigger_responses (trigger_id, response_text)
+		SELECT r.id, r.response_text FROM bushlatinga_bot.bushlatinga_responses r
+		WHERE r.response_text IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM bushlatinga_bot.trigger_responses tr WHERE tr.trigger_id = r.id);
+		
+		UPDATE bushlatinga_bot.bushlatinga_responses SET response_text = NULL
+		WHERE response_text IS NOT NULL;
+		
+		COMMENT ON TABLE bushlatinga_bot.trigger_responses IS 'Варианты ответов на триггеры с весами';
+	`
+
+	// 3.6. Типы ответов: текст, стикеры, медиа по file_id, кубики и последовательности
+	upgradeTriggerResponsesTypeQuery := `
+		ALTER TABLE bushlatinga_bot.trigger_responses
+		ADD COLUMN IF NOT EXISTS response_type VARCHAR(20) NOT NULL DEFAULT 'text',
+		ADD COLUMN IF NOT EXISTS file_id TEXT,
+		ADD COLUMN IF NOT EXISTS parts JSONB;
+		
+		COMMENT ON COLUMN bushlatinga_bot.trigger_responses.response_type IS 'Тип ответа: text, sticker, photo, animation, voice, video_note, document, dice, sequence';
+		COMMENT ON COLUMN bushlatinga_bot.trigger_responses.parts IS 'Части ответа типа sequence';
+	`
+
+	// 3.7. Область действия триггера: глобально или в отдельных чатах.
+	// Один ключ может существовать глобально и в чатах, поэтому уникальность - по паре (ключ, чаты).
+	upgradeResponsesScopeQuery := `
+		ALTER TABLE bushlatinga_bot.bushlatinga_responses
+		ADD COLUMN IF NOT EXISTS chat_ids BIGINT[] NOT NULL DEFAULT '{}';
+		
+		ALTER TABLE bushlatinga_bot.bushlatinga_responses
+		DROP CONSTRAINT IF EXISTS bushlatinga_responses_trigger_text_key;
+		
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_bushlatinga_trigger_scope
+		ON bushlatinga_bot.bushlatinga_responses(trigger_text, chat_ids);
+		
+		COMMENT ON COLUMN bushlatinga_bot.bushlatinga_responses.chat_ids IS 'Чаты, в которых действует триггер; пустой массив - во всех';
+	`
+
+	// 3.8. Глобальные триггеры, отключенные в отдельных чатах
+	createChatDisabledTriggersTableQuery := `
+		CREATE TABLE IF NOT EXISTS bushlatinga_bot.chat_disabled_triggers (
+			chat_id BIGINT NOT NULL,
+			trigger_id BIGINT NOT NULL REFERENCES bushlatinga_bot.bushlatinga_responses(id) ON DELETE CASCADE,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			PRIMARY KEY (chat_id, trigger_id)
+		);
+		
+		COMMENT ON TABLE bushlatinga_bot.chat_disabled_triggers IS 'Триггеры, отключенные в отдельных чатах';
+	`
+
+	// 3.9. Ограничения частоты ответов: кулдаун триггера, паузы и бюджет чата.
+	// Счетчики окон общие для всех реплик и переживают перезапуск.
+	upgradeRateLimitsQuery := `
+		ALTER TABLE bushlatinga_bot.bushlatinga_responses
+		ADD COLUMN IF NOT EXISTS cooldown_seconds INT NOT NULL DEFAULT 0;
+		
+		ALTER TABLE bushlatinga_bot.chat_settings
+		ADD COLUMN IF NOT EXISTS chat_cooldown_seconds INT NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS user_cooldown_seconds INT NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS budget_count INT NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS budget_window_seconds INT NOT NULL DEFAULT 0;
+		
+		CREATE TABLE IF NOT EXISTS bushlatinga_bot.rate_limits (
+			key TEXT PRIMARY KEY,
+			window_end TIMESTAMPTZ NOT NULL,
+			hits INT NOT NULL DEFAULT 1
+		);
+		
+		COMMENT ON COLUMN bushlatinga_bot.bushlatinga_responses.cooldown_seconds IS 'Не чаще одного ответа триггера в чате за столько секунд';
+		COMMENT ON TABLE bushlatinga_bot.rate_limits IS 'Окна кулдаунов и бюджетов ответов';
+	`
+
+	// 3.10. Вероятность ответа и расписание активности триггера
+	upgradeResponsesScheduleQuery := `
+		ALTER TABLE bushlatinga_bot.bushlatinga_responses
+		ADD COLUMN IF NOT EXISTS probability INT NOT NULL DEFAULT 100 CHECK (probability BETWEEN 1 AND 100),
+		ADD COLUMN IF NOT EXISTS schedule TEXT NOT NULL DEFAULT '';
+		
+		COMMENT ON COLUMN bushlatinga_bot.bushlatinga_responses.probability IS 'Вероятность ответа в процентах';
+		COMMENT ON COLUMN bushlatinga_bot.bushlatinga_responses.schedule IS 'Когда триггер активен: дни недели, часы, даты; пусто - всегда';
+	`
+
+	// 3.11. Флаг enabled и детекторы точных слов.
+	// Бывший встроенный ЕБ-детектор добавляется один раз как обычная запись:
+	// отметка в seeds не дает вернуть его после удаления админом.
+	upgradeDetectorsQuery := `
+		ALTER TABLE bushlatinga_bot.bushlatinga_responses
+		ADD COLUMN IF NOT EXISTS enabled BOOLEAN NOT NULL DEFAULT TRUE;
+		
+		COMMENT ON COLUMN bushlatinga_bot.bushlatinga_responses.enabled IS 'Выключенный триггер хранится, но не срабатывает';
+		COMMENT ON COLUMN bushlatinga_bot.bushlatinga_responses.trigger_type IS 'Тип триггера: text, regex, exact (детектор слова), sticker, sticker_set, emoji, custom_emoji';
+		
+		CREATE TABLE IF NOT EXISTS bushlatinga_bot.seeds (
+			name TEXT PRIMARY KEY,
+			applied_at TIMESTAMPTZ DEFAULT NOW()
+		);
+		
+		COMMENT ON TABLE bushlatinga_bot.seeds IS 'Начальные данные, которые уже были добавлены';
+		
+		WITH seed AS (
+			INSERT INTO bushlatinga_bot.seeds (name) VALUES ('eb_detector')
+			ON CONFLICT DO NOTHING
+			RETURNING name
+		), detector AS (
+			INSERT INTO bushlatinga_bot.bushlatinga_responses (trigger_text, trigger_type, match_mode, priority)
+			SELECT 'ЕБ|ЁБ', 'exact', 'word', 100 FROM seed
+			ON CONFLICT (trigger_text, chat_ids) DO NOTHING
+			RETURNING id
+		)
+		INSERT INTO bushlatinga_bot.trigger_responses (trigger_id, response_type, response_text, parts)
+		SELECT id, 'sequence', 'Еген борисыч ла-ла-ла-ла-ла-ла',
+			'[{"type": "sticker", "file_id": "CAACAgIAAxkBAANTaUVkrWrIsoO8kVNAifaUqz16ex4AAqqFAAJVF1hIHdoBVVf89Yg2BA"},
+			  {"type": "text", "text": "Еген борисыч ла-ла-ла-ла-ла-ла"}]'::jsonb
+		FROM detector;
+	`
+
+	// 3.12. Режим ответа в чате: обычным сообщением или ответом на сообщение с триггером
+	upgradeChatSettingsReplyModeQuery := `
+		ALTER TABLE bushlatinga_bot.chat_settings
+		ADD COLUMN IF NOT EXISTS reply_mode VARCHAR(20) NOT NULL DEFAULT 'plain';
+		
+		COMMENT ON COLUMN bushlatinga_bot.chat_settings.reply_mode IS 'Как отправлять ответы: plain - обычным сообщением, reply - ответом на сообщение';
+	`
+
+	// 3.13. Нечеткое сравнение: нормализация текста и опечатки
+	upgradeResponsesFuzzyQuery := `
+		ALTER TABLE bushlatinga_bot.bushlatinga_responses
+		ADD COLUMN IF NOT EXISTS fuzzy SMALLINT NOT NULL DEFAULT -1 CHECK (fuzzy BETWEEN -1 AND 2);
+		
+		COMMENT ON COLUMN bushlatinga_bot.bushlatinga_responses.fuzzy IS 'Нечеткое сравнение: -1 - нет, 0 - нормализация текста, 1..2 - нормализация и опечатки в слове';
+	`
+
+	// 3.14. Отредактированные сообщения: политика чата и ответы бота, которые можно удалить
+	upgradeEditedMessagesQuery := `
+		ALTER TABLE bushlatinga_bot.chat_settings
+		ADD COLUMN IF NOT EXISTS edit_policy VARCHAR(20) NOT NULL DEFAULT 'ignore';
+		
+		COMMENT ON COLUMN bushlatinga_bot.chat_settings.edit_policy IS 'Правки сообщений: ignore - только лог, respond - ответ на новые триггеры, sync - еще и удаление ответов на убранные';
+		
+		CREATE TABLE IF NOT EXISTS bushlatinga_bot.sent_responses (
+			chat_id BIGINT NOT NULL,
+			message_id BIGINT NOT NULL,
+			trigger_id BIGINT NOT NULL,
+			response_message_id BIGINT NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			PRIMARY KEY (chat_id, response_message_id)
+		);
+		
+		CREATE INDEX IF NOT EXISTS idx_sent_responses_message ON bushlatinga_bot.sent_responses(chat_id, message_id);
+		
+		COMMENT ON TABLE bushlatinga_bot.sent_responses IS 'Ответы бота на сообщения с триггерами (для политики правок sync)';
+	`
+
+	// 3.15. Администраторы с ролями; chat_id = 0 - роль во всех чатах
+	createAdminsTableQuery := `
+		CREATE TABLE IF NOT EXISTS bushlatinga_bot.admins (
+			user_id BIGINT NOT NULL,
+			chat_id BIGINT NOT NULL DEFAULT 0,
+			role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+			granted_by BIGINT,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			PRIMARY KEY (user_id, chat_id)
+		);
+		
+		ALTER TABLE bushlatinga_bot.chat_settings
+		ADD COLUMN IF NOT EXISTS chat_admins_edit BOOLEAN NOT NULL DEFAULT FALSE;
+		
+		COMMENT ON TABLE bushlatinga_bot.admins IS 'Администраторы бота: owner, editor, viewer - во всех чатах (chat_id = 0) или в одном';
+		COMMENT ON COLUMN bushlatinga_bot.chat_settings.chat_admins_edit IS 'Админы чата в Telegram могут менять триггеры этого чата';
+	`
+
+	// 3.16. Журнал изменений триггеров: полное состояние до и после каждого изменения
+	createTriggerAuditTableQuery := `
+		CREATE TABLE IF NOT EXISTS bushlatinga_bot.trigger_audit (
+			id BIGSERIAL PRIMARY KEY,
+			trigger_text VARCHAR(100) NOT NULL,
+			trigger_type VARCHAR(20) NOT NULL,
+			chat_ids BIGINT[] NOT NULL DEFAULT '{}',
+			version INT NOT NULL,
+			action VARCHAR(20) NOT NULL,
+			user_id BIGINT,
+			chat_id BIGINT,
+			old_value JSONB,
+			new_value JSONB,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			
+			CONSTRAINT unique_trigger_version UNIQUE(trigger_text, chat_ids, version)
+		);
+		
+		CREATE INDEX IF NOT EXISTS idx_trigger_audit_user ON bushlatinga_bot.trigger_audit(user_id, id);
+		
+		COMMENT ON TABLE bushlatinga_bot.trigger_audit IS 'Журнал изменений триггеров: кто, когда, из какого чата, состояние до и после';
+	`
+
+	// 3.17. Счетчик срабатываний триггера и сохраненные фильтры листаемых списков /admin list
+	upgradeResponsesHitsQuery := `
+		ALTER TABLE bushlatinga_bot.bushlatinga_responses
+		ADD COLUMN IF NOT EXISTS hit_count BIGINT NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS last_hit_at TIMESTAMPTZ;
+		
+		CREATE TABLE IF NOT EXISTS bushlatinga_bot.admin_list_views (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			chat_id BIGINT NOT NULL,
+			filter JSONB NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		);
+		
+		COMMENT ON COLUMN bushlatinga_bot.bushlatinga_responses.hit_count IS 'Сколько раз бот ответил на триггер';
+		COMMENT ON TABLE bushlatinga_bot.admin_list_views IS 'Фильтры списков /admin list, по которым листают кнопки';
+	`
+
+	// 3.18. Срок действия триггера и корзина: удаленный триггер хранится с deleted_at
+	upgradeResponsesLifecycleQuery := `
+		ALTER TABLE bushlatinga_bot.bushlatinga_responses
+		ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ,
+		ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ,
+		ADD COLUMN IF NOT EXISTS deleted_by BIGINT;
+		
+		CREATE INDEX IF NOT EXISTS idx_bushlatinga_expires ON bushlatinga_bot.bushlatinga_responses(expires_at)
+			WHERE expires_at IS NOT NULL AND deleted_at IS NULL;
+		CREATE INDEX IF NOT EXISTS idx_bushlatinga_deleted ON bushlatinga_bot.bushlatinga_responses(deleted_at)
+			WHERE deleted_at IS NOT NULL;
+		
+		COMMENT ON COLUMN bushlatinga_bot.bushlatinga_responses.expires_at IS 'Когда триггер выключится сам, NULL - бессрочно';
+		COMMENT ON COLUMN bushlatinga_bot.bushlatinga_responses.deleted_at IS 'Когда триггер удален в корзину, NULL - действующий';
+	`
+
+	// 3.19. Каждое совпадение триггера: отправлен ли ответ или почему он подавлен
+	createTriggerMatchesTableQuery := `
+		CREATE TABLE IF NOT EXISTS bushlatinga_bot.trigger_matches (
+			id BIGSERIAL PRIMARY KEY,
+			trigger_id BIGINT NOT NULL,
+			chat_id BIGINT NOT NULL,
+			user_id BIGINT NOT NULL,
+			outcome VARCHAR(20) NOT NULL,
+			matched_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);
+		
+		CREATE INDEX IF NOT EXISTS idx_trigger_matches_time ON bushlatinga_bot.trigger_matches(matched_at);
+		CREATE INDEX IF NOT EXISTS idx_trigger_matches_trigger ON bushlatinga_bot.trigger_matches(trigger_id, matched_at);
+		CREATE INDEX IF NOT EXISTS idx_trigger_matches_chat ON bushlatinga_bot.trigger_matches(chat_id, matched_at);
+		
+		COMMENT ON TABLE bushlatinga_bot.trigger_matches IS 'Совпадения триггеров для /admin stats: sent - ответ отправлен, остальное - причина подавления';
+	`
+
+	// 3.20. Незавершенные диалоги /admin add: шаг и введенные данные на пару пользователь-чат
+	createAdminDialogsTableQuery := `
+		CREATE TABLE IF NOT EXISTS bushlatinga_bot.admin_dialogs (
+			user_id BIGINT NOT NULL,
+			chat_id BIGINT NOT NULL,
+			step VARCHAR(20) NOT NULL,
+			data JSONB NOT NULL DEFAULT '{}',
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (user_id, chat_id)
+		);
+		
+		COMMENT ON TABLE bushlatinga_bot.admin_dialogs IS 'Пошаговое добавление триггера: /admin add без аргументов, отмена - /cancel';
+	`
+
 	// 4. Создаем таблицу для логов сообщений в схеме main
 	createMessagesTableQuery := `
 		CREATE TABLE IF NOT EXISTS main.messages_log (
@@ -71,6 +392,25 @@ func (h *BotDatabaseHandler) initializeDatabase() error {
 		COMMENT ON TABLE main.messages_log IS 'Логи всех сообщений, полученных ботом';
 	`
 
+	// 4.1. Версии отредактированных сообщений; исходный текст остается в messages_log
+	createMessageRevisionsTableQuery := `
+		CREATE TABLE IF NOT EXISTS main.message_revisions (
+			id BIGSERIAL PRIMARY KEY,
+			bot_id BIGINT NOT NULL,
+			chat_id BIGINT NOT NULL,
+			message_id BIGINT NOT NULL,
+			revision INT NOT NULL,
+			message_text TEXT,
+			edited_at TIMESTAMPTZ DEFAULT NOW(),
+			
+			CONSTRAINT unique_message_revision UNIQUE(bot_id, chat_id, message_id, revision)
+		);
+		
+		CREATE INDEX IF NOT EXISTS idx_message_revisions_message ON main.message_revisions(chat_id, message_id);
+		
+		COMMENT ON TABLE main.message_revisions IS 'Правки сообщений: каждая версия текста после редактирования';
+	`
+
 	// 5. Создаем таблицу для статистики бота
 	createStatsTableQuery := `
 		CREATE TABLE IF NOT EXISTS main.bot_stats (
@@ -101,6 +441,13 @@ func (h *BotDatabaseHandler) initializeDatabase() error {
 	}
 	defer tx.Rollback()
 
+	// Несколько запущенных копий бота проводят миграции по очереди: иначе обе успеют
+	// прочитать старые данные (например, response_text в 3.5) и перенесут их дважды.
+	// Блокировка снимается вместе с концом транзакции.
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", schemaLockID); err != nil {
+		return fmt.Errorf("ошибка блокировки миграций: %v", err)
+	}
+
 	// Создаем схемы
 	if _, err := tx.Exec(createBotSchemaQuery); err != nil {
 		return fmt.Errorf("ошибка создания схемы бота: %v", err)
@@ -118,11 +465,121 @@ func (h *BotDatabaseHandler) initializeDatabase() error {
 	}
 	log.Println("✅ Таблица 'bushlatinga_bot.bushlatinga_responses' создана/проверена")
 
+	if _, err := tx.Exec(upgradeResponsesMatchModeQuery); err != nil {
+		return fmt.Errorf("ошибка добавления колонки match_mode: %v", err)
+	}
+	log.Println("✅ Колонка 'match_mode' создана/проверена")
+
+	if _, err := tx.Exec(upgradeResponsesTypeQuery); err != nil {
+		return fmt.Errorf("ошибка добавления колонки trigger_type: %v", err)
+	}
+	log.Println("✅ Колонка 'trigger_type' создана/проверена")
+
+	if _, err := tx.Exec(upgradeResponsesPriorityQuery); err != nil {
+		return fmt.Errorf("ошибка добавления колонки priority: %v", err)
+	}
+	log.Println("✅ Колонка 'priority' создана/проверена")
+
+	if _, err := tx.Exec(createChatSettingsTableQuery); err != nil {
+		return fmt.Errorf("ошибка создания таблицы настроек чатов: %v", err)
+	}
+	log.Println("✅ Таблица 'bushlatinga_bot.chat_settings' создана/проверена")
+
+	if _, err := tx.Exec(upgradeChatSettingsTimezoneQuery); err != nil {
+		return fmt.Errorf("ошибка добавления часового пояса чата: %v", err)
+	}
+	log.Println("✅ Колонка 'chat_settings.timezone' создана/проверена")
+
+	if _, err := tx.Exec(createTriggerResponsesTableQuery); err != nil {
+		return fmt.Errorf("ошибка создания таблицы вариантов ответа: %v", err)
+	}
+	log.Println("✅ Таблица 'bushlatinga_bot.trigger_responses' создана/проверена")
+
+	if _, err := tx.Exec(upgradeTriggerResponsesTypeQuery); err != nil {
+		return fmt.Errorf("ошибка добавления типов ответа: %v", err)
+	}
+	log.Println("✅ Колонки типов ответа созданы/проверены")
+
+	if _, err := tx.Exec(upgradeResponsesScopeQuery); err != nil {
+		return fmt.Errorf("ошибка добавления области действия триггеров: %v", err)
+	}
+	log.Println("✅ Колонка 'chat_ids' создана/проверена")
+
+	if _, err := tx.Exec(createChatDisabledTriggersTableQuery); err != nil {
+		return fmt.Errorf("ошибка создания таблицы отключенных триггеров: %v", err)
+	}
+	log.Println("✅ Таблица 'bushlatinga_bot.chat_disabled_triggers' создана/проверена")
+
+	if _, err := tx.Exec(upgradeRateLimitsQuery); err != nil {
+		return fmt.Errorf("ошибка создания ограничений частоты ответов: %v", err)
+	}
+	log.Println("✅ Таблица 'bushlatinga_bot.rate_limits' создана/проверена")
+
+	if _, err := tx.Exec(upgradeResponsesScheduleQuery); err != nil {
+		return fmt.Errorf("ошибка добавления расписания триггеров: %v", err)
+	}
+	log.Println("✅ Колонки 'probability' и 'schedule' созданы/проверены")
+
+	if _, err := tx.Exec(upgradeDetectorsQuery); err != nil {
+		return fmt.Errorf("ошибка добавления детекторов: %v", err)
+	}
+	log.Println("✅ Детекторы и флаг 'enabled' созданы/проверены")
+
+	if _, err := tx.Exec(upgradeChatSettingsReplyModeQuery); err != nil {
+		return fmt.Errorf("ошибка добавления режима ответа: %v", err)
+	}
+	log.Println("✅ Колонка 'chat_settings.reply_mode' создана/проверена")
+
+	if _, err := tx.Exec(upgradeResponsesFuzzyQuery); err != nil {
+		return fmt.Errorf("ошибка добавления нечеткого сравнения: %v", err)
+	}
+	log.Println("✅ Колонка 'fuzzy' создана/проверена")
+
+	if _, err := tx.Exec(upgradeEditedMessagesQuery); err != nil {
+		return fmt.Errorf("ошибка добавления политики правок: %v", err)
+	}
+	log.Println("✅ Таблица 'bushlatinga_bot.sent_responses' создана/проверена")
+
+	if _, err := tx.Exec(createAdminsTableQuery); err != nil {
+		return fmt.Errorf("ошибка создания таблицы администраторов: %v", err)
+	}
+	log.Println("✅ Таблица 'bushlatinga_bot.admins' создана/проверена")
+
+	if _, err := tx.Exec(createTriggerAuditTableQuery); err != nil {
+		return fmt.Errorf("ошибка создания журнала изменений: %v", err)
+	}
+	log.Println("✅ Таблица 'bushlatinga_bot.trigger_audit' создана/проверена")
+
+	if _, err := tx.Exec(upgradeResponsesHitsQuery); err != nil {
+		return fmt.Errorf("ошибка добавления счетчика срабатываний: %v", err)
+	}
+	log.Println("✅ Счетчики срабатываний и таблица 'bushlatinga_bot.admin_list_views' созданы/проверены")
+
+	if _, err := tx.Exec(upgradeResponsesLifecycleQuery); err != nil {
+		return fmt.Errorf("ошибка добавления срока действия и корзины: %v", err)
+	}
+	log.Println("✅ Срок действия и корзина триггеров созданы/проверены")
+
+	if _, err := tx.Exec(createTriggerMatchesTableQuery); err != nil {
+		return fmt.Errorf("ошибка создания таблицы совпадений: %v", err)
+	}
+	log.Println("✅ Таблица 'bushlatinga_bot.trigger_matches' создана/проверена")
+
+	if _, err := tx.Exec(createAdminDialogsTableQuery); err != nil {
+		return fmt.Errorf("ошибка создания таблицы диалогов: %v", err)
+	}
+	log.Println("✅ Таблица 'bushlatinga_bot.admin_dialogs' создана/проверена")
+
 	if _, err := tx.Exec(createMessagesTableQuery); err != nil {
 		return fmt.Errorf("ошибка создания таблицы логов: %v", err)
 	}
 	log.Println("✅ Таблица 'main.messages_log' создана/проверена")
 
+	if _, err := tx.Exec(createMessageRevisionsTableQuery); err != nil {
+		return fmt.Errorf("ошибка создания таблицы правок: %v", err)
+	}
+	log.Println("✅ Таблица 'main.message_revisions' создана/проверена")
+
 	if _, err := tx.Exec(createStatsTableQuery); err != nil {
 		return fmt.Errorf("ошибка создания таблицы статистики: %v", err)
 	}
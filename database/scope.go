@@ -0,0 +1,189 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Scope - чаты, в которых действует триггер (колонка chat_ids).
+// Пустой список означает глобальный триггер, который работает во всех чатах.
+type Scope []int64
+
+// ChatScope возвращает область по умолчанию для команды из чата:
+// в группе - только эта группа, в личке - глобально
+func ChatScope(chatID int64) Scope {
+	if chatID < 0 {
+		return Scope{chatID}
+	}
+	return Scope{}
+}
+
+// ParseScope разбирает значение опции scope=:
+// here/тут - текущий чат, global/глобально - все чаты, иначе список ID через запятую
+func ParseScope(value string, chatID int64) (Scope, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "here", "this", "тут", "здесь", "чат":
+		return ChatScope(chatID), nil
+	case "global", "глобально", "везде", "*":
+		return Scope{}, nil
+	}
+
+	var scope Scope
+	for _, item := range strings.Split(value, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(item), 10, 64)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("некорректная область '%s': ожидается here, global или ID чатов через запятую", value)
+		}
+		scope = append(scope, id)
+	}
+	return scope.normalize(), nil
+}
+
+// normalize сортирует ID и убирает повторы, чтобы одинаковые области совпадали в уникальном индексе
+func (s Scope) normalize() Scope {
+	result := append(Scope{}, s...)
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+
+	unique := result[:0]
+	for _, id := range result {
+		if len(unique) == 0 || id != unique[len(unique)-1] {
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// IsGlobal сообщает, что триггер действует во всех чатах
+func (s Scope) IsGlobal() bool {
+	return len(s) == 0
+}
+
+// Contains сообщает, действует ли триггер в чате
+func (s Scope) Contains(chatID int64) bool {
+	if s.IsGlobal() {
+		return true
+	}
+	for _, id := range s {
+		if id == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+// array готовит область для записи в BIGINT[]: глобальная область - пустой массив, не NULL
+func (s Scope) array() pq.Int64Array {
+	return pq.Int64Array(s.normalize())
+}
+
+// String форматирует область для сообщений админу
+func (s Scope) String() string {
+	if s.IsGlobal() {
+		return "глобально"
+	}
+
+	ids := make([]string, len(s))
+	for i, id := range s {
+		ids[i] = strconv.FormatInt(id, 10)
+	}
+	if len(ids) == 1 {
+		return "чат " + ids[0]
+	}
+	return "чаты " + strings.Join(ids, ", ")
+}
+
+// TriggerFilter - какие триггеры показывать в /admin list и search
+type TriggerFilter struct {
+	AllScopes bool   // все триггеры независимо от области
+	Scope     Scope  // иначе - глобальные и действующие хотя бы в одном из этих чатов
+	Type      string // только триггеры этого типа, пусто = любые
+	Search    string // только триггеры, у которых хотя бы один ответ содержит текст
+
+	CreatedFrom time.Time // созданные не раньше, нулевое время = без ограничения
+	CreatedTo   time.Time // и раньше этого момента
+	MinHits     int64     // сработавшие не меньше MinHits раз
+	MaxHits     *int64    // и не больше MaxHits, nil = без ограничения
+
+	Trash bool // триггеры из корзины вместо действующих
+}
+
+// String форматирует фильтр для заголовка списка
+func (f TriggerFilter) String() string {
+	description := "глобальные и " + f.Scope.String()
+	if f.Scope.IsGlobal() {
+		description = "только глобальные"
+	}
+	if f.AllScopes {
+		description = "все чаты"
+	}
+
+	if f.Type != "" {
+		description += ", тип " + f.Type
+	}
+	if f.Search != "" {
+		description += fmt.Sprintf(", ответ содержит '%s'", f.Search)
+	}
+	if !f.CreatedFrom.IsZero() || !f.CreatedTo.IsZero() {
+		description += ", созданы " + formatDateRange(f.CreatedFrom, f.CreatedTo)
+	}
+	if f.MinHits > 0 || f.MaxHits != nil {
+		description += ", срабатываний " + formatHitRange(f.MinHits, f.MaxHits)
+	}
+	if f.Trash {
+		description += ", в корзине"
+	}
+	return description
+}
+
+// sql возвращает условие WHERE для фильтра; параметры нумеруются с firstArg
+func (f TriggerFilter) sql(firstArg int) (string, []interface{}) {
+	conditions := []string{"deleted_at IS NULL"}
+	if f.Trash {
+		conditions[0] = "deleted_at IS NOT NULL"
+	}
+	var args []interface{}
+
+	switch {
+	case f.AllScopes:
+	case f.Scope.IsGlobal():
+		conditions = append(conditions, "chat_ids = '{}'")
+	default:
+		args = append(args, f.Scope.array())
+		conditions = append(conditions, fmt.Sprintf("(chat_ids = '{}' OR chat_ids && $%d)", firstArg+len(args)-1))
+	}
+
+	if f.Type != "" {
+		args = append(args, f.Type)
+		conditions = append(conditions, fmt.Sprintf("trigger_type = $%d", firstArg+len(args)-1))
+	}
+	if f.Search != "" {
+		args = append(args, "%"+strings.ToLower(f.Search)+"%")
+		conditions = append(conditions, fmt.Sprintf(`id IN (
+			SELECT trigger_id FROM bushlatinga_bot.trigger_responses
+			WHERE LOWER(response_text) LIKE $%d
+		)`, firstArg+len(args)-1))
+	}
+	if !f.CreatedFrom.IsZero() {
+		args = append(args, f.CreatedFrom)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", firstArg+len(args)-1))
+	}
+	if !f.CreatedTo.IsZero() {
+		args = append(args, f.CreatedTo)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", firstArg+len(args)-1))
+	}
+	if f.MinHits > 0 {
+		args = append(args, f.MinHits)
+		conditions = append(conditions, fmt.Sprintf("hit_count >= $%d", firstArg+len(args)-1))
+	}
+	if f.MaxHits != nil {
+		args = append(args, *f.MaxHits)
+		conditions = append(conditions, fmt.Sprintf("hit_count <= $%d", firstArg+len(args)-1))
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
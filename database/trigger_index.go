@@ -0,0 +1,353 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Канал Postgres, через который реплики сообщают друг другу об изменении триггеров и настроек
+const (
+	triggerNotifyChannel   = "bushlatinga_triggers"
+	triggerResyncInterval  = 5 * time.Minute
+	listenerMinReconnect   = 10 * time.Second
+	listenerMaxReconnect   = time.Minute
+	triggerNotifyMaxLength = 200
+)
+
+// Trigger - одна запись из bushlatinga_responses, загруженная в память
+type Trigger struct {
+	ID        int64
+	Text      string        // trigger_text как в БД
+	MatchMode string        // substring, word или stem
+	Type      string        // text, regex или exact
+	Priority  int           // чем больше, тем важнее при политике priority
+	ChatIDs   Scope         // в каких чатах действует, пусто = глобальный
+	Cooldown  time.Duration // не чаще одного ответа за это время в каждом чате
+	Chance    int           // вероятность ответа в процентах, 100 = всегда
+	Schedule  *Schedule     // когда триггер активен, nil = всегда
+	Enabled   bool          // выключенный триггер хранится, но не срабатывает
+	Fuzzy     int           // FuzzyOff, FuzzyNormal или допустимое число опечаток
+	CreatedAt time.Time     // когда триггер создан
+	Hits      int64         // сколько раз бот на него ответил
+	ExpiresAt time.Time     // когда триггер перестанет срабатывать, нулевое время = бессрочно
+	DeletedAt time.Time     // когда триггер удален в корзину, нулевое время = действующий
+
+	Responses []*TriggerResponse // варианты ответа из trigger_responses
+
+	lower       []rune         // trigger_text в нижнем регистре для автомата
+	folded      []rune         // нормализованный trigger_text для нечеткого поиска
+	foldedWords [][]rune       // слова folded для поиска с опечатками
+	re          *regexp.Regexp // скомпилированное выражение для regex-триггеров
+	words       []string       // варианты слова для детекторов
+}
+
+// triggerSnapshot - неизменяемый снимок всех триггеров на момент загрузки.
+// Шаблон i автоматов matcher и fuzzy соответствует триггеру triggers[i].
+// Автоматы общие для всех чатов, область действия проверяется после поиска.
+type triggerSnapshot struct {
+	triggers []*Trigger
+	matcher  *Matcher
+	fuzzy    *Matcher                 // по нормализованному тексту, nil - нечетких триггеров нет
+	typos    bool                     // есть триггеры с поиском опечаток
+	hidden   map[int64]map[int64]bool // чат -> ID триггеров, отключенных или переопределенных в нем
+	loadedAt time.Time
+}
+
+// newTriggerSnapshot компилирует автомат по набору триггеров.
+// disabled - триггеры, отключенные в отдельных чатах; к ним добавляются глобальные
+// триггеры, переопределенные в чате собственным триггером с тем же ключом.
+func newTriggerSnapshot(triggers []*Trigger, disabled map[int64]map[int64]bool) *triggerSnapshot {
+	patterns := make([][]rune, len(triggers))
+	fuzzyPatterns := make([][]rune, len(triggers))
+	hasFuzzy, hasTypos := false, false
+	globals := make(map[string]*Trigger)
+	for i, t := range triggers {
+		patterns[i] = t.matchPattern()
+		if fuzzyPatterns[i] = t.fuzzyPattern(); fuzzyPatterns[i] != nil {
+			hasFuzzy = true
+			hasTypos = hasTypos || t.Fuzzy > FuzzyNormal
+		}
+		if t.ChatIDs.IsGlobal() {
+			globals[t.Type+"\x00"+t.Text] = t
+		}
+	}
+
+	hidden := make(map[int64]map[int64]bool, len(disabled))
+	hide := func(chatID, triggerID int64) {
+		if hidden[chatID] == nil {
+			hidden[chatID] = make(map[int64]bool)
+		}
+		hidden[chatID][triggerID] = true
+	}
+	for chatID, ids := range disabled {
+		for id := range ids {
+			hide(chatID, id)
+		}
+	}
+	for _, t := range triggers {
+		if global := globals[t.Type+"\x00"+t.Text]; global != nil && global != t {
+			for _, chatID := range t.ChatIDs {
+				hide(chatID, global.ID)
+			}
+		}
+	}
+
+	snapshot := &triggerSnapshot{
+		triggers: triggers,
+		matcher:  NewMatcher(patterns),
+		typos:    hasTypos,
+		hidden:   hidden,
+		loadedAt: time.Now(),
+	}
+	if hasFuzzy {
+		snapshot.fuzzy = NewMatcher(fuzzyPatterns)
+	}
+	return snapshot
+}
+
+// activeIn сообщает, должен ли триггер срабатывать в чате
+func (s *triggerSnapshot) activeIn(t *Trigger, chatID int64) bool {
+	return t.Enabled && t.ChatIDs.Contains(chatID) && !s.hidden[chatID][t.ID]
+}
+
+// expired сообщает, что срок действия триггера истек к моменту now
+func (t *Trigger) expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !now.Before(t.ExpiresAt)
+}
+
+// compile готовит триггер к поиску. Ошибки не фатальны:
+// запись с некорректным выражением просто никогда не срабатывает,
+// а некорректное расписание игнорируется.
+func (t *Trigger) compile(schedule string) {
+	t.lower = lowerRunes(t.Text)
+
+	var err error
+	if t.Schedule, err = ParseSchedule(schedule); err != nil {
+		log.Printf("⚠️ Расписание триггера #%d не используется: %v", t.ID, err)
+	}
+
+	if t.Type == TriggerText && t.Fuzzy >= FuzzyNormal {
+		t.folded = foldString(t.Text)
+		t.foldedWords = splitFoldedWords(t.folded)
+	}
+
+	switch t.Type {
+	case TriggerRegex:
+		re, err := compileTriggerRegex(t.Text)
+		if err != nil {
+			log.Printf("⚠️ Триггер #%d пропущен: %v", t.ID, err)
+		}
+		t.re = re
+	case TriggerExact:
+		words, err := parseExactWords(t.Text)
+		if err != nil {
+			log.Printf("⚠️ Детектор #%d пропущен: %v", t.ID, err)
+		}
+		t.words = words
+	}
+}
+
+// triggerColumns - колонки bushlatinga_responses в порядке, который ожидает queryTriggers
+const triggerColumns = "id, trigger_text, match_mode, trigger_type, priority, chat_ids, cooldown_seconds, probability, schedule, enabled, fuzzy, " +
+	"COALESCE(created_at, NOW()), hit_count, expires_at, deleted_at"
+
+// queryTriggers выполняет SELECT по bushlatinga_responses и собирает триггеры вместе с ответами.
+// where и orderBy подставляются в запрос как есть, args - параметры для where.
+func (h *BotDatabaseHandler) queryTriggers(where, orderBy string, args ...interface{}) ([]*Trigger, error) {
+	query := "SELECT " + triggerColumns + " FROM bushlatinga_bot.bushlatinga_responses"
+	if where != "" {
+		query += " WHERE " + where
+	}
+	if orderBy != "" {
+		query += " ORDER BY " + orderBy
+	}
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки триггеров: %v", err)
+	}
+	defer rows.Close()
+
+	var triggers []*Trigger
+	for rows.Next() {
+		t := &Trigger{}
+		var chatIDs pq.Int64Array
+		var cooldown int
+		var schedule string
+		var expiresAt, deletedAt sql.NullTime
+		if err := rows.Scan(&t.ID, &t.Text, &t.MatchMode, &t.Type, &t.Priority, &chatIDs, &cooldown, &t.Chance, &schedule, &t.Enabled, &t.Fuzzy,
+			&t.CreatedAt, &t.Hits, &expiresAt, &deletedAt); err != nil {
+			return nil, fmt.Errorf("ошибка чтения триггера: %v", err)
+		}
+		t.ExpiresAt, t.DeletedAt = expiresAt.Time, deletedAt.Time
+		t.ChatIDs = Scope(chatIDs)
+		t.Cooldown = time.Duration(cooldown) * time.Second
+		t.compile(schedule)
+		triggers = append(triggers, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения триггеров: %v", err)
+	}
+	rows.Close()
+
+	if err := h.attachResponses(triggers); err != nil {
+		return nil, err
+	}
+	return triggers, nil
+}
+
+// reloadTriggers перечитывает все триггеры из БД (кроме корзины) и атомарно подменяет индекс в памяти
+func (h *BotDatabaseHandler) reloadTriggers() error {
+	triggers, err := h.queryTriggers("deleted_at IS NULL", "id")
+	if err != nil {
+		return err
+	}
+
+	disabled, err := h.loadDisabledTriggers()
+	if err != nil {
+		return err
+	}
+
+	// Автомат строится до захвата блокировки: читатели видят либо старый, либо новый снимок целиком
+	snapshot := newTriggerSnapshot(triggers, disabled)
+
+	h.mu.Lock()
+	h.triggers = snapshot
+	h.mu.Unlock()
+	h.pruneRecentResponses(triggers)
+
+	log.Printf("✅ [bushlatinga_bot] Индекс триггеров загружен: %d записей", len(triggers))
+	return nil
+}
+
+// reloadIndex перечитывает все, что бот держит в памяти: триггеры, настройки чатов,
+// роли администраторов и незавершенные диалоги
+func (h *BotDatabaseHandler) reloadIndex() error {
+	if err := h.reloadTriggers(); err != nil {
+		return err
+	}
+	if err := h.reloadChatSettings(); err != nil {
+		return err
+	}
+	if err := h.reloadRoles(); err != nil {
+		return err
+	}
+	return h.reloadDialogs()
+}
+
+// triggerSnapshot возвращает текущий снимок триггеров (никогда не nil)
+func (h *BotDatabaseHandler) triggerSnapshot() *triggerSnapshot {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.triggers == nil {
+		return &triggerSnapshot{}
+	}
+	return h.triggers
+}
+
+// notifyIndexChanged сообщает всем репликам (включая текущую), что триггеры или настройки изменились.
+// Локальный индекс перезагружается сразу, не дожидаясь уведомления.
+func (h *BotDatabaseHandler) notifyIndexChanged(reason string) {
+	if err := h.reloadIndex(); err != nil {
+		log.Printf("❌ Ошибка перезагрузки индекса триггеров: %v", err)
+	}
+
+	h.publishChange(reason)
+}
+
+// publishChange отправляет уведомление в канал изменений, не трогая локальное состояние
+func (h *BotDatabaseHandler) publishChange(reason string) {
+	reason = truncateRunes(reason, triggerNotifyMaxLength)
+	if _, err := h.db.Exec("SELECT pg_notify($1, $2)", triggerNotifyChannel, reason); err != nil {
+		log.Printf("❌ Ошибка отправки pg_notify: %v", err)
+	}
+}
+
+// startTriggerListener подписывается на канал изменений триггеров от других реплик
+func (h *BotDatabaseHandler) startTriggerListener(connectionString string) error {
+	listener := pq.NewListener(connectionString, listenerMinReconnect, listenerMaxReconnect,
+		func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				log.Printf("⚠️ [listener] Событие %d: %v", event, err)
+			}
+		})
+
+	if err := listener.Listen(triggerNotifyChannel); err != nil {
+		listener.Close()
+		return fmt.Errorf("ошибка подписки на %s: %v", triggerNotifyChannel, err)
+	}
+
+	h.listener = listener
+
+	log.Printf("✅ [bushlatinga_bot] Подписка на уведомления '%s' активна", triggerNotifyChannel)
+	return nil
+}
+
+// triggerSyncLoop перезагружает индекс при получении уведомлений и по таймеру, а по таймеру
+// еще и чистит устаревшие данные. Работает и без подписки (listener == nil): тогда изменения
+// с других реплик подхватываются только полной синхронизацией по таймеру.
+func (h *BotDatabaseHandler) triggerSyncLoop(listener *pq.Listener) {
+	ticker := time.NewTicker(triggerResyncInterval)
+	defer ticker.Stop()
+
+	// Из nil-канала select никогда не читает
+	var notify <-chan *pq.Notification
+	if listener != nil {
+		notify = listener.Notify
+	}
+
+	for {
+		select {
+		case <-h.stop:
+			return
+
+		case n, ok := <-notify:
+			if !ok {
+				// Подписка закрыта: дальше только по таймеру
+				notify = nil
+				continue
+			}
+			switch {
+			// n == nil означает переподключение: уведомления могли потеряться
+			case n == nil:
+				log.Println("🔄 [listener] Переподключение, полная синхронизация триггеров")
+				h.reloadIndexSafely()
+			case n.Extra == dialogsNotifyReason:
+				h.reloadDialogsSafely()
+			case n.Extra == rolesNotifyReason:
+				if err := h.reloadRoles(); err != nil {
+					log.Printf("⚠️ Не удалось обновить роли, работаю на старых: %v", err)
+				}
+			default:
+				log.Printf("🔔 [listener] Индекс изменен: %s", n.Extra)
+				h.reloadIndexSafely()
+			}
+
+		case <-ticker.C:
+			h.reloadIndexSafely()
+			h.cleanupRateLimits()
+			h.cleanupSentResponses()
+			h.cleanupListViews()
+			h.sweepExpiredTriggers()
+			h.cleanupTrash()
+			h.cleanupMatches()
+			h.cleanupAdminDialogs()
+			if listener != nil {
+				go listener.Ping()
+			}
+		}
+	}
+}
+
+// reloadIndexSafely перезагружает индекс; при ошибке оставляет прежний снимок
+func (h *BotDatabaseHandler) reloadIndexSafely() {
+	if err := h.reloadIndex(); err != nil {
+		log.Printf("⚠️ Не удалось обновить индекс, работаю на старом снимке: %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Сколько триггер хранится в корзине, прежде чем удалиться насовсем.
+// Его версии остаются в журнале, и /admin restore <ключ> <версия> вернет его и после этого.
+const trashRetention = 30 * 24 * time.Hour
+
+// AdminNotifier отправляет служебное сообщение администраторам (в чат TeleLogger)
+type AdminNotifier func(text string)
+
+// SetAdminNotifier подключает отправку уведомлений администраторам.
+// Без нее фоновые изменения триггеров попадают только в лог.
+func (h *BotDatabaseHandler) SetAdminNotifier(notify AdminNotifier) {
+	h.mu.Lock()
+	h.adminNotifier = notify
+	h.mu.Unlock()
+}
+
+// notifyAdmins отправляет уведомление, если оно подключено
+func (h *BotDatabaseHandler) notifyAdmins(text string) {
+	h.mu.RLock()
+	notify := h.adminNotifier
+	h.mu.RUnlock()
+
+	if notify != nil {
+		notify(text)
+	}
+}
+
+// trashTrigger помечает триггер удаленным. Ответы и настройки сохраняются,
+// поэтому /admin restore возвращает его целиком.
+func trashTrigger(tx *sql.Tx, by Actor, id int64) error {
+	query := `
+		UPDATE bushlatinga_bot.bushlatinga_responses
+		SET deleted_at = NOW(), deleted_by = $2, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	if _, err := tx.Exec(query, id, by.UserID); err != nil {
+		return fmt.Errorf("ошибка удаления записи в корзину: %v", err)
+	}
+	return nil
+}
+
+// RestoreFromTrash возвращает триггер из корзины в той области, откуда он удален
+func (h *BotDatabaseHandler) RestoreFromTrash(by Actor, key string, scope Scope) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id FROM bushlatinga_bot.bushlatinga_responses
+		WHERE ` + triggerKeyCondition("$1") + `
+		  AND chat_ids = $2 AND deleted_at IS NOT NULL
+		ORDER BY (trigger_type <> 'text') DESC
+		LIMIT 1
+	`
+	var id int64
+	err = tx.QueryRow(query, strings.TrimSpace(key), scope.array()).Scan(&id)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("в корзине нет '%s' (%s); см. /admin trash", key, scope)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка поиска в корзине: %v", err)
+	}
+
+	restoreQuery := "UPDATE bushlatinga_bot.bushlatinga_responses SET deleted_at = NULL, deleted_by = NULL, updated_at = NOW() WHERE id = $1"
+	if _, err := tx.Exec(restoreQuery, id); err != nil {
+		return fmt.Errorf("ошибка восстановления записи: %v", err)
+	}
+	after, err := loadTriggerState(tx, id)
+	if err != nil {
+		return err
+	}
+	if err := recordChange(tx, by, AuditRestore, nil, after); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка коммита транзакции: %v", err)
+	}
+
+	log.Printf("✅ [bushlatinga_bot] Запись возвращена из корзины: '%s' [%s] (ID: %d)\n", key, scope, id)
+	h.notifyIndexChanged("untrash:" + key)
+	return nil
+}
+
+// ListTrash возвращает последние удаленные триггеры под фильтром (filter.Trash) и их общее количество
+func (h *BotDatabaseHandler) ListTrash(filter TriggerFilter, limit int) ([]*Trigger, int, error) {
+	where, args := filter.sql(1)
+
+	var total int
+	if err := h.db.QueryRow("SELECT COUNT(*) FROM bushlatinga_bot.bushlatinga_responses WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("ошибка подсчета корзины: %v", err)
+	}
+
+	triggers, err := h.queryTriggers(where, fmt.Sprintf("deleted_at DESC LIMIT %d", limit), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return triggers, total, nil
+}
+
+// cleanupTrash удаляет насовсем триггеры, которые пролежали в корзине дольше trashRetention
+func (h *BotDatabaseHandler) cleanupTrash() {
+	query := "DELETE FROM bushlatinga_bot.bushlatinga_responses WHERE deleted_at < NOW() - $1::float8 * INTERVAL '1 second'"
+	res, err := h.db.Exec(query, trashRetention.Seconds())
+	if err != nil {
+		log.Printf("⚠️ Ошибка очистки корзины: %v", err)
+		return
+	}
+	if purged, _ := res.RowsAffected(); purged > 0 {
+		log.Printf("🗑 [bushlatinga_bot] Корзина: удалено насовсем %d триггеров", purged)
+	}
+}
+
+// sweepExpiredTriggers выключает триггеры с истекшим сроком действия и сообщает об этом админам.
+// Срабатывать они перестают сразу по истечении срока, выключение лишь фиксирует это в БД и журнале.
+// Строки блокируются с SKIP LOCKED, поэтому каждый триггер выключает и объявляет одна реплика.
+func (h *BotDatabaseHandler) sweepExpiredTriggers() {
+	expired, err := h.disableExpiredTriggers()
+	if err != nil {
+		log.Printf("⚠️ Ошибка выключения истекших триггеров: %v", err)
+		return
+	}
+	if len(expired) == 0 {
+		return
+	}
+
+	log.Printf("⌛ [bushlatinga_bot] Выключены истекшие триггеры: %s", strings.Join(expired, ", "))
+	h.notifyIndexChanged("expired")
+	h.notifyAdmins("⌛ Истек срок действия, триггеры выключены:\n• " + strings.Join(expired, "\n• ") +
+		"\n\nВключить снова: /admin enable <ключ>")
+}
+
+// disableExpiredTriggers выключает истекшие триггеры одной транзакцией и возвращает их описания
+func (h *BotDatabaseHandler) disableExpiredTriggers() ([]string, error) {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT id, trigger_text, chat_ids FROM bushlatinga_bot.bushlatinga_responses
+		WHERE expires_at <= NOW() AND enabled AND deleted_at IS NULL
+		ORDER BY id
+		FOR UPDATE SKIP LOCKED
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска истекших триггеров: %v", err)
+	}
+
+	var ids []int64
+	var expired []string
+	for rows.Next() {
+		var id int64
+		var text string
+		var chatIDs pq.Int64Array
+		if err := rows.Scan(&id, &text, &chatIDs); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("ошибка чтения истекшего триггера: %v", err)
+		}
+		ids = append(ids, id)
+		expired = append(expired, fmt.Sprintf("'%s' (%s)", text, Scope(chatIDs)))
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("ошибка чтения истекших триггеров: %v", err)
+	}
+	rows.Close()
+
+	system := Actor{}
+	for _, id := range ids {
+		err := auditedChange(tx, system, id, func() error {
+			_, err := tx.Exec("UPDATE bushlatinga_bot.bushlatinga_responses SET enabled = FALSE, updated_at = NOW() WHERE id = $1", id)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка коммита транзакции: %v", err)
+	}
+	return expired, nil
+}
+
+// ParseExpiry разбирает срок действия из команды админа относительно now (в часовом поясе чата):
+// длительность (12h, 7d), дата (2025-01-31 - триггер действует весь этот день)
+// или дата со временем (2025-01-31T18:00). off убирает срок. Нулевое время - бессрочно.
+func ParseExpiry(value string, now time.Time) (time.Time, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "off", "no", "0", "нет", "выкл", "never":
+		return time.Time{}, nil
+	}
+
+	var expiresAt time.Time
+	if date, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		expiresAt = date.AddDate(0, 0, 1)
+	} else if date, err := time.ParseInLocation("02.01.2006", value, now.Location()); err == nil {
+		expiresAt = date.AddDate(0, 0, 1)
+	} else if moment, err := time.ParseInLocation("2006-01-02t15:04", value, now.Location()); err == nil {
+		expiresAt = moment
+	} else if strings.HasSuffix(value, "d") || strings.HasSuffix(value, "д") {
+		n, err := strconv.Atoi(strings.TrimRight(value, "dд"))
+		if err != nil || n < 1 {
+			return time.Time{}, fmt.Errorf("некорректный срок '%s' (пример: 7d, 12h, 2025-01-31, off)", value)
+		}
+		expiresAt = now.AddDate(0, 0, n)
+	} else {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < time.Minute {
+			return time.Time{}, fmt.Errorf("некорректный срок '%s' (пример: 7d, 12h, 2025-01-31, off)", value)
+		}
+		expiresAt = now.Add(d)
+	}
+
+	if !expiresAt.After(now) {
+		return time.Time{}, fmt.Errorf("срок '%s' уже прошел", value)
+	}
+	return expiresAt.Truncate(time.Second), nil
+}
+
+// formatExpiry форматирует срок действия для сообщений админу
+func formatExpiry(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return "бессрочно"
+	}
+	return t.In(loc).Format("2006-01-02 15:04")
+}
@@ -0,0 +1,343 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"text/template"
+
+	"github.com/lib/pq"
+)
+
+// TriggerResponse - один из вариантов ответа триггера (таблица trigger_responses)
+type TriggerResponse struct {
+	ID     int64
+	Parts  []ResponsePart // сообщения ответа по порядку
+	Weight int            // относительная вероятность выбора, не меньше 1
+
+	tmpls      []*template.Template // шаблоны текста частей, nil - без подстановок
+	usesMember bool                 // шаблону нужен случайный участник чата (запрос в БД)
+}
+
+// recentResponseKey - ключ последнего отправленного ответа: чат + триггер
+type recentResponseKey struct {
+	chatID    int64
+	triggerID int64
+}
+
+// attachResponses загружает варианты ответов для переданных триггеров
+func (h *BotDatabaseHandler) attachResponses(triggers []*Trigger) error {
+	if len(triggers) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(triggers))
+	byID := make(map[int64]*Trigger, len(triggers))
+	for i, t := range triggers {
+		ids[i] = t.ID
+		byID[t.ID] = t
+	}
+
+	query := `
+		SELECT id, trigger_id, response_type, COALESCE(file_id, ''), response_text, parts, weight
+		FROM bushlatinga_bot.trigger_responses
+		WHERE trigger_id = ANY($1)
+		ORDER BY id
+	`
+
+	rows, err := h.db.Query(query, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки ответов: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r := &TriggerResponse{}
+		var triggerID int64
+		var responseType, fileID, text string
+		var partsJSON []byte
+		if err := rows.Scan(&r.ID, &triggerID, &responseType, &fileID, &text, &partsJSON, &r.Weight); err != nil {
+			return fmt.Errorf("ошибка чтения ответа: %v", err)
+		}
+
+		parts, err := decodeResponse(responseType, fileID, text, partsJSON)
+		if err != nil {
+			log.Printf("⚠️ Ответ #%d пропущен: %v", r.ID, err)
+			continue
+		}
+		r.Parts = parts
+
+		r.tmpls = make([]*template.Template, len(parts))
+		for i, p := range parts {
+			tmpl, err := parseResponseTemplate(p.Text)
+			if err != nil {
+				log.Printf("⚠️ Шаблон ответа #%d не используется: %v", r.ID, err)
+			}
+			r.tmpls[i] = tmpl
+			if tmpl != nil && strings.Contains(p.Text, "random_member") {
+				r.usesMember = true
+			}
+		}
+
+		t := byID[triggerID]
+		t.Responses = append(t.Responses, r)
+	}
+
+	return rows.Err()
+}
+
+// pickResponse выбирает ответ триггера случайно с учетом весов.
+// Последний ответ, отправленный в этот чат, не повторяется, если есть из чего выбрать.
+func (h *BotDatabaseHandler) pickResponse(chatID int64, t *Trigger) *TriggerResponse {
+	if len(t.Responses) == 0 {
+		return nil
+	}
+
+	key := recentResponseKey{chatID: chatID, triggerID: t.ID}
+
+	h.recentMu.Lock()
+	defer h.recentMu.Unlock()
+
+	lastID := h.recentResponses[key]
+	candidates := t.Responses
+	if len(candidates) > 1 && lastID != 0 {
+		candidates = make([]*TriggerResponse, 0, len(t.Responses))
+		for _, r := range t.Responses {
+			if r.ID != lastID {
+				candidates = append(candidates, r)
+			}
+		}
+	}
+
+	chosen := weightedChoice(candidates)
+	h.recentResponses[key] = chosen.ID
+	return chosen
+}
+
+// pruneRecentResponses забывает последние ответы удаленных триггеров и удаленных вариантов ответа,
+// чтобы память не росла за счет триггеров, которых уже нет
+func (h *BotDatabaseHandler) pruneRecentResponses(triggers []*Trigger) {
+	responses := make(map[int64]map[int64]bool, len(triggers))
+	for _, t := range triggers {
+		ids := make(map[int64]bool, len(t.Responses))
+		for _, r := range t.Responses {
+			ids[r.ID] = true
+		}
+		responses[t.ID] = ids
+	}
+
+	h.recentMu.Lock()
+	defer h.recentMu.Unlock()
+	for key, responseID := range h.recentResponses {
+		if !responses[key.triggerID][responseID] {
+			delete(h.recentResponses, key)
+		}
+	}
+}
+
+// weightedChoice выбирает элемент с вероятностью, пропорциональной весу
+func weightedChoice(responses []*TriggerResponse) *TriggerResponse {
+	total := 0
+	for _, r := range responses {
+		total += max(r.Weight, 1)
+	}
+
+	n := rand.Intn(total)
+	for _, r := range responses {
+		n -= max(r.Weight, 1)
+		if n < 0 {
+			return r
+		}
+	}
+	return responses[len(responses)-1]
+}
+
+// render подставляет переменные в шаблоны текста частей.
+// При ошибке шаблона часть отправляется с исходным текстом.
+func (r *TriggerResponse) render(data map[string]string) []ResponsePart {
+	parts := make([]ResponsePart, len(r.Parts))
+	copy(parts, r.Parts)
+
+	for i, tmpl := range r.tmpls {
+		if tmpl != nil {
+			parts[i].Text = renderTemplate(tmpl, data, parts[i].Text)
+		}
+	}
+	return parts
+}
+
+// queryRower - общее у *sql.DB и *sql.Tx для запросов одной строки
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// triggerKeyCondition - условие WHERE для поиска триггера по ключу из параметра param.
+// Текстовые триггеры сравниваются без учета регистра, все остальные - точно.
+func triggerKeyCondition(param string) string {
+	return fmt.Sprintf(`((trigger_type <> 'text' AND trigger_text = %[1]s)
+		    OR (trigger_type = 'text' AND trigger_text = LOWER(%[1]s)))`, param)
+}
+
+// findTriggerByKey ищет триггер по ключу из команды админа в точно такой же области
+func findTriggerByKey(q queryRower, key string, scope Scope) (int64, string, error) {
+	query := `
+		SELECT id, trigger_type FROM bushlatinga_bot.bushlatinga_responses
+		WHERE ` + triggerKeyCondition("$1") + `
+		  AND chat_ids = $2 AND deleted_at IS NULL
+		ORDER BY (trigger_type <> 'text') DESC
+		LIMIT 1
+	`
+
+	var id int64
+	var triggerType string
+	err := q.QueryRow(query, strings.TrimSpace(key), scope.array()).Scan(&id, &triggerType)
+	return id, triggerType, err
+}
+
+// keyNotFound - ошибка для ключа, которого нет в области команды
+func keyNotFound(key string, scope Scope) error {
+	if scope.IsGlobal() {
+		return fmt.Errorf("ключ '%s' не найден среди глобальных", key)
+	}
+	return fmt.Errorf("ключ '%s' не найден (%s); для глобальных триггеров укажите scope=global", key, scope)
+}
+
+// RemoveResponse удаляет вариант ответа номер n (с 1, в порядке /admin list).
+// Если это был последний ответ, сам триггер удаляется в корзину.
+func (h *BotDatabaseHandler) RemoveResponse(by Actor, key string, scope Scope, n int) error {
+	if n < 1 {
+		return fmt.Errorf("номер ответа должен быть положительным")
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	triggerID, _, err := findTriggerByKey(tx, key, scope)
+	if err == sql.ErrNoRows {
+		return keyNotFound(key, scope)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка поиска записи: %v", err)
+	}
+
+	var left int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM bushlatinga_bot.trigger_responses WHERE trigger_id = $1", triggerID).Scan(&left); err != nil {
+		return fmt.Errorf("ошибка подсчета ответов: %v", err)
+	}
+	if n > left {
+		return fmt.Errorf("у ключа '%s' нет ответа номер %d", key, n)
+	}
+	left--
+
+	err = auditedChange(tx, by, triggerID, func() error {
+		// Последний ответ остается у триггера, чтобы его можно было вернуть из корзины целиком
+		if left == 0 {
+			return trashTrigger(tx, by, triggerID)
+		}
+
+		query := `
+			DELETE FROM bushlatinga_bot.trigger_responses
+			WHERE id = (
+				SELECT id FROM bushlatinga_bot.trigger_responses
+				WHERE trigger_id = $1 ORDER BY id OFFSET $2 LIMIT 1
+			)
+		`
+		if _, err := tx.Exec(query, triggerID, n-1); err != nil {
+			return fmt.Errorf("ошибка удаления ответа: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка коммита транзакции: %v", err)
+	}
+
+	log.Printf("✅ [bushlatinga_bot] Удален ответ %d у '%s' (осталось %d)\n", n, key, left)
+	h.notifyIndexChanged("remove-response:" + key)
+	return nil
+}
+
+// AppendToLastResponse дописывает части к последнему добавленному ответу триггера
+func (h *BotDatabaseHandler) AppendToLastResponse(by Actor, key string, scope Scope, parts []ResponsePart) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	triggerID, _, err := findTriggerByKey(tx, key, scope)
+	if err == sql.ErrNoRows {
+		return keyNotFound(key, scope)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка поиска записи: %v", err)
+	}
+
+	before, err := loadTriggerState(tx, triggerID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		SELECT id, response_type, COALESCE(file_id, ''), response_text, parts
+		FROM bushlatinga_bot.trigger_responses
+		WHERE trigger_id = $1
+		ORDER BY id DESC LIMIT 1
+		FOR UPDATE
+	`
+
+	var responseID int64
+	var responseType, fileID, text string
+	var partsJSON []byte
+	if err := tx.QueryRow(query, triggerID).Scan(&responseID, &responseType, &fileID, &text, &partsJSON); err != nil {
+		return fmt.Errorf("ошибка чтения ответа: %v", err)
+	}
+
+	existing, err := decodeResponse(responseType, fileID, text, partsJSON)
+	if err != nil {
+		return err
+	}
+	combined := append(existing, parts...)
+	if err := validateResponseParts(combined); err != nil {
+		return err
+	}
+
+	responseType, fileID, text, partsJSON, err = encodeResponse(combined)
+	if err != nil {
+		return err
+	}
+
+	updateQuery := `
+		UPDATE bushlatinga_bot.trigger_responses
+		SET response_type = $2, file_id = NULLIF($3, ''), response_text = $4, parts = $5
+		WHERE id = $1
+	`
+	partsArg := sql.NullString{String: string(partsJSON), Valid: partsJSON != nil}
+	if _, err := tx.Exec(updateQuery, responseID, responseType, fileID, text, partsArg); err != nil {
+		return fmt.Errorf("ошибка обновления ответа: %v", err)
+	}
+
+	after, err := loadTriggerState(tx, triggerID)
+	if err != nil {
+		return err
+	}
+	if err := recordChange(tx, by, AuditUpdate, before, after); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка коммита транзакции: %v", err)
+	}
+
+	log.Printf("✅ [bushlatinga_bot] Ответ #%d у '%s' дополнен до %d сообщений\n", responseID, key, len(combined))
+	h.notifyIndexChanged("append:" + key)
+	return nil
+}
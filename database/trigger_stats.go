@@ -0,0 +1,290 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Исход совпадения триггера в trigger_matches
+const (
+	MatchSent    = "sent"    // ответ отправлен
+	MatchChance  = "chance"  // не выпала вероятность ответа
+	MatchLimited = "limited" // кулдаун, пауза или бюджет чата
+	MatchPolicy  = "policy"  // не выбран политикой нескольких совпадений или перебит детектором
+	MatchEmpty   = "empty"   // у триггера нет подходящего ответа
+)
+
+// Сколько хранятся совпадения: /admin stats смотрит не дальше этого срока
+const matchRetention = 90 * 24 * time.Hour
+
+// Сколько строк в каждом разделе /admin stats
+const statsListLimit = 10
+
+// matchRecord - совпадение триггера в сообщении и его исход
+type matchRecord struct {
+	trigger *Trigger
+	outcome string
+}
+
+// SetBotID задает ID бота, чьи счетчики совпадений ведутся в main.bot_stats.
+// Без него совпадения записываются только в trigger_matches.
+func (h *BotDatabaseHandler) SetBotID(botID int64) {
+	h.mu.Lock()
+	h.botID = botID
+	h.mu.Unlock()
+}
+
+// notSelected возвращает триггеры из found, которые не попали в selected:
+// их ответ подавлен политикой чата
+func notSelected(snapshot *triggerSnapshot, found, selected []Match) []matchRecord {
+	seen := snapshot.triggerIDs(selected)
+	var records []matchRecord
+	for _, m := range found {
+		trigger := snapshot.triggers[m.Pattern]
+		if seen[trigger.ID] {
+			continue
+		}
+		seen[trigger.ID] = true
+		records = append(records, matchRecord{trigger: trigger, outcome: MatchPolicy})
+	}
+	return records
+}
+
+// recordMatches записывает совпадения одним запросом и увеличивает счетчики бота:
+// total_eb_matches - детекторы, total_name_matches - все остальные триггеры
+func (h *BotDatabaseHandler) recordMatches(msg IncomingMessage, matches []matchRecord) {
+	if len(matches) == 0 {
+		return
+	}
+
+	var ids pq.Int64Array
+	var outcomes pq.StringArray
+	var names, detectors int
+	for _, m := range matches {
+		ids = append(ids, m.trigger.ID)
+		outcomes = append(outcomes, m.outcome)
+		if m.trigger.Type == TriggerExact {
+			detectors++
+		} else {
+			names++
+		}
+	}
+
+	h.mu.RLock()
+	botID := h.botID
+	h.mu.RUnlock()
+
+	query := `
+		WITH recorded AS (
+			INSERT INTO bushlatinga_bot.trigger_matches (trigger_id, chat_id, user_id, outcome)
+			SELECT trigger_id, $2, $3, outcome FROM unnest($1::bigint[], $4::text[]) AS m(trigger_id, outcome)
+		)
+		UPDATE main.bot_stats
+		SET total_name_matches = COALESCE(total_name_matches, 0) + $5,
+		    total_eb_matches = COALESCE(total_eb_matches, 0) + $6
+		WHERE bot_id = $7
+	`
+	if _, err := h.db.Exec(query, ids, msg.ChatID, msg.UserID, outcomes, names, detectors, botID); err != nil {
+		log.Printf("❌ Ошибка записи совпадений: %v", err)
+	}
+}
+
+// cleanupMatches удаляет совпадения старше matchRetention
+func (h *BotDatabaseHandler) cleanupMatches() {
+	query := "DELETE FROM bushlatinga_bot.trigger_matches WHERE matched_at < NOW() - $1::float8 * INTERVAL '1 second'"
+	if _, err := h.db.Exec(query, matchRetention.Seconds()); err != nil {
+		log.Printf("⚠️ Ошибка очистки совпадений: %v", err)
+	}
+}
+
+// UsageStats - статистика срабатываний за период для /admin stats
+type UsageStats struct {
+	ChatID   int64 // 0 - все чаты
+	Since    time.Time
+	Outcomes map[string]int64 // число совпадений по исходам
+
+	Top       []TriggerUsage // чаще всего отвечавшие триггеры
+	Dead      []TriggerUsage // действующие триггеры без единого ответа за период
+	DeadTotal int
+	Chats     []ChatUsage // разбивка по чатам, только для всех чатов
+
+	// Счетчики из main.bot_stats за все время, если известен ID бота
+	NameMatches, DetectorMatches int64
+	HasTotals                    bool
+}
+
+// TriggerUsage - срабатывания одного триггера за период
+type TriggerUsage struct {
+	ID         int64
+	Key        string // пусто, если триггер уже удален насовсем
+	Scope      Scope
+	Sent       int64
+	Suppressed int64
+	LastHit    time.Time // для мертвых триггеров - последний ответ за все время
+}
+
+// ChatUsage - срабатывания в одном чате за период
+type ChatUsage struct {
+	ChatID     int64
+	Title      string
+	Sent       int64
+	Suppressed int64
+}
+
+// TriggerUsageStats собирает статистику совпадений за период days в чате chatID (0 - во всех чатах).
+// Мертвые триггеры - действующие в этих чатах, созданные до начала периода и ни разу не ответившие за него.
+func (h *BotDatabaseHandler) TriggerUsageStats(chatID int64, days int) (*UsageStats, error) {
+	if maxDays := int(matchRetention.Hours() / 24); days > maxDays {
+		return nil, fmt.Errorf("совпадения хранятся %d дней, период не может быть длиннее", maxDays)
+	}
+	stats := &UsageStats{
+		ChatID:   chatID,
+		Since:    time.Now().AddDate(0, 0, -days),
+		Outcomes: make(map[string]int64),
+	}
+
+	rows, err := h.db.Query(`
+		SELECT outcome, COUNT(*) FROM bushlatinga_bot.trigger_matches
+		WHERE matched_at >= $1 AND ($2::bigint = 0 OR chat_id = $2)
+		GROUP BY outcome
+	`, stats.Since, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки статистики: %v", err)
+	}
+	for rows.Next() {
+		var outcome string
+		var count int64
+		if err := rows.Scan(&outcome, &count); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("ошибка чтения статистики: %v", err)
+		}
+		stats.Outcomes[outcome] = count
+	}
+	rows.Close()
+
+	if stats.Top, err = h.topTriggers(stats.Since, chatID); err != nil {
+		return nil, err
+	}
+	if stats.Dead, stats.DeadTotal, err = h.deadTriggers(stats.Since, chatID); err != nil {
+		return nil, err
+	}
+	if chatID == 0 {
+		if stats.Chats, err = h.chatUsage(stats.Since); err != nil {
+			return nil, err
+		}
+	}
+
+	h.mu.RLock()
+	botID := h.botID
+	h.mu.RUnlock()
+	if botID != 0 {
+		err := h.db.QueryRow("SELECT COALESCE(total_name_matches, 0), COALESCE(total_eb_matches, 0) FROM main.bot_stats WHERE bot_id = $1",
+			botID).Scan(&stats.NameMatches, &stats.DetectorMatches)
+		stats.HasTotals = err == nil
+	}
+	return stats, nil
+}
+
+// topTriggers возвращает триггеры с наибольшим числом ответов за период
+func (h *BotDatabaseHandler) topTriggers(since time.Time, chatID int64) ([]TriggerUsage, error) {
+	rows, err := h.db.Query(`
+		SELECT m.trigger_id, COALESCE(r.trigger_text, ''), COALESCE(r.chat_ids, '{}'),
+		       COUNT(*) FILTER (WHERE m.outcome = 'sent') AS sent,
+		       COUNT(*) FILTER (WHERE m.outcome <> 'sent')
+		FROM bushlatinga_bot.trigger_matches m
+		LEFT JOIN bushlatinga_bot.bushlatinga_responses r ON r.id = m.trigger_id
+		WHERE m.matched_at >= $1 AND ($2::bigint = 0 OR m.chat_id = $2)
+		GROUP BY m.trigger_id, r.trigger_text, r.chat_ids
+		ORDER BY sent DESC, COUNT(*) DESC
+		LIMIT $3
+	`, since, chatID, statsListLimit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки популярных триггеров: %v", err)
+	}
+	defer rows.Close()
+
+	var top []TriggerUsage
+	for rows.Next() {
+		var u TriggerUsage
+		var chatIDs pq.Int64Array
+		if err := rows.Scan(&u.ID, &u.Key, &chatIDs, &u.Sent, &u.Suppressed); err != nil {
+			return nil, fmt.Errorf("ошибка чтения популярных триггеров: %v", err)
+		}
+		u.Scope = Scope(chatIDs)
+		top = append(top, u)
+	}
+	return top, rows.Err()
+}
+
+// deadTriggers возвращает действующие триггеры без ответов за период и их общее количество
+func (h *BotDatabaseHandler) deadTriggers(since time.Time, chatID int64) ([]TriggerUsage, int, error) {
+	rows, err := h.db.Query(`
+		SELECT r.id, r.trigger_text, r.chat_ids, r.last_hit_at, COUNT(*) OVER ()
+		FROM bushlatinga_bot.bushlatinga_responses r
+		WHERE r.deleted_at IS NULL AND r.enabled AND r.created_at < $1
+		  AND ($2::bigint = 0 OR r.chat_ids = '{}' OR $2::bigint = ANY(r.chat_ids))
+		  AND NOT EXISTS (
+			SELECT 1 FROM bushlatinga_bot.trigger_matches m
+			WHERE m.trigger_id = r.id AND m.outcome = 'sent' AND m.matched_at >= $1
+			  AND ($2::bigint = 0 OR m.chat_id = $2)
+		  )
+		ORDER BY r.last_hit_at NULLS FIRST, r.trigger_text
+		LIMIT $3
+	`, since, chatID, statsListLimit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("ошибка загрузки мертвых триггеров: %v", err)
+	}
+	defer rows.Close()
+
+	var dead []TriggerUsage
+	total := 0
+	for rows.Next() {
+		var u TriggerUsage
+		var chatIDs pq.Int64Array
+		var lastHit sql.NullTime
+		if err := rows.Scan(&u.ID, &u.Key, &chatIDs, &lastHit, &total); err != nil {
+			return nil, 0, fmt.Errorf("ошибка чтения мертвых триггеров: %v", err)
+		}
+		u.Scope, u.LastHit = Scope(chatIDs), lastHit.Time
+		dead = append(dead, u)
+	}
+	return dead, total, rows.Err()
+}
+
+// chatUsage возвращает чаты с наибольшим числом ответов за период.
+// Название чата берется из последнего сообщения в логе.
+func (h *BotDatabaseHandler) chatUsage(since time.Time) ([]ChatUsage, error) {
+	rows, err := h.db.Query(`
+		SELECT m.chat_id,
+		       COUNT(*) FILTER (WHERE m.outcome = 'sent') AS sent,
+		       COUNT(*) FILTER (WHERE m.outcome <> 'sent'),
+		       COALESCE((
+				SELECT l.chat_title FROM main.messages_log l
+				WHERE l.chat_id = m.chat_id AND l.chat_title <> ''
+				ORDER BY l.id DESC LIMIT 1
+		       ), '')
+		FROM bushlatinga_bot.trigger_matches m
+		WHERE m.matched_at >= $1
+		GROUP BY m.chat_id
+		ORDER BY sent DESC, COUNT(*) DESC
+		LIMIT $2
+	`, since, statsListLimit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки статистики чатов: %v", err)
+	}
+	defer rows.Close()
+
+	var chats []ChatUsage
+	for rows.Next() {
+		var c ChatUsage
+		if err := rows.Scan(&c.ChatID, &c.Sent, &c.Suppressed, &c.Title); err != nil {
+			return nil, fmt.Errorf("ошибка чтения статистики чатов: %v", err)
+		}
+		chats = append(chats, c)
+	}
+	return chats, rows.Err()
+}
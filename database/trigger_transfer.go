@@ -0,0 +1,642 @@
+package database
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Форматы файлов экспорта и импорта триггеров
+const (
+	FormatJSON = "json" // массив триггеров со всеми ответами
+	FormatCSV  = "csv"  // строка на каждый вариант ответа, триггер повторяется
+)
+
+// Режимы импорта
+const (
+	ImportMerge   = "merge"   // добавить новые и обновить совпавшие триггеры, остальные не трогать
+	ImportReplace = "replace" // еще и удалить триггеры тех же областей, которых нет в файле
+)
+
+// maxImportFileSize - ограничение на размер файла импорта
+const maxImportFileSize = 1 << 20
+
+// csvColumns - колонки CSV-файла. Одиночный ответ хранится в response_type/file_id/response_text,
+// последовательность - в parts (JSON), как в trigger_responses.
+var csvColumns = []string{
+	"trigger_text", "trigger_type", "match_mode", "chat_ids", "priority", "cooldown_seconds",
+	"probability", "schedule", "enabled", "fuzzy", "expires_at", "weight", "response_type", "file_id", "response_text", "parts",
+}
+
+// ImportError - ошибка в строке файла импорта
+type ImportError struct {
+	Line int
+	Err  error
+}
+
+// ImportChange - триггер, который импорт добавит, изменит или удалит
+type ImportChange struct {
+	Key   string
+	Scope Scope
+}
+
+// ImportReport - результат разбора файла и сравнения его с текущими триггерами
+type ImportReport struct {
+	Mode      string
+	Total     int // триггеров в файле
+	Added     []ImportChange
+	Changed   []ImportChange
+	Removed   []ImportChange
+	Unchanged int
+	Errors    []ImportError
+	Applied   bool
+}
+
+// importItem - триггер из файла вместе со строкой, где он начинается
+type importItem struct {
+	line  int
+	state *triggerState
+}
+
+// ParseTransferFormat приводит введенный админом формат к json или csv
+func ParseTransferFormat(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", FormatJSON:
+		return FormatJSON, nil
+	case FormatCSV, "таблица":
+		return FormatCSV, nil
+	default:
+		return "", fmt.Errorf("неизвестный формат '%s' (доступно: json, csv)", value)
+	}
+}
+
+// ParseImportMode приводит введенный админом режим импорта к merge или replace
+func ParseImportMode(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", ImportMerge, "слить", "добавить":
+		return ImportMerge, nil
+	case ImportReplace, "заменить":
+		return ImportReplace, nil
+	default:
+		return "", fmt.Errorf("неизвестный режим импорта '%s' (доступно: merge, replace)", value)
+	}
+}
+
+// DetectTransferFormat определяет формат файла по расширению, а без него - по содержимому
+func DetectTransferFormat(fileName string, data []byte) string {
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".json":
+		return FormatJSON
+	case ".csv":
+		return FormatCSV
+	}
+	if trimmed := bytes.TrimSpace(stripBOM(data)); len(trimmed) > 0 && trimmed[0] == '[' {
+		return FormatJSON
+	}
+	return FormatCSV
+}
+
+// stripBOM убирает метку порядка байтов, которую добавляют табличные редакторы
+func stripBOM(data []byte) []byte {
+	return bytes.TrimPrefix(data, []byte("\ufeff"))
+}
+
+// stateOf собирает состояние триггера, загруженного из БД, в том же виде, что и журнал
+func stateOf(t *Trigger) *triggerState {
+	s := &triggerState{
+		Text:            t.Text,
+		MatchMode:       t.MatchMode,
+		Type:            t.Type,
+		Priority:        t.Priority,
+		ChatIDs:         t.ChatIDs.normalize(),
+		CooldownSeconds: int(t.Cooldown.Seconds()),
+		Probability:     t.Chance,
+		Enabled:         t.Enabled,
+		Fuzzy:           t.Fuzzy,
+		ExpiresAt:       expiryOf(t.ExpiresAt),
+	}
+	if t.Schedule != nil {
+		s.Schedule = t.Schedule.String()
+	}
+	for _, r := range t.Responses {
+		s.Responses = append(s.Responses, responseState{Parts: r.Parts, Weight: r.Weight})
+	}
+	return s
+}
+
+// stateKey - ключ триггера вместе с областью, уникальный как в индексе БД
+func stateKey(text string, scope Scope) string {
+	return text + "\x00" + Scope(scope).normalize().String()
+}
+
+// ExportTriggers выгружает триггеры под фильтром в файл выбранного формата.
+// Возвращает содержимое файла и количество триггеров в нем.
+func (h *BotDatabaseHandler) ExportTriggers(filter TriggerFilter, format string) ([]byte, int, error) {
+	triggers, err := h.ListTriggers(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	states := make([]*triggerState, 0, len(triggers))
+	for _, t := range triggers {
+		states = append(states, stateOf(t))
+	}
+
+	var data []byte
+	switch format {
+	case FormatCSV:
+		data, err = encodeTriggersCSV(states)
+	default:
+		data, err = json.MarshalIndent(states, "", "  ")
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("ошибка формирования файла: %v", err)
+	}
+	return data, len(states), nil
+}
+
+// encodeTriggersCSV записывает триггеры в CSV: строка на каждый вариант ответа
+func encodeTriggersCSV(states []*triggerState) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(csvColumns); err != nil {
+		return nil, err
+	}
+
+	for _, s := range states {
+		for _, r := range s.Responses {
+			responseType, fileID, text, partsJSON, err := encodeResponse(r.Parts)
+			if err != nil {
+				return nil, err
+			}
+			if responseType == ResponseSequence {
+				// Текст последовательности нужен в БД только для поиска
+				text = ""
+			}
+
+			fuzzy := "off"
+			if s.Fuzzy >= FuzzyNormal {
+				fuzzy = strconv.Itoa(s.Fuzzy)
+			}
+			scope := ""
+			if !Scope(s.ChatIDs).IsGlobal() {
+				scope = Scope(s.ChatIDs).String()
+			}
+			expires := ""
+			if s.ExpiresAt != nil {
+				expires = s.ExpiresAt.Format(time.RFC3339)
+			}
+
+			record := []string{
+				s.Text, s.Type, s.MatchMode, scope, strconv.Itoa(s.Priority), strconv.Itoa(s.CooldownSeconds),
+				strconv.Itoa(s.Probability), s.Schedule, strconv.FormatBool(s.Enabled), fuzzy, expires,
+				strconv.Itoa(r.Weight), responseType, fileID, text, string(partsJSON),
+			}
+			if err := w.Write(record); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// parseImportFile разбирает файл импорта. Ошибки собираются по строкам,
+// чтобы админ исправил их все за один раз.
+func parseImportFile(data []byte, format string) ([]importItem, []ImportError) {
+	data = stripBOM(data)
+	if format == FormatCSV {
+		return decodeTriggersCSV(data)
+	}
+	return decodeTriggersJSON(data)
+}
+
+// newImportState - триггер со значениями по умолчанию для полей, которых нет в файле
+func newImportState() *triggerState {
+	return &triggerState{
+		Type:        TriggerText,
+		MatchMode:   MatchSubstring,
+		Probability: defaultProbability,
+		Enabled:     true,
+		Fuzzy:       FuzzyOff,
+	}
+}
+
+// decodeTriggersJSON читает массив триггеров по одному элементу, запоминая строку каждого
+func decodeTriggersJSON(data []byte) ([]importItem, []ImportError) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if token, err := dec.Token(); err != nil || token != json.Delim('[') {
+		return nil, []ImportError{{Line: 1, Err: fmt.Errorf("ожидается JSON-массив триггеров")}}
+	}
+
+	var items []importItem
+	var errs []ImportError
+	for dec.More() {
+		line := lineAt(data, dec.InputOffset())
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if syntaxErr, ok := err.(*json.SyntaxError); ok {
+				line = lineAt(data, syntaxErr.Offset)
+			}
+			// После синтаксической ошибки продолжить разбор нельзя
+			return items, append(errs, ImportError{Line: line, Err: fmt.Errorf("некорректный JSON: %v", err)})
+		}
+
+		state := newImportState()
+		fields := json.NewDecoder(bytes.NewReader(raw))
+		fields.DisallowUnknownFields()
+		if err := fields.Decode(state); err != nil {
+			errs = append(errs, ImportError{Line: line, Err: fmt.Errorf("некорректный триггер: %v", err)})
+			continue
+		}
+		items = append(items, importItem{line: line, state: state})
+	}
+	return items, errs
+}
+
+// lineAt возвращает номер строки, с которой начинается значение после offset
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	for offset < int64(len(data)) && strings.IndexByte(" \t\r\n,", data[offset]) >= 0 {
+		offset++
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// decodeTriggersCSV читает CSV с заголовком. Строки с одинаковыми ключом и областью -
+// варианты ответа одного триггера, настройки триггера берутся из первой из них.
+func decodeTriggersCSV(data []byte) ([]importItem, []ImportError) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, []ImportError{{Line: 1, Err: fmt.Errorf("не удалось прочитать заголовок CSV: %v", err)}}
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(csvColumns, name) {
+			return nil, []ImportError{{Line: 1, Err: fmt.Errorf("неизвестная колонка '%s' (доступно: %s)", name, strings.Join(csvColumns, ", "))}}
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["trigger_text"]; !ok {
+		return nil, []ImportError{{Line: 1, Err: fmt.Errorf("нет колонки trigger_text")}}
+	}
+
+	var items []importItem
+	var errs []ImportError
+	index := make(map[string]int)
+	for {
+		record, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			line := 0
+			if parseErr, ok := err.(*csv.ParseError); ok {
+				line = parseErr.Line
+			}
+			return items, append(errs, ImportError{Line: line, Err: fmt.Errorf("некорректный CSV: %v", err)})
+		}
+		line, _ := r.FieldPos(0)
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		state, response, err := csvRowState(field)
+		if err != nil {
+			errs = append(errs, ImportError{Line: line, Err: err})
+			continue
+		}
+
+		key := stateKey(state.Text, state.ChatIDs)
+		if i, ok := index[key]; ok {
+			items[i].state.Responses = append(items[i].state.Responses, response)
+			continue
+		}
+		state.Responses = []responseState{response}
+		index[key] = len(items)
+		items = append(items, importItem{line: line, state: state})
+	}
+	return items, errs
+}
+
+// csvRowState собирает триггер и вариант ответа из строки CSV; пустые ячейки - значения по умолчанию
+func csvRowState(field func(string) string) (*triggerState, responseState, error) {
+	state := newImportState()
+	state.Text = field("trigger_text")
+	if value := field("trigger_type"); value != "" {
+		state.Type = value
+	}
+	if value := field("match_mode"); value != "" {
+		state.MatchMode = value
+	}
+	state.Schedule = field("schedule")
+
+	state.ChatIDs = Scope{}
+	if value := field("chat_ids"); value != "" {
+		scope, err := ParseScope(value, 0)
+		if err != nil {
+			return nil, responseState{}, err
+		}
+		state.ChatIDs = scope
+	}
+
+	for _, column := range []struct {
+		name   string
+		target *int
+	}{{"priority", &state.Priority}, {"cooldown_seconds", &state.CooldownSeconds}, {"probability", &state.Probability}} {
+		value := field(column.name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, responseState{}, fmt.Errorf("%s должно быть целым числом, получено '%s'", column.name, value)
+		}
+		*column.target = n
+	}
+
+	if value := field("enabled"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, responseState{}, fmt.Errorf("enabled должно быть true или false, получено '%s'", value)
+		}
+		state.Enabled = enabled
+	}
+	if value := field("fuzzy"); value != "" {
+		fuzzy, err := ParseFuzzy(value)
+		if err != nil {
+			return nil, responseState{}, err
+		}
+		state.Fuzzy = fuzzy
+	}
+	if value := field("expires_at"); value != "" {
+		expiresAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, responseState{}, fmt.Errorf("expires_at должно быть в формате 2006-01-02T15:04:05Z, получено '%s'", value)
+		}
+		state.ExpiresAt = &expiresAt
+	}
+
+	response := responseState{Weight: 1}
+	if value := field("weight"); value != "" {
+		weight, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, responseState{}, fmt.Errorf("weight должно быть целым числом, получено '%s'", value)
+		}
+		response.Weight = weight
+	}
+
+	responseType := field("response_type")
+	partsJSON := []byte(field("parts"))
+	if responseType == "" {
+		responseType = ResponseText
+		if len(partsJSON) > 0 {
+			responseType = ResponseSequence
+		}
+	}
+	parts, err := decodeResponse(responseType, field("file_id"), field("response_text"), partsJSON)
+	if err != nil {
+		return nil, responseState{}, err
+	}
+	response.Parts = parts
+	return state, response, nil
+}
+
+// validateImportState проверяет триггер из файла и приводит его к виду для БД, как /admin add
+func validateImportState(s *triggerState) error {
+	var err error
+	if s.Type, err = ParseTriggerType(s.Type); err != nil {
+		return err
+	}
+	if s.MatchMode, err = ParseMatchMode(s.MatchMode); err != nil {
+		return err
+	}
+
+	var groups []string
+	if s.Text, groups, err = normalizeTriggerKey(s.Type, s.Text); err != nil {
+		return err
+	}
+
+	for _, id := range s.ChatIDs {
+		if id == 0 {
+			return fmt.Errorf("некорректный ID чата 0 в chat_ids")
+		}
+	}
+	s.ChatIDs = Scope(s.ChatIDs).normalize()
+
+	if s.CooldownSeconds < 0 {
+		return fmt.Errorf("cooldown_seconds не может быть отрицательным")
+	}
+	if s.Probability < minProbability || s.Probability > defaultProbability {
+		return fmt.Errorf("вероятность должна быть от %d до %d процентов", minProbability, defaultProbability)
+	}
+	if s.Schedule != "" {
+		schedule, err := ParseSchedule(s.Schedule)
+		if err != nil {
+			return err
+		}
+		s.Schedule = schedule.String()
+	}
+	if s.Fuzzy < FuzzyOff || s.Fuzzy > maxFuzzyTypos {
+		return fmt.Errorf("fuzzy должно быть от %d до %d", FuzzyOff, maxFuzzyTypos)
+	}
+	if s.Fuzzy != FuzzyOff && s.Type != TriggerText {
+		return fmt.Errorf("нечеткое сравнение доступно только для текстовых триггеров")
+	}
+	if s.ExpiresAt != nil {
+		s.ExpiresAt = expiryOf(*s.ExpiresAt)
+	}
+
+	if len(s.Responses) == 0 {
+		return fmt.Errorf("у триггера нет ни одного ответа")
+	}
+	for i := range s.Responses {
+		r := &s.Responses[i]
+		if r.Weight == 0 {
+			r.Weight = 1
+		}
+		if r.Weight < 0 {
+			return fmt.Errorf("вес ответа %d должен быть положительным", i+1)
+		}
+		if err := validateResponseParts(r.Parts); err != nil {
+			return fmt.Errorf("ответ %d: %v", i+1, err)
+		}
+		for _, p := range r.Parts {
+			if err := validateResponseTemplate(p.Text, groups); err != nil {
+				return fmt.Errorf("ответ %d: %v", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ImportTriggers разбирает файл и сравнивает его с текущими триггерами.
+// С apply=false ничего не меняет - это предпросмотр; с apply=true применяет изменения
+// одной транзакцией и только если в файле нет ни одной ошибки.
+func (h *BotDatabaseHandler) ImportTriggers(by Actor, data []byte, format, mode string, apply bool) (*ImportReport, error) {
+	if len(data) > maxImportFileSize {
+		return nil, fmt.Errorf("файл больше %d КБ", maxImportFileSize>>10)
+	}
+
+	items, errs := parseImportFile(data, format)
+	report := &ImportReport{Mode: mode, Total: len(items), Errors: errs}
+
+	current, err := h.ListTriggers(TriggerFilter{AllScopes: true})
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]*triggerState, len(current))
+	for _, t := range current {
+		existing[stateKey(t.Text, t.ChatIDs)] = stateOf(t)
+	}
+
+	var writes []*triggerState
+	seen := make(map[string]int)
+	scopes := make(map[string]bool)
+	for _, item := range items {
+		s := item.state
+		if err := validateImportState(s); err != nil {
+			report.Errors = append(report.Errors, ImportError{Line: item.line, Err: err})
+			continue
+		}
+
+		key := stateKey(s.Text, s.ChatIDs)
+		if line, ok := seen[key]; ok {
+			report.Errors = append(report.Errors, ImportError{Line: item.line, Err: fmt.Errorf("'%s' (%s) уже есть в строке %d", s.Text, Scope(s.ChatIDs), line)})
+			continue
+		}
+		seen[key] = item.line
+
+		if !h.canEditScope(by.UserID, s.ChatIDs) {
+			report.Errors = append(report.Errors, ImportError{Line: item.line, Err: fmt.Errorf("нет прав менять триггеры: %s", Scope(s.ChatIDs))})
+			continue
+		}
+		scopes[Scope(s.ChatIDs).String()] = true
+
+		change := ImportChange{Key: s.Text, Scope: s.ChatIDs}
+		old, ok := existing[key]
+		switch {
+		case !ok:
+			report.Added = append(report.Added, change)
+		case old.Type != s.Type:
+			report.Errors = append(report.Errors, ImportError{Line: item.line, Err: errTypeChanged(s.Text, s.Type)})
+			continue
+		case sameState(old, s):
+			report.Unchanged++
+			continue
+		default:
+			report.Changed = append(report.Changed, change)
+		}
+		writes = append(writes, s)
+	}
+
+	var removes []ImportChange
+	if mode == ImportReplace {
+		for key, s := range existing {
+			if _, ok := seen[key]; !ok && scopes[Scope(s.ChatIDs).String()] {
+				removes = append(removes, ImportChange{Key: s.Text, Scope: s.ChatIDs})
+			}
+		}
+		sort.Slice(removes, func(i, j int) bool { return removes[i].Key < removes[j].Key })
+		report.Removed = removes
+	}
+	sort.Slice(report.Errors, func(i, j int) bool { return report.Errors[i].Line < report.Errors[j].Line })
+
+	if !apply || len(report.Errors) > 0 {
+		return report, nil
+	}
+	if err := h.applyImport(by, writes, removes); err != nil {
+		return nil, err
+	}
+	report.Applied = true
+	return report, nil
+}
+
+// sameState сравнивает триггеры по всем полям и ответам
+func sameState(a, b *triggerState) bool {
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(aJSON, bJSON)
+}
+
+// applyImport записывает и удаляет триггеры одной транзакцией, каждое изменение попадает в журнал
+func (h *BotDatabaseHandler) applyImport(by Actor, writes []*triggerState, removes []ImportChange) error {
+	if len(writes) == 0 && len(removes) == 0 {
+		return nil
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	for _, s := range writes {
+		id, err := findTriggerID(tx, s.Text, s.ChatIDs)
+		if err != nil {
+			return fmt.Errorf("ошибка поиска записи: %v", err)
+		}
+		before, err := loadTriggerState(tx, id)
+		if err != nil {
+			return err
+		}
+		if id, err = writeTriggerState(tx, s); err != nil {
+			return fmt.Errorf("'%s': %v", s.Text, err)
+		}
+		after, err := loadTriggerState(tx, id)
+		if err != nil {
+			return err
+		}
+
+		action := AuditUpdate
+		if before == nil {
+			action = AuditAdd
+		}
+		if err := recordChange(tx, by, action, before, after); err != nil {
+			return err
+		}
+	}
+
+	// Удаленные импортом триггеры попадают в корзину, как после /admin remove
+	for _, r := range removes {
+		id, err := findTriggerID(tx, r.Key, r.Scope)
+		if err != nil {
+			return fmt.Errorf("ошибка поиска записи: %v", err)
+		}
+		err = auditedChange(tx, by, id, func() error {
+			return trashTrigger(tx, by, id)
+		})
+		if err != nil {
+			return fmt.Errorf("ошибка удаления '%s': %v", r.Key, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка коммита транзакции: %v", err)
+	}
+
+	log.Printf("✅ [bushlatinga_bot] Импорт пользователем %d: записано %d, удалено %d\n", by.UserID, len(writes), len(removes))
+	h.notifyIndexChanged("import")
+	return nil
+}
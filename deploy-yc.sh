@@ -54,10 +54,9 @@ if [ -z "$DATABASE_URL" ]; then
     echo -e "${YELLOW}⚠️  DATABASE_URL не указан, бот будет работать в memory-only режиме${NC}"
 fi
 
-read -p "Введите ADMIN_CHAT_ID (ваш ID в Telegram): " ADMIN_CHAT_ID
+read -p "Введите ADMIN_CHAT_ID (ваш ID в Telegram, станет первым владельцем бота): " ADMIN_CHAT_ID
 if [ -z "$ADMIN_CHAT_ID" ]; then
-    ADMIN_CHAT_ID="266468924"
-    echo -e "${YELLOW}⚠️  Используем ADMIN_CHAT_ID по умолчанию: 266468924${NC}"
+    echo -e "${YELLOW}⚠️  ADMIN_CHAT_ID не указан: если в таблице admins нет владельца, команды /admin будут недоступны${NC}"
 fi
 
 # Опциональные переменные
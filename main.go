@@ -7,6 +7,7 @@ import (
 	"os"
 	"strconv"
 	"time"
+	_ "time/tzdata" // часовые пояса чатов не зависят от tzdata в контейнере
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/joho/godotenv"
@@ -164,24 +165,23 @@ func main() {
 	if dbURL != "" {
 		log.Println("📊 Инициализация подключения к базе данных...")
 
-		// Определяем ID администратора бота
-		adminID := int64(266468924) // Значение по умолчанию
-
-		// Пробуем получить ID администратора из переменных окружения
+		// Первый владелец бота: записывается в таблицу admins, только пока в ней нет ни одного
+		// глобального владельца. Дальше роли назначаются командами /admin grant и /admin revoke.
+		var ownerID int64
 		if adminEnv := os.Getenv("ADMIN_CHAT_ID"); adminEnv != "" {
-			if id, err := strconv.ParseInt(adminEnv, 10, 64); err == nil {
-				adminID = id
-				log.Printf("👑 Администратор из .env: %d", adminID)
+			if id, err := strconv.ParseInt(adminEnv, 10, 64); err == nil && id > 0 {
+				ownerID = id
+				log.Printf("👑 Первый владелец из .env: %d", ownerID)
 			} else {
-				log.Printf("⚠️ Неверный формат ADMIN_CHAT_ID: %s, использую значение по умолчанию", adminEnv)
+				log.Printf("⚠️ Неверный формат ADMIN_CHAT_ID: %s, владельцы берутся только из таблицы admins", adminEnv)
 			}
 		} else {
-			log.Printf("👑 Администратор по умолчанию: %d", adminID)
+			log.Println("⚠️ ADMIN_CHAT_ID не задан, владельцы берутся только из таблицы admins")
 		}
 
 		// Создаем обработчик базы данных
 		var err error
-		dbHandler, err = database.NewBotDatabaseHandler(adminID, dbURL)
+		dbHandler, err = database.NewBotDatabaseHandler(ownerID, dbURL)
 		if err != nil {
 			log.Printf("❌ Ошибка инициализации обработчика БД: %v", err)
 			log.Printf("⚠️ Бот будет работать в ограниченном режиме без базы данных")
@@ -190,7 +190,15 @@ func main() {
 			// Убеждаемся, что соединение будет закрыто при завершении программы
 			defer dbHandler.Close()
 			log.Printf("✅ Обработчик БД успешно инициализирован")
-			log.Printf("ℹ️ Администратор бота: %d", adminID)
+
+			// Фоновые изменения триггеров (истечение срока) сообщаем в Чат А
+			if teleLogger.IsEnabled() {
+				dbHandler.SetAdminNotifier(func(text string) {
+					if _, err := botAPI.Send(tgbotapi.NewMessage(teleLoggerChatID, text)); err != nil {
+						log.Printf("⚠️ Не удалось отправить уведомление в Чат А %d: %v", teleLoggerChatID, err)
+					}
+				})
+			}
 		}
 	} else {
 		log.Println("ℹ️ DATABASE_URL не установлен, бот работает без базы данных")
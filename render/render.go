@@ -0,0 +1,265 @@
+// Package render собирает текст сообщений бота для Telegram: экранирует текст
+// под выбранную разметку (HTML или MarkdownV2), делит длинный текст на несколько сообщений
+// и хранит его же без разметки, чтобы сообщение можно было отправить повторно,
+// если Telegram не примет разметку.
+package render
+
+import (
+	"strings"
+)
+
+// Mode - разметка сообщения, значение parse_mode в Telegram
+type Mode string
+
+const (
+	Plain      Mode = ""
+	HTML       Mode = "HTML"
+	MarkdownV2 Mode = "MarkdownV2"
+)
+
+// Лимиты Telegram на длину текста после разбора разметки, в символах UTF-16
+const (
+	MessageLimit = 4096
+	CaptionLimit = 1024
+)
+
+// Виды частей сообщения
+const (
+	kindText = iota // обычный текст
+	kindCode        // моноширинный текст
+	kindBold        // жирный текст
+)
+
+// segment - часть сообщения одного вида
+type segment struct {
+	text string
+	kind int
+}
+
+// Builder собирает сообщение из обычного, моноширинного и жирного текста.
+// Текст передается как есть: экранируется он только при Render.
+type Builder struct {
+	segments []segment
+}
+
+// Chunk - одно сообщение из результата Render: текст с разметкой и он же без разметки
+type Chunk struct {
+	Mode  Mode
+	Text  string
+	Plain string
+}
+
+// Markup разбирает упрощенную разметку ответов администратору: `код` - моноширинный текст,
+// все остальное - обычный текст, в котором любые символы безопасны.
+// Непарная обратная кавычка остается обычным символом.
+func Markup(text string) *Builder {
+	b := &Builder{}
+	parts := strings.Split(text, "`")
+	for i, part := range parts {
+		switch {
+		case i%2 == 0:
+			b.Text(part)
+		case i == len(parts)-1:
+			b.Text("`" + part)
+		default:
+			b.Code(part)
+		}
+	}
+	return b
+}
+
+// Text добавляет обычный текст
+func (b *Builder) Text(text string) *Builder {
+	return b.add(text, kindText)
+}
+
+// Code добавляет моноширинный текст
+func (b *Builder) Code(text string) *Builder {
+	return b.add(text, kindCode)
+}
+
+// Bold добавляет жирный текст
+func (b *Builder) Bold(text string) *Builder {
+	return b.add(text, kindBold)
+}
+
+func (b *Builder) add(text string, kind int) *Builder {
+	if text != "" {
+		b.segments = append(b.segments, segment{text: text, kind: kind})
+	}
+	return b
+}
+
+// Render возвращает сообщение в разметке mode, разделенное на части не длиннее limit.
+// Делится оно по строкам, а строка длиннее limit - посимвольно.
+// Пустое сообщение дает пустой результат: Telegram такие не принимает.
+func (b *Builder) Render(mode Mode, limit int) []Chunk {
+	var chunks []Chunk
+	var current [][]segment
+	size := 0
+
+	flush := func() {
+		// Пустые строки в конце части не нужны
+		for len(current) > 0 && len(current[len(current)-1]) == 0 {
+			current = current[:len(current)-1]
+		}
+		if len(current) > 0 {
+			chunks = append(chunks, renderLines(mode, current))
+		}
+		current, size = nil, 0
+	}
+
+	for _, line := range b.lines() {
+		for _, part := range splitLine(line, limit) {
+			n := lineLength(part)
+			if len(current) > 0 && size+1+n > limit {
+				flush()
+			}
+			// Пустые строки в начале части тоже не нужны
+			if len(current) == 0 && n == 0 {
+				continue
+			}
+			if len(current) > 0 {
+				size++
+			}
+			current = append(current, part)
+			size += n
+		}
+	}
+	flush()
+	return chunks
+}
+
+// lines делит сообщение на строки; часть, в которой есть перевод строки, делится на несколько
+func (b *Builder) lines() [][]segment {
+	lines := [][]segment{nil}
+	for _, s := range b.segments {
+		for i, piece := range strings.Split(s.text, "\n") {
+			if i > 0 {
+				lines = append(lines, nil)
+			}
+			if piece != "" {
+				lines[len(lines)-1] = append(lines[len(lines)-1], segment{text: piece, kind: s.kind})
+			}
+		}
+	}
+	return lines
+}
+
+// splitLine делит строку длиннее limit на куски по limit символов
+func splitLine(line []segment, limit int) [][]segment {
+	if lineLength(line) <= limit {
+		return [][]segment{line}
+	}
+
+	var parts [][]segment
+	var current []segment
+	size := 0
+	for _, s := range line {
+		var piece strings.Builder
+		for _, r := range s.text {
+			n := runeLength(r)
+			if size+n > limit {
+				if piece.Len() > 0 {
+					current = append(current, segment{text: piece.String(), kind: s.kind})
+					piece.Reset()
+				}
+				parts = append(parts, current)
+				current, size = nil, 0
+			}
+			piece.WriteRune(r)
+			size += n
+		}
+		if piece.Len() > 0 {
+			current = append(current, segment{text: piece.String(), kind: s.kind})
+		}
+	}
+	if len(current) > 0 {
+		parts = append(parts, current)
+	}
+	return parts
+}
+
+// renderLines собирает часть сообщения в разметке mode и без нее
+func renderLines(mode Mode, lines [][]segment) Chunk {
+	var text, plain strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			text.WriteString("\n")
+			plain.WriteString("\n")
+		}
+		for _, s := range line {
+			text.WriteString(format(mode, s))
+			plain.WriteString(s.text)
+		}
+	}
+	return Chunk{Mode: mode, Text: text.String(), Plain: plain.String()}
+}
+
+// Символы, которые экранируются в разметке
+var (
+	htmlEscaper       = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	markdownV2Escaper = newBackslashEscaper("\\_*[]()~`>#+-=|{}.!")
+	markdownV2Code    = newBackslashEscaper("\\`")
+)
+
+// newBackslashEscaper экранирует обратной косой чертой каждый из символов chars
+func newBackslashEscaper(chars string) *strings.Replacer {
+	var pairs []string
+	for _, r := range chars {
+		pairs = append(pairs, string(r), "\\"+string(r))
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// format экранирует часть сообщения и оборачивает ее в разметку mode
+func format(mode Mode, s segment) string {
+	switch mode {
+	case HTML:
+		text := htmlEscaper.Replace(s.text)
+		switch s.kind {
+		case kindCode:
+			return "<code>" + text + "</code>"
+		case kindBold:
+			return "<b>" + text + "</b>"
+		}
+		return text
+
+	case MarkdownV2:
+		switch s.kind {
+		case kindCode:
+			return "`" + markdownV2Code.Replace(s.text) + "`"
+		case kindBold:
+			return "*" + markdownV2Escaper.Replace(s.text) + "*"
+		}
+		return markdownV2Escaper.Replace(s.text)
+
+	default:
+		return s.text
+	}
+}
+
+// lineLength - длина строки в символах UTF-16, как ее считает Telegram
+func lineLength(line []segment) int {
+	n := 0
+	for _, s := range line {
+		for _, r := range s.text {
+			n += runeLength(r)
+		}
+	}
+	return n
+}
+
+// runeLength - сколько символов UTF-16 занимает руна
+func runeLength(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// IsParseError сообщает, что Telegram отклонил сообщение из-за разметки:
+// тогда его стоит отправить еще раз без нее (Chunk.Plain)
+func IsParseError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "can't parse entities")
+}
@@ -0,0 +1,204 @@
+package render
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestRenderEscaping(t *testing.T) {
+	tests := []struct {
+		name    string
+		message *Builder
+		mode    Mode
+		want    string
+	}{
+		{
+			name:    "html text",
+			message: (&Builder{}).Text(`a < b && c > "d" _e_ *f*`),
+			mode:    HTML,
+			want:    `a &lt; b &amp;&amp; c &gt; "d" _e_ *f*`,
+		},
+		{
+			name:    "html code and bold",
+			message: (&Builder{}).Bold("<b>").Text(" ").Code("x<y>&z"),
+			mode:    HTML,
+			want:    "<b>&lt;b&gt;</b> <code>x&lt;y&gt;&amp;z</code>",
+		},
+		{
+			name:    "markdownv2 text",
+			message: (&Builder{}).Text(`_*[]()~` + "`" + `>#+-=|{}.!\`),
+			mode:    MarkdownV2,
+			want:    `\_\*\[\]\(\)\~` + "\\`" + `\>\#\+\-\=\|\{\}\.\!\\`,
+		},
+		{
+			name:    "markdownv2 code escapes only backslash and backtick",
+			message: (&Builder{}).Code("a_b*c`d\\e.f"),
+			mode:    MarkdownV2,
+			want:    "`a_b*c\\`d\\\\e.f`",
+		},
+		{
+			name:    "markdownv2 bold",
+			message: (&Builder{}).Bold("v1.2!"),
+			mode:    MarkdownV2,
+			want:    `*v1\.2\!*`,
+		},
+		{
+			name:    "plain keeps text as is",
+			message: (&Builder{}).Bold("<b>").Text(" _x_ ").Code("`y`"),
+			mode:    Plain,
+			want:    "<b> _x_ `y`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := tt.message.Render(tt.mode, MessageLimit)
+			if len(chunks) != 1 {
+				t.Fatalf("Render returned %d chunks, want 1", len(chunks))
+			}
+			if chunks[0].Text != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.mode, chunks[0].Text, tt.want)
+			}
+			if chunks[0].Mode != tt.mode {
+				t.Errorf("chunk mode = %q, want %q", chunks[0].Mode, tt.mode)
+			}
+		})
+	}
+}
+
+func TestMarkup(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		wantHTML  string
+		wantPlain string
+	}{
+		{"no backticks", "a < b", "a &lt; b", "a < b"},
+		{"code span", "ключ `a_b` добавлен", "ключ <code>a_b</code> добавлен", "ключ a_b добавлен"},
+		{"two code spans", "`a` и `b`", "<code>a</code> и <code>b</code>", "a и b"},
+		{"unmatched backtick stays literal", "use `key` and `rest", "use <code>key</code> and `rest", "use key and `rest"},
+		{"single backtick", "a`b", "a`b", "a`b"},
+		{"trailing backtick", "a`", "a`", "a`"},
+		{"empty code span is dropped", "a``b", "ab", "ab"},
+		{"html inside code", "`<i>`", "<code>&lt;i&gt;</code>", "<i>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := Markup(tt.text).Render(HTML, MessageLimit)
+			if len(chunks) != 1 {
+				t.Fatalf("Markup(%q) rendered %d chunks, want 1", tt.text, len(chunks))
+			}
+			if chunks[0].Text != tt.wantHTML {
+				t.Errorf("Markup(%q) HTML = %q, want %q", tt.text, chunks[0].Text, tt.wantHTML)
+			}
+			if chunks[0].Plain != tt.wantPlain {
+				t.Errorf("Markup(%q) plain = %q, want %q", tt.text, chunks[0].Plain, tt.wantPlain)
+			}
+		})
+	}
+}
+
+func TestMarkupUnmatchedBacktickMarkdownV2(t *testing.T) {
+	chunks := Markup("`key` and `rest").Render(MarkdownV2, MessageLimit)
+	want := "`key` and \\`rest"
+	if len(chunks) != 1 || chunks[0].Text != want {
+		t.Errorf("Render = %+v, want one chunk %q", chunks, want)
+	}
+}
+
+func TestRuneLength(t *testing.T) {
+	tests := []struct {
+		r    rune
+		want int
+	}{
+		{'a', 1},
+		{'я', 1},
+		{'€', 1},
+		{'\uFFFF', 1},
+		{'😀', 2},
+		{'\U00010000', 2},
+		{'\U0010FFFF', 2},
+	}
+
+	for _, tt := range tests {
+		if got := runeLength(tt.r); got != tt.want {
+			t.Errorf("runeLength(%U) = %d, want %d", tt.r, got, tt.want)
+		}
+		if got := len(utf16.Encode([]rune{tt.r})); got != tt.want {
+			t.Errorf("utf16 length of %U = %d, test expects %d", tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestRenderChunkBoundaries(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []int // длины частей в символах UTF-16
+	}{
+		{"exactly the limit", strings.Repeat("a", MessageLimit), []int{MessageLimit}},
+		{"one over the limit", strings.Repeat("a", MessageLimit+1), []int{MessageLimit, 1}},
+		{"astral characters fill the limit", strings.Repeat("😀", MessageLimit/2), []int{MessageLimit}},
+		{"astral character is not split", "a" + strings.Repeat("😀", MessageLimit/2), []int{MessageLimit - 1, 2}},
+		{"lines fit with newline", strings.Repeat("a", 2048) + "\n" + strings.Repeat("b", 2047), []int{MessageLimit}},
+		{"lines split at newline", strings.Repeat("a", 2048) + "\n" + strings.Repeat("b", 2048), []int{2048, 2048}},
+		{"escaping does not count", strings.Repeat("<", MessageLimit), []int{MessageLimit}},
+		{"blank lines at chunk edges are dropped", "\n\n" + strings.Repeat("a", MessageLimit) + "\n\n\nb\n\n", []int{MessageLimit, 1}},
+		{"empty message", "", nil},
+		{"only newlines", "\n\n\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := (&Builder{}).Text(tt.text).Render(HTML, MessageLimit)
+
+			var got []int
+			var plain []string
+			for _, c := range chunks {
+				got = append(got, len(utf16.Encode([]rune(c.Plain))))
+				plain = append(plain, c.Plain)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("chunk lengths = %v, want %v", got, tt.want)
+			}
+			if joined := strings.ReplaceAll(strings.Join(plain, ""), "\n", ""); joined != strings.ReplaceAll(tt.text, "\n", "") {
+				t.Errorf("chunks lost or changed text")
+			}
+		})
+	}
+}
+
+func TestRenderSplitKeepsSegmentKinds(t *testing.T) {
+	message := (&Builder{}).Text("ab").Code("cdef").Text("g")
+	chunks := message.Render(HTML, 3)
+
+	var got []string
+	for _, c := range chunks {
+		got = append(got, c.Text)
+	}
+	want := []string{"ab<code>c</code>", "<code>def</code>", "g"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Render = %q, want %q", got, want)
+	}
+}
+
+func TestIsParseError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("Bad Request: can't parse entities: Can't find end of the entity starting at byte offset 5"), true},
+		{errors.New("Bad Request: message is too long"), false},
+	}
+
+	for _, tt := range tests {
+		if got := IsParseError(tt.err); got != tt.want {
+			t.Errorf("IsParseError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}